@@ -1,6 +1,7 @@
 package objconv
 
 import (
+	"fmt"
 	"reflect"
 	"sync"
 )
@@ -32,12 +33,13 @@ func Install(typ reflect.Type, adapter Adapter) {
 	adapterStore[typ] = adapter
 	adapterMutex.Unlock()
 
-	// We have to clear the struct cache because it may now have become invalid.
+	// We have to clear the caches because they may now have become invalid.
 	// Because installing adapters is done in the package initialization phase
 	// it's unlikely that any encoding or decoding operations are taking place
 	// at this time so there should be no performance impact of clearing the
-	// cache.
+	// caches.
 	structCache.clear()
+	clearDecodeFuncCache()
 }
 
 // AdapterOf returns the adapter for typ, setting ok to true if one was found,
@@ -49,7 +51,89 @@ func AdapterOf(typ reflect.Type) (a Adapter, ok bool) {
 	return
 }
 
+// InstallKindDecoder sets decode as the function used to decode values of the
+// given kind, which is consulted for kinds that the package doesn't support
+// natively, like reflect.Chan or reflect.Func. It has no effect on the kinds
+// that are already supported.
+//
+// The function receives the decoder and the destination value, it must consume
+// the next value from the decoder's parser and return its type. Passing a nil
+// function removes the decoder installed for kind.
+//
+// Like Install, this function is intended to be called during the package
+// initialization phase.
+func InstallKindDecoder(kind reflect.Kind, decode func(Decoder, reflect.Value) (Type, error)) {
+	adapterMutex.Lock()
+	if decode == nil {
+		delete(kindDecoderStore, kind)
+	} else {
+		kindDecoderStore[kind] = decode
+	}
+	adapterMutex.Unlock()
+	structCache.clear()
+	clearDecodeFuncCache()
+}
+
+// RegisterFieldDecoder sets decode as the function used to decode the field
+// named fieldName of the struct type structType, instead of the decoder of the
+// field type. This is useful to customize the decoding of fields which have
+// types that the program doesn't own, and therefore cannot implement
+// ValueDecoder.
+//
+// The field name is the name of the field in the Go struct, not the one that it
+// may have been given by its tag. The function receives the decoder and the
+// field value, it must consume the next value from the decoder's parser.
+// Passing a nil function removes the decoder registered for the field.
+//
+// The function returns an error if structType is not a struct or has no
+// exported field named fieldName. Like Install, it is intended to be called
+// during the package initialization phase.
+func RegisterFieldDecoder(structType reflect.Type, fieldName string, decode func(Decoder, reflect.Value) error) error {
+	if structType.Kind() != reflect.Struct {
+		return fmt.Errorf("objconv: cannot register a field decoder on %s which is not a struct type", structType)
+	}
+
+	if f, ok := structType.FieldByName(fieldName); !ok || len(f.Index) != 1 || len(f.PkgPath) != 0 {
+		return fmt.Errorf("objconv: cannot register a field decoder on %s which has no exported field named %s", structType, fieldName)
+	}
+
+	key := fieldDecoderKey{structType, fieldName}
+
+	adapterMutex.Lock()
+	if decode == nil {
+		delete(fieldDecoderStore, key)
+	} else {
+		fieldDecoderStore[key] = decode
+	}
+	adapterMutex.Unlock()
+	structCache.clear()
+	clearDecodeFuncCache()
+	return nil
+}
+
+func fieldDecoderOf(t reflect.Type, name string) (f func(Decoder, reflect.Value) error, ok bool) {
+	adapterMutex.RLock()
+	f, ok = fieldDecoderStore[fieldDecoderKey{t, name}]
+	adapterMutex.RUnlock()
+	return
+}
+
+type fieldDecoderKey struct {
+	typ  reflect.Type
+	name string
+}
+
+func kindDecoderOf(kind reflect.Kind) (f decodeFunc, ok bool) {
+	adapterMutex.RLock()
+	f, ok = kindDecoderStore[kind]
+	adapterMutex.RUnlock()
+	return
+}
+
 var (
-	adapterMutex sync.RWMutex
-	adapterStore = make(map[reflect.Type]Adapter)
+	adapterMutex     sync.RWMutex
+	adapterStore     = make(map[reflect.Type]Adapter)
+	kindDecoderStore = make(map[reflect.Kind]decodeFunc)
+
+	fieldDecoderStore = make(map[fieldDecoderKey]func(Decoder, reflect.Value) error)
 )
@@ -0,0 +1,310 @@
+package objconv
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Value is a tagged representation of decoded values, it can be used to load
+// documents which have no predefined Go types and traverse them without having
+// to type-assert the maps and slices produced when decoding into an empty
+// interface:
+//
+//	v, err := d.DecodeAny()
+//	if err != nil {
+//		...
+//	}
+//
+//	name, ok := v.Get("users").Index(0).Get("name").AsString()
+//
+// The lookup methods return the zero value (of type Unknown) when the element
+// doesn't exist, so they can be chained, and the accessors report whether the
+// value had the expected type.
+//
+// The scalar values are held as bool, int64, uint64, float64, string, []byte,
+// time.Time, time.Duration or error, depending on the type of the value.
+type Value struct {
+	typ     Type
+	value   interface{}
+	array   []Value
+	entries []ValueEntry
+}
+
+// ValueEntry is a key/value pair of a map held by a Value.
+type ValueEntry struct {
+	Key   Value
+	Value Value
+}
+
+// DecodeAny decodes the next value into a Value.
+func (d Decoder) DecodeAny() (v Value, err error) {
+	err = d.Decode(&v)
+	return
+}
+
+// Type returns the type of the value, which is Unknown for the zero value.
+func (v Value) Type() Type { return v.typ }
+
+// IsNil returns true if the value is null.
+func (v Value) IsNil() bool { return v.typ == Nil }
+
+// Len returns the number of elements of arrays, or the number of entries of
+// maps. It returns zero for other types.
+func (v Value) Len() int {
+	switch v.typ {
+	case Array:
+		return len(v.array)
+	case Map:
+		return len(v.entries)
+	default:
+		return 0
+	}
+}
+
+// Index returns the element at index i of an array, or the zero value if v is
+// not an array or i is out of range.
+func (v Value) Index(i int) Value {
+	if i < 0 || i >= len(v.array) {
+		return Value{}
+	}
+	return v.array[i]
+}
+
+// Get returns the value associated with the string key in a map, or the zero
+// value if v is not a map or has no such key. When the key appears multiple
+// times the last value wins, like when decoding into a Go map.
+func (v Value) Get(key string) Value {
+	for i := len(v.entries) - 1; i >= 0; i-- {
+		if k, ok := v.entries[i].Key.AsString(); ok && k == key {
+			return v.entries[i].Value
+		}
+	}
+	return Value{}
+}
+
+// Entries returns the entries of a map in the order that they were decoded, or
+// nil if v is not a map. The returned slice must not be modified.
+func (v Value) Entries() []ValueEntry { return v.entries }
+
+// AsBool returns the value of a boolean.
+func (v Value) AsBool() (b bool, ok bool) {
+	b, ok = v.value.(bool)
+	return
+}
+
+// AsInt returns the value of an integer, it also accepts unsigned integers
+// which fit in an int64.
+func (v Value) AsInt() (int64, bool) {
+	switch x := v.value.(type) {
+	case int64:
+		return x, true
+	case uint64:
+		if x <= 1<<63-1 {
+			return int64(x), true
+		}
+	}
+	return 0, false
+}
+
+// AsUint returns the value of an unsigned integer, it also accepts positive
+// signed integers.
+func (v Value) AsUint() (uint64, bool) {
+	switch x := v.value.(type) {
+	case uint64:
+		return x, true
+	case int64:
+		if x >= 0 {
+			return uint64(x), true
+		}
+	}
+	return 0, false
+}
+
+// AsFloat returns the value of a number as a float64, integers are converted.
+func (v Value) AsFloat() (float64, bool) {
+	switch x := v.value.(type) {
+	case float64:
+		return x, true
+	case int64:
+		return float64(x), true
+	case uint64:
+		return float64(x), true
+	}
+	return 0, false
+}
+
+// AsString returns the value of a string.
+func (v Value) AsString() (s string, ok bool) {
+	s, ok = v.value.(string)
+	return
+}
+
+// AsBytes returns the value of a byte slice.
+func (v Value) AsBytes() (b []byte, ok bool) {
+	b, ok = v.value.([]byte)
+	return
+}
+
+// AsTime returns the value of a time.
+func (v Value) AsTime() (t time.Time, ok bool) {
+	t, ok = v.value.(time.Time)
+	return
+}
+
+// AsDuration returns the value of a duration.
+func (v Value) AsDuration() (d time.Duration, ok bool) {
+	d, ok = v.value.(time.Duration)
+	return
+}
+
+// AsError returns the value of an error.
+func (v Value) AsError() (err error, ok bool) {
+	err, ok = v.value.(error)
+	return
+}
+
+// Interface returns the value as the Go types produced when decoding into an
+// empty interface, arrays are returned as []interface{} and maps as
+// map[interface{}]interface{}.
+//
+// Map keys which cannot be used as keys of a Go map are converted to strings:
+// byte slices hold their content, arrays and maps are formatted like String
+// does.
+func (v Value) Interface() interface{} {
+	switch v.typ {
+	case Array:
+		a := make([]interface{}, len(v.array))
+		for i, e := range v.array {
+			a[i] = e.Interface()
+		}
+		return a
+
+	case Map:
+		m := make(map[interface{}]interface{}, len(v.entries))
+		for _, e := range v.entries {
+			m[e.Key.mapKey()] = e.Value.Interface()
+		}
+		return m
+
+	default:
+		return v.value
+	}
+}
+
+// mapKey returns the value as a key of the maps returned by Interface.
+func (v Value) mapKey() interface{} {
+	switch v.typ {
+	case Array, Map:
+		return v.String()
+	case Bytes:
+		b, _ := v.AsBytes()
+		return string(b)
+	default:
+		return v.value
+	}
+}
+
+// String satisfies the fmt.Stringer interface.
+func (v Value) String() string {
+	return fmt.Sprint(v.Interface())
+}
+
+// EncodeValue satisfies the ValueEncoder interface.
+func (v Value) EncodeValue(e Encoder) error {
+	switch v.typ {
+	case Array:
+		i := 0
+		return e.EncodeArray(len(v.array), func(e Encoder) error {
+			i++
+			return e.Encode(v.array[i-1])
+		})
+
+	case Map:
+		i := 0
+		return e.EncodeMap(len(v.entries), func(ke Encoder, ve Encoder) error {
+			i++
+			if err := ke.Encode(v.entries[i-1].Key); err != nil {
+				return err
+			}
+			return ve.Encode(v.entries[i-1].Value)
+		})
+
+	default:
+		return e.Encode(v.value)
+	}
+}
+
+// DecodeValue satisfies the ValueDecoder interface.
+func (v *Value) DecodeValue(d Decoder) (err error) {
+	var t Type
+	var x interface{}
+
+	if t, err = d.Parser.ParseType(); err != nil {
+		return
+	}
+
+	to := reflect.ValueOf(&x).Elem()
+
+	switch t {
+	case Nil:
+		err = d.Parser.ParseNil()
+	case Bool:
+		err = d.decodeInterfaceFrom(boolType, t, to, Decoder.decodeBoolFromType)
+	case Int:
+		err = d.decodeInterfaceFrom(int64Type, t, to, Decoder.decodeIntFromType)
+	case Uint:
+		err = d.decodeInterfaceFrom(uint64Type, t, to, Decoder.decodeUintFromType)
+	case Float:
+		err = d.decodeInterfaceFrom(float64Type, t, to, Decoder.decodeFloatFromType)
+	case String:
+		err = d.decodeInterfaceFrom(stringType, t, to, Decoder.decodeStringFromType)
+	case Bytes:
+		err = d.decodeInterfaceFrom(bytesType, t, to, Decoder.decodeBytesFromType)
+	case Time:
+		err = d.decodeInterfaceFrom(timeType, t, to, Decoder.decodeTimeFromType)
+	case Duration:
+		err = d.decodeInterfaceFrom(durationType, t, to, Decoder.decodeDurationFromType)
+	case Error:
+		err = d.decodeInterfaceFrom(errorInterface, t, to, Decoder.decodeErrorFromType)
+
+	case Array:
+		var a []Value
+		if err = d.decodeArrayImpl(t, func(d Decoder) error {
+			var e Value
+			if err := d.Decode(&e); err != nil {
+				return decodeErrorWithIndex(err, len(a))
+			}
+			a = append(a, e)
+			return nil
+		}); err == nil {
+			*v = Value{typ: t, array: a}
+		}
+		return
+
+	case Map:
+		var m []ValueEntry
+		if err = d.decodeMapImpl(t, func(kd Decoder, vd Decoder) error {
+			var e ValueEntry
+			if err := kd.Decode(&e.Key); err != nil {
+				return err
+			}
+			if err := vd.Decode(&e.Value); err != nil {
+				return decodeErrorWithKey(err, e.Key.Interface())
+			}
+			m = append(m, e)
+			return nil
+		}); err == nil {
+			*v = Value{typ: t, entries: m}
+		}
+		return
+
+	default:
+		err = fmt.Errorf("objconv: parser returned an unsupported value type: %s", t)
+	}
+
+	if err == nil {
+		*v = Value{typ: t, value: x}
+	}
+	return
+}
@@ -0,0 +1,142 @@
+package objconv
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDecoderDecodeAny(t *testing.T) {
+	date := time.Date(2016, 12, 12, 1, 1, 1, 0, time.UTC)
+
+	in := map[string]interface{}{
+		"name":    "objconv",
+		"version": uint64(2),
+		"stars":   int64(-1),
+		"ratio":   0.5,
+		"public":  true,
+		"created": date,
+		"owner":   nil,
+		"users": []interface{}{
+			map[string]interface{}{"name": "Luke", "tags": []interface{}{"jedi", []byte("x")}},
+			map[string]interface{}{"name": "Leia"},
+		},
+	}
+
+	v, err := NewDecoder(NewValueParser(in)).DecodeAny()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Type() != Map || v.Len() != len(in) {
+		t.Fatalf("bad map: %s (%d entries)", v.Type(), v.Len())
+	}
+
+	if s, ok := v.Get("name").AsString(); !ok || s != "objconv" {
+		t.Errorf("bad name: %q", s)
+	}
+
+	if i, ok := v.Get("version").AsInt(); !ok || i != 2 {
+		t.Errorf("bad version: %d", i)
+	}
+
+	if _, ok := v.Get("stars").AsUint(); ok {
+		t.Error("negative integers must not be returned as unsigned integers")
+	}
+
+	if f, ok := v.Get("stars").AsFloat(); !ok || f != -1 {
+		t.Errorf("bad stars: %g", f)
+	}
+
+	if f, ok := v.Get("ratio").AsFloat(); !ok || f != 0.5 {
+		t.Errorf("bad ratio: %g", f)
+	}
+
+	if b, ok := v.Get("public").AsBool(); !ok || !b {
+		t.Errorf("bad public: %t", b)
+	}
+
+	if d, ok := v.Get("created").AsTime(); !ok || !d.Equal(date) {
+		t.Errorf("bad created: %s", d)
+	}
+
+	if !v.Get("owner").IsNil() {
+		t.Errorf("bad owner: %s", v.Get("owner").Type())
+	}
+
+	users := v.Get("users")
+
+	if users.Type() != Array || users.Len() != 2 {
+		t.Fatalf("bad users: %s (%d elements)", users.Type(), users.Len())
+	}
+
+	if s, ok := users.Index(1).Get("name").AsString(); !ok || s != "Leia" {
+		t.Errorf("bad user name: %q", s)
+	}
+
+	if s, ok := users.Index(0).Get("tags").Index(0).AsString(); !ok || s != "jedi" {
+		t.Errorf("bad tag: %q", s)
+	}
+
+	if b, ok := users.Index(0).Get("tags").Index(1).AsBytes(); !ok || string(b) != "x" {
+		t.Errorf("bad tag: %q", b)
+	}
+
+	// Missing elements produce zero values which can be traversed further.
+	for _, m := range []Value{
+		v.Get("missing"),
+		v.Get("name").Get("name"),
+		users.Index(2).Get("name"),
+		users.Index(-1),
+		v.Index(0),
+	} {
+		if m.Type() != Unknown {
+			t.Errorf("expected the zero value but got %s", m.Type())
+		}
+		if _, ok := m.AsString(); ok {
+			t.Error("the zero value must not be a string")
+		}
+	}
+
+	t.Run("encode", func(t *testing.T) {
+		e := NewValueEmitter()
+
+		if err := NewEncoder(e).Encode(v); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(e.Value(), v.Interface()) {
+			t.Errorf("%#v != %#v", e.Value(), v.Interface())
+		}
+
+		if s, ok := v.Interface().(map[interface{}]interface{})["name"]; !ok || s != "objconv" {
+			t.Errorf("bad name: %#v", s)
+		}
+	})
+}
+
+func TestValueInterfaceUnhashableKeys(t *testing.T) {
+	type point struct {
+		X int `objconv:"x"`
+	}
+
+	in := map[interface{}]interface{}{
+		[2]int{1, 2}: "array",
+		point{X: 1}:  "map",
+	}
+
+	v, err := NewDecoder(NewValueParser(in)).DecodeAny()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := v.Interface()
+	exp := map[interface{}]interface{}{
+		"[1 2]":    "array",
+		"map[x:1]": "map",
+	}
+
+	if !reflect.DeepEqual(out, exp) {
+		t.Errorf("%#v != %#v", out, exp)
+	}
+}
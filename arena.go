@@ -0,0 +1,125 @@
+package objconv
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// An Arena is a memory allocator that decoders may use to allocate the backing
+// arrays of the strings, byte slices and slices that they produce.
+//
+// Programs decoding large volumes of data can use an arena to reduce the
+// pressure on the garbage collector, all values allocated from an arena are
+// released at once when the program is done with them.
+//
+// Values decoded with an arena share its memory, they must not be used after
+// the arena was reset or reused. Only slices of types that contain no pointers
+// are allocated from the arena, other slices and maps are always allocated by
+// the Go runtime.
+type Arena interface {
+	// Bytes returns a byte slice of length n. The content of the slice is not
+	// required to be zeroed.
+	Bytes(n int) []byte
+}
+
+// BumpArena is a simple implementation of the Arena interface which allocates
+// memory by advancing an offset into large chunks of memory.
+//
+// BumpArena values are not safe for use by multiple goroutines.
+type BumpArena struct {
+	// ChunkSize is the size of the memory chunks allocated by the arena, zero
+	// means the default of 64 KB.
+	ChunkSize int
+
+	buf []byte
+	off int
+}
+
+// Bytes satisfies the Arena interface.
+func (a *BumpArena) Bytes(n int) []byte {
+	if n > len(a.buf)-a.off {
+		size := a.ChunkSize
+		if size <= 0 {
+			size = 65536
+		}
+		if size < n {
+			size = n
+		}
+		a.buf, a.off = make([]byte, size), 0
+	}
+	b := a.buf[a.off : a.off+n : a.off+n]
+	a.off += n
+	return b
+}
+
+// Reset makes the memory of the arena available for new allocations. All the
+// values previously allocated from the arena become invalid.
+func (a *BumpArena) Reset() {
+	a.off = 0
+}
+
+func arenaString(a Arena, b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	s := a.Bytes(len(b))
+	copy(s, b)
+	return unsafeString(s)
+}
+
+func arenaBytes(a Arena, b []byte) []byte {
+	s := a.Bytes(len(b))
+	copy(s, b)
+	return s
+}
+
+// arenaSlice returns a slice of type t and length n allocated from a, or an
+// invalid value if the slice elements cannot be allocated from an arena.
+func arenaSlice(a Arena, t reflect.Type, n int) reflect.Value {
+	e := t.Elem()
+
+	if n == 0 || e.Size() == 0 || hasPointers(e) {
+		return reflect.Value{}
+	}
+
+	size := int(e.Size()) * n
+	align := e.Align()
+	b := a.Bytes(size + align - 1)
+	off := 0
+
+	if r := int(uintptr(unsafe.Pointer(&b[0])) % uintptr(align)); r != 0 {
+		off = align - r
+	}
+
+	b = b[off : off+size]
+
+	for i := range b {
+		b[i] = 0
+	}
+
+	return reflect.NewAt(reflect.ArrayOf(n, e), unsafe.Pointer(&b[0])).Elem().Slice(0, n).Convert(t)
+}
+
+func hasPointers(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return false
+
+	case reflect.Array:
+		return hasPointers(t.Elem())
+
+	case reflect.Struct:
+		for i, n := 0, t.NumField(); i != n; i++ {
+			if hasPointers(t.Field(i).Type) {
+				return true
+			}
+		}
+		return false
+
+	default:
+		return true
+	}
+}
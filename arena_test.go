@@ -0,0 +1,78 @@
+package objconv
+
+import (
+	"reflect"
+	"testing"
+	"unsafe"
+)
+
+func TestBumpArena(t *testing.T) {
+	a := &BumpArena{ChunkSize: 16}
+
+	b1 := a.Bytes(10)
+	b2 := a.Bytes(6)
+	b3 := a.Bytes(32)
+
+	if len(b1) != 10 || len(b2) != 6 || len(b3) != 32 {
+		t.Fatal("invalid lengths:", len(b1), len(b2), len(b3))
+	}
+
+	if cap(b1) != 10 {
+		t.Error("the capacity of arena slices must be bounded to their length:", cap(b1))
+	}
+
+	if uintptr(unsafe.Pointer(&b1[0]))+10 != uintptr(unsafe.Pointer(&b2[0])) {
+		t.Error("consecutive allocations must share the same chunk")
+	}
+}
+
+func TestDecoderArena(t *testing.T) {
+	type T struct {
+		S string
+		B []byte
+		I []int64
+		P []*int
+	}
+
+	x := 42
+	a := &BumpArena{}
+	v := T{}
+	d := Decoder{
+		Parser: NewValueParser(T{S: "Hello", B: []byte("World"), I: []int64{1, 2, 3}, P: []*int{&x}}),
+		Arena:  a,
+	}
+
+	if err := d.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	if v.S != "Hello" || string(v.B) != "World" || !reflect.DeepEqual(v.I, []int64{1, 2, 3}) || *v.P[0] != 42 {
+		t.Errorf("%#v", v)
+	}
+
+	inArena := func(p unsafe.Pointer) bool {
+		b := a.buf[:cap(a.buf)]
+		start := uintptr(unsafe.Pointer(&b[0]))
+		return uintptr(p) >= start && uintptr(p) < start+uintptr(len(b))
+	}
+
+	if !inArena(*(*unsafe.Pointer)(unsafe.Pointer(&v.S))) { // data pointer of the string
+		t.Error("the string was not allocated from the arena")
+	}
+
+	if !inArena(unsafe.Pointer(&v.B[0])) {
+		t.Error("the byte slice was not allocated from the arena")
+	}
+
+	if !inArena(unsafe.Pointer(&v.I[0])) {
+		t.Error("the int64 slice was not allocated from the arena")
+	}
+
+	if uintptr(unsafe.Pointer(&v.I[0]))%unsafe.Alignof(v.I[0]) != 0 {
+		t.Error("the int64 slice is not aligned")
+	}
+
+	if inArena(unsafe.Pointer(&v.P[0])) {
+		t.Error("slices of pointers must not be allocated from the arena")
+	}
+}
@@ -0,0 +1,87 @@
+//go:build go1.19
+// +build go1.19
+
+package objconv
+
+import (
+	"reflect"
+	"sync/atomic"
+)
+
+// The atomic.Int64, atomic.Uint64 and atomic.Bool types were added in Go 1.19,
+// programs built with older versions of Go decode and encode these types like
+// any other struct.
+
+var (
+	atomicInt64Type  = reflect.TypeOf(atomic.Int64{})
+	atomicUint64Type = reflect.TypeOf(atomic.Uint64{})
+	atomicBoolType   = reflect.TypeOf(atomic.Bool{})
+)
+
+func makeAtomicDecodeFunc(t reflect.Type) decodeFunc {
+	switch t {
+	case atomicInt64Type:
+		return Decoder.decodeAtomicInt64
+	case atomicUint64Type:
+		return Decoder.decodeAtomicUint64
+	case atomicBoolType:
+		return Decoder.decodeAtomicBool
+	default:
+		return nil
+	}
+}
+
+func makeAtomicEncodeFunc(t reflect.Type) encodeFunc {
+	switch t {
+	case atomicInt64Type:
+		return Encoder.encodeAtomicInt64
+	case atomicUint64Type:
+		return Encoder.encodeAtomicUint64
+	case atomicBoolType:
+		return Encoder.encodeAtomicBool
+	default:
+		return nil
+	}
+}
+
+// decodeAtomicInt64 decodes atomic.Int64 values like int64 values, the decoded
+// integer is stored atomically.
+func (d Decoder) decodeAtomicInt64(to reflect.Value) (t Type, err error) {
+	var v int64
+	if t, err = d.decodeInt(reflect.ValueOf(&v).Elem()); err == nil && to.IsValid() {
+		to.Addr().Interface().(*atomic.Int64).Store(v)
+	}
+	return
+}
+
+// decodeAtomicUint64 decodes atomic.Uint64 values like uint64 values, the
+// decoded integer is stored atomically.
+func (d Decoder) decodeAtomicUint64(to reflect.Value) (t Type, err error) {
+	var v uint64
+	if t, err = d.decodeUint(reflect.ValueOf(&v).Elem()); err == nil && to.IsValid() {
+		to.Addr().Interface().(*atomic.Uint64).Store(v)
+	}
+	return
+}
+
+// decodeAtomicBool decodes atomic.Bool values like bool values, the decoded
+// boolean is stored atomically.
+func (d Decoder) decodeAtomicBool(to reflect.Value) (t Type, err error) {
+	var v bool
+	if t, err = d.decodeBool(reflect.ValueOf(&v).Elem()); err == nil && to.IsValid() {
+		to.Addr().Interface().(*atomic.Bool).Store(v)
+	}
+	return
+}
+
+func (e Encoder) encodeAtomicInt64(v reflect.Value) error {
+	return e.Emitter.EmitInt(atomicPtr(v).(*atomic.Int64).Load(), 64)
+}
+
+func (e Encoder) encodeAtomicUint64(v reflect.Value) error {
+	return e.Emitter.EmitUint(atomicPtr(v).(*atomic.Uint64).Load(), 64)
+}
+
+func (e Encoder) encodeAtomicBool(v reflect.Value) error {
+	return e.Emitter.EmitBool(atomicPtr(v).(*atomic.Bool).Load())
+}
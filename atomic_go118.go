@@ -0,0 +1,10 @@
+//go:build !go1.19
+// +build !go1.19
+
+package objconv
+
+import "reflect"
+
+func makeAtomicDecodeFunc(t reflect.Type) decodeFunc { return nil }
+
+func makeAtomicEncodeFunc(t reflect.Type) encodeFunc { return nil }
@@ -0,0 +1,56 @@
+//go:build go1.19
+// +build go1.19
+
+package objconv
+
+import (
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDecoderAtomic(t *testing.T) {
+	type T struct {
+		A atomic.Int64
+		B atomic.Uint64
+		C atomic.Bool
+		D atomic.Value
+		E *atomic.Int64
+	}
+
+	in := map[string]interface{}{
+		"A": -1,
+		"B": uint64(1) << 63,
+		"C": true,
+		"D": "hello",
+		"E": 42,
+	}
+
+	var v T
+
+	if err := NewDecoder(NewValueParser(in)).Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	if v.A.Load() != -1 || v.B.Load() != 1<<63 || !v.C.Load() || v.D.Load() != "hello" || v.E == nil || v.E.Load() != 42 {
+		t.Errorf("bad value: %d %d %t %#v %v", v.A.Load(), v.B.Load(), v.C.Load(), v.D.Load(), v.E)
+	}
+
+	e := NewValueEmitter()
+
+	if err := NewEncoder(e).Encode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := map[interface{}]interface{}{
+		"A": int64(-1),
+		"B": uint64(1) << 63,
+		"C": true,
+		"D": "hello",
+		"E": int64(42),
+	}
+
+	if x := e.Value(); !reflect.DeepEqual(x, expect) {
+		t.Errorf("bad encoded value: %#v", x)
+	}
+}
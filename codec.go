@@ -1,6 +1,7 @@
 package objconv
 
 import (
+	"bytes"
 	"io"
 	"sync"
 )
@@ -31,6 +32,22 @@ func (c Codec) NewStreamDecoder(r io.Reader) *StreamDecoder {
 	return NewStreamDecoder(c.NewParser(r))
 }
 
+// Marshal encodes v with the emitter of codec and returns the serialized bytes.
+func Marshal(v interface{}, codec Codec) ([]byte, error) {
+	var b bytes.Buffer
+
+	if err := codec.NewEncoder(&b).Encode(v); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// Unmarshal decodes b with the parser of codec and loads the result into v.
+func Unmarshal(b []byte, v interface{}, codec Codec) error {
+	return codec.NewDecoder(bytes.NewReader(b)).Decode(v)
+}
+
 // A Registry associates mime types to codecs.
 //
 // It is safe to use a registry concurrently from multiple goroutines.
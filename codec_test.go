@@ -0,0 +1,74 @@
+package objconv
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+// stringCodec is a minimal codec for documents holding a single string, it is
+// used to test the codec functions without depending on the format packages.
+var stringCodec = Codec{
+	NewEmitter: func(w io.Writer) Emitter { return &stringEmitter{ValueEmitter: NewValueEmitter(), w: w} },
+	NewParser:  func(r io.Reader) Parser { return &stringParser{r: r} },
+}
+
+type stringEmitter struct {
+	*ValueEmitter
+	w io.Writer
+}
+
+func (e *stringEmitter) EmitString(v string) error {
+	_, err := io.WriteString(e.w, v)
+	return err
+}
+
+func (e *stringEmitter) EmitInt(int64, int) error {
+	return errors.New("the string codec only supports strings")
+}
+
+type stringParser struct {
+	*ValueParser
+	r io.Reader
+}
+
+func (p *stringParser) ParseType() (Type, error) {
+	if p.ValueParser == nil {
+		b, err := ioutil.ReadAll(p.r)
+		if err != nil {
+			return Nil, err
+		}
+		p.ValueParser = NewValueParser(string(b))
+	}
+	return p.ValueParser.ParseType()
+}
+
+func TestMarshalUnmarshal(t *testing.T) {
+	b, err := Marshal("Hello World!", stringCodec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(b) != "Hello World!" {
+		t.Errorf("bad output: %q", b)
+	}
+
+	var s string
+	if err := Unmarshal(b, &s, stringCodec); err != nil {
+		t.Fatal(err)
+	}
+
+	if s != "Hello World!" {
+		t.Errorf("bad value: %q", s)
+	}
+
+	var n int
+	if err := Unmarshal([]byte("42"), &n, stringCodec); err != nil || n != 42 {
+		t.Errorf("bad value: %d (%v)", n, err)
+	}
+
+	if b, err := Marshal(42, stringCodec); err == nil {
+		t.Errorf("expected an error but got %q", b)
+	}
+}
@@ -1,12 +1,27 @@
 package objconv
 
 import (
+	"bytes"
 	"encoding"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"net"
+	"net/url"
 	"reflect"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode"
+	"unicode/utf8"
 	"unsafe"
 
 	"github.com/segmentio/objconv/objutil"
@@ -24,9 +39,449 @@ type Decoder struct {
 	// there is not destination type (when decoding to an empty interface).
 	MapType reflect.Type
 
-	off int // offset of the value when decoding a map
+	// FuzzyFieldMatch enables matching of unknown keys to struct fields with
+	// the closest name (by edit distance), which is useful to tolerate typos in
+	// configuration files written by humans. A key which is equally close to
+	// two fields or more is reported as an error.
+	FuzzyFieldMatch bool
+
+	// FuzzyFieldDistance is the maximum edit distance allowed between a key and
+	// a field name when FuzzyFieldMatch is enabled. Zero means the default of 2.
+	FuzzyFieldDistance int
+
+	// KeyRewriter, when not nil, is applied to the keys of maps decoded into
+	// structs before matching them with the names of the struct fields. It can
+	// be used to adapt naming conventions, for example to let "user-name" match
+	// a field named "UserName" with a function removing dashes and converting
+	// keys to camel case.
+	//
+	// The keys reported as unknown fields, and the keys that JSON pointers of
+	// jsonpath fields resolve through, are not rewritten.
+	KeyRewriter func(rawKey string) string
+
+	// SizeProfile, when not nil, records the sizes of strings, arrays and maps
+	// seen by the decoder.
+	SizeProfile *SizeProfile
+
+	// RequireSortedKeys makes the decoder verify that the keys of maps appear
+	// in increasing order, which is useful to validate documents that claim to
+	// use a canonical encoding.
+	RequireSortedKeys bool
+
+	// KeyCompare defines the ordering of keys that is enforced when
+	// RequireSortedKeys is set. The function must return a negative number if
+	// the first key sorts before the second, zero if they are equal and a
+	// positive number otherwise.
+	//
+	// When nil, CompareKeys is used.
+	KeyCompare func(interface{}, interface{}) int
+
+	// RejectDuplicateKeys makes the decoder return an error when a key appears
+	// more than once in a map decoded into a Go map or struct, instead of
+	// letting the last value win. Keys of maps with interface keys which are
+	// numerically equal (like 1 and 1.0) are considered duplicates.
+	RejectDuplicateKeys bool
+
+	// NilPointersAsZeroValue makes the decoder set pointers to a newly
+	// allocated zero value when decoding null values, instead of setting them
+	// to nil. For example a null value decoded into a *int produces a pointer
+	// to 0. This applies to pointers anywhere in the decoded values, including
+	// the values of maps and struct fields.
+	//
+	// Null values decoded into empty interfaces still produce nil interface
+	// values, since there is no pointer type to allocate in this case.
+	NilPointersAsZeroValue bool
+
+	// MergeMaps makes the decoder add the entries of decoded maps to the
+	// existing entries of non-nil maps that it decodes into, instead of
+	// replacing their content. Keys which already exist in the maps have their
+	// values overwritten. This is useful to layer configuration files for
+	// example, where each file overrides some of the values of the previous
+	// ones.
+	//
+	// When decoding a map fails the existing map may have been partially
+	// modified.
+	MergeMaps bool
+
+	// Lazy delays decoding the values of maps of type map[string]interface{},
+	// each value is stored in the map as a LazyValue which is only decoded
+	// when its Decode method is called. See LazyValue for details.
+	Lazy bool
+
+	// PairArraysAsMaps allows maps to be decoded from arrays of two-element
+	// arrays, where each element holds a key and its associated value, for
+	// example [["a",1],["b",2]].
+	PairArraysAsMaps bool
+
+	// Arena, when not nil, is used to allocate the memory of strings, byte
+	// slices and slices of types that contain no pointers.
+	//
+	// Values decoded with an arena must not outlive it, see the Arena type for
+	// more details.
+	Arena Arena
+
+	// InterfaceTimeAsString controls how time, duration and error values are
+	// decoded into empty interfaces. By default the decoder produces values of
+	// type time.Time, time.Duration and error for parsers that support these
+	// types natively (like msgpack or cbor), setting this field makes it
+	// produce strings instead, which is consistent with the values produced
+	// with formats like json.
+	InterfaceTimeAsString bool
+
+	// DisallowUnknownFields makes the decoder return an error when decoding a
+	// map into a struct and a key doesn't match any of the struct fields.
+	DisallowUnknownFields bool
+
+	// OnUnknownField, when not nil, is called with the keys of maps decoded into
+	// structs which don't match any of the struct fields, for example to record
+	// them. Decoding is aborted with the error returned by the function, or
+	// continues like when the function is not set if it returns nil, so keys
+	// are still rejected when DisallowUnknownFields is also set.
+	OnUnknownField func(key string) error
+
+	// PromoteOverflowToFloat makes the decoder produce float64 values instead
+	// of failing when decoding integers that don't fit in 64 bits into empty
+	// interfaces, and for unsigned integers that don't fit in an int64.
+	//
+	// The float64 type has only 53 bits of mantissa, so the promoted values
+	// may lose precision. This is off by default so the decoder never alters
+	// numbers silently.
+	PromoteOverflowToFloat bool
+
+	// DefaultFunc, when not nil, is called to construct the values which the
+	// decoder resets destinations to, instead of their zero values, when it
+	// decodes null values into slices, arrays, maps, pointers, and interfaces,
+	// or when it fails to decode a struct. The function returns false to use
+	// the zero value of the type.
+	//
+	// The returned value must be assignable to the type, and should not be
+	// shared between calls when it holds references (like a map), since it may
+	// be stored in multiple places.
+	DefaultFunc func(reflect.Type) (reflect.Value, bool)
+
+	// OnLossyConversion, when not nil, is called when the decoder converts a
+	// number to a type which cannot represent it exactly: integers beyond 2^53
+	// promoted to float64 by PromoteOverflowToFloat, numbers rounded to the
+	// precision of float32 values (integers beyond 2^24 or float64 values),
+	// and floating point values with a fractional part truncated to integers
+	// with WeaklyTypedInput. Other conversions, like parsing strings holding
+	// decimal numbers, are not reported. The function receives the type of the
+	// parsed value, the destination type, and a description of the conversion.
+	// It is only used for observability, the conversions happen regardless.
+	OnLossyConversion func(from Type, to reflect.Type, detail string)
+
+	// ErrorHandler, when not nil, is called with the index and the error of the
+	// elements of arrays which fail to decode into slices. If the function
+	// returns nil the element is skipped and decoding continues with the next
+	// one, otherwise decoding is aborted with the returned error. SkippedErrors
+	// can be used to collect the errors of the skipped elements.
+	//
+	// Skipping the remaining bytes of an element requires the parser to
+	// implement both RawParser and Reparser, which is the case of the json
+	// parser: each element is read with ParseRaw and decoded from a parser
+	// returned by Reparse. Elements exposed as Go values by a DirectParser are
+	// also skipped. With other parsers the function is called but decoding is
+	// aborted with the original error even if it returns nil, because the
+	// parser cannot find where the element ends. Malformed elements are
+	// skipped as long as the parser can find where they end, errors reading
+	// the input (like unterminated strings) still abort decoding.
+	ErrorHandler func(index int, err error) error
+
+	// RawEmitter is used to decode RawValue destinations when the parser does
+	// not implement the RawParser interface. The parsed value is re-encoded
+	// with the emitter returned by the function, which must produce the same
+	// format than the one read by the parser.
+	RawEmitter func(io.Writer) Emitter
+
+	// Registry is used to construct values when decoding into non-empty
+	// interface types, see TypeRegistry for more details. Decoding into
+	// non-empty interfaces fails when Registry is nil.
+	Registry *TypeRegistry
+
+	// ProgressFunc, when not nil, is called periodically while decoding arrays
+	// with the number of elements decoded so far, and once more when reaching
+	// the end of the array.
+	//
+	// Arrays nested in an array that reports progress do not report progress
+	// themselves, so the function only observes the outermost arrays.
+	ProgressFunc func(decoded int)
+
+	// ProgressInterval is the number of elements decoded between calls to
+	// ProgressFunc. Zero means the default of 1000.
+	ProgressInterval int
+
+	// TimeLayouts is the list of layouts tried in order when decoding time
+	// values from strings, the first layout that successfully parses the string
+	// is used. When empty, strings are expected to be formatted with
+	// time.RFC3339Nano.
+	//
+	// Two special layouts are supported, "unix" and "unixmilli", which parse
+	// strings holding integers as the number of seconds or milliseconds elapsed
+	// since the unix epoch.
+	TimeLayouts []string
+
+	// AssumeLocalTime makes the decoder interpret time values decoded from
+	// strings which have no time zone information, like "2006-01-02 15:04:05",
+	// in the location set by TimeLocation instead of UTC. Strings which carry
+	// a zone offset are not affected.
+	//
+	// This is useful to ingest data from systems that report local wall-clock
+	// times, which is sometimes the case of logs.
+	AssumeLocalTime bool
+
+	// TimeLocation is the location used when AssumeLocalTime is set. When nil,
+	// time.Local is used.
+	TimeLocation *time.Location
+
+	// DurationUnit is the unit of numbers decoded into time.Duration values,
+	// formats which have no native representation for durations often encode
+	// them as a number of seconds (with a fractional part for sub-second
+	// precision) or milliseconds. Zero means the default of time.Second.
+	DurationUnit time.Duration
+
+	// TimeUnit is the unit of numbers decoded into time.Time values, which are
+	// interpreted as the time elapsed since the Unix epoch, like the timestamps
+	// of many systems. Floating point values carry sub-unit precision. Zero means
+	// the default of time.Second. The times are produced in UTC.
+	TimeUnit time.Duration
+
+	// WeaklyTypedInput enables conversions of scalar values which are useful
+	// to decode inputs produced by programs that quote all values, like
+	// "true" for a boolean. When set, strings holding "1", "t", "T", "TRUE",
+	// "true", "True", "0", "f", "F", "FALSE", "false" or "False" (the values
+	// accepted by strconv.ParseBool) can be decoded into booleans. Booleans can
+	// also be decoded into integers (true is 1 and false is 0), and integers
+	// into booleans (zero is false and other values are true), for formats
+	// which mix up both.
+	//
+	// Floating point values can also be decoded into integers when it's set,
+	// for formats which have a single number type: the fractional part is
+	// discarded (like a Go conversion, rounding toward zero) and reported to
+	// OnLossyConversion, and values out of the range of the integer type are
+	// errors like integers would be. Without the option decoding a floating
+	// point value into an integer is an error.
+	//
+	// Strings holding numbers can always be decoded into numeric types, like
+	// numbers can be decoded into strings, regardless of this option.
+	WeaklyTypedInput bool
+
+	// BoolStrings, when not nil, is the table of strings which can be decoded
+	// into booleans, with the values that they map to, for inputs like INI
+	// files which spell booleans "yes" or "off". The keys of the table must be
+	// lower case, the strings are matched regardless of case by looking up
+	// their lower case form. The table replaces the strings accepted with
+	// WeaklyTypedInput, which doesn't need to be set. DefaultBoolStrings
+	// returns a table of the common spellings.
+	BoolStrings map[string]bool
+
+	// EnumParser, when not nil, maps named types of a boolean, numeric or
+	// string kind to the functions used to convert the strings decoded into
+	// values of these types, for enums which have a function like
+	// ParseColor(string) (Color, error) and don't implement
+	// encoding.TextUnmarshaler. The functions must return values of the type
+	// that they are set for. Other types of values are decoded according to the
+	// kind of the type, for example integers can still be decoded into a type
+	// of the int kind.
+	EnumParser map[reflect.Type]func(string) (interface{}, error)
+
+	// AllowSpecialFloats makes the decoder produce float64 values instead of
+	// strings when it decodes the strings "NaN", "Infinity" and "-Infinity"
+	// into empty interfaces, so they can be told apart from other strings.
+	// Formats which can't represent these special values natively often encode
+	// them this way.
+	//
+	// Strings decoded into floating point values are parsed with
+	// strconv.ParseFloat whether this option is set or not, which accepts
+	// these spellings and others like "Inf" or "-inf".
+	AllowSpecialFloats bool
+
+	// StringHook, when not nil, is called with the content of the strings and
+	// byte slices read from the parser before they are decoded, including the
+	// map keys. It may return a transformed version of its argument, for
+	// example to trim white spaces or normalize unicode, or an error which
+	// aborts decoding. The slice passed to the function may be reused by the
+	// parser, it must not be retained.
+	StringHook func([]byte) ([]byte, error)
+
+	// ValueHook, when not nil, is called after each value of a scalar Go type
+	// (booleans, numbers, strings, byte slices, time.Time and time.Duration)
+	// was decoded, including map keys and scalar values stored in empty
+	// interfaces, with the type of the parsed value and the value that it was
+	// decoded into. Returning an error aborts decoding, which makes it possible
+	// to enforce invariants on the decoded values in a single place.
+	ValueHook func(Type, reflect.Value) error
+
+	// StrictArrayLength makes decoding into Go arrays return an error when the
+	// input has more elements than the length of the array. By default the
+	// elements which don't fit are discarded. Inputs with fewer elements than
+	// the length of the array are always reported as errors.
+	StrictArrayLength bool
+
+	// ArrayToStruct allows structs to be decoded from arrays, the elements are
+	// decoded into the fields by position, in the order in which the fields
+	// are declared (the fields of embedded structs come after those of the
+	// struct itself). This is useful for tabular formats where each row is an
+	// array of columns. Structs declaring the `positional` tag option on any
+	// of their fields, usually a `_ struct{}` placeholder, are decoded from
+	// arrays this way regardless of this option.
+	//
+	// Arrays with fewer elements than the struct has fields leave the other
+	// fields unchanged, unless they are required. Arrays with more elements
+	// are reported as errors, unless ArrayToStructTruncate is set, in which
+	// case the extra elements are discarded.
+	ArrayToStruct bool
+
+	// ArrayToStructTruncate makes the decoder discard the elements of arrays
+	// decoded into structs by position which have no matching field, instead
+	// of returning an error.
+	ArrayToStructTruncate bool
+
+	// ScalarToSlice allows slices to be decoded from values that are not
+	// arrays, the value is decoded as the single element of the slice. This is
+	// useful for formats where collections of one element are represented by
+	// the element itself.
+	//
+	// The option only applies when the destinations are slices, values stored
+	// in empty interfaces (including the values of maps with fast decoding
+	// paths like map[string]interface{}) are not converted. Byte slices are
+	// not affected either.
+	ScalarToSlice bool
+
+	// ReuseSlices makes the decoder reuse the backing arrays of non-nil slices
+	// that it decodes into, a new array is only allocated when the decoded
+	// array has more elements than the capacity of the slice. This reduces
+	// allocations in programs that repeatedly decode into the same values.
+	//
+	// Elements are reset to their zero value before being decoded, and the
+	// elements past the decoded length are zeroed as well, so no data from the
+	// previous content of the slice is retained.
+	ReuseSlices bool
+
+	// MaxDepth limits the nesting depth of arrays and maps that the decoder
+	// accepts, which protects programs decoding untrusted input from running
+	// out of stack space. Zero means no limit.
+	MaxDepth int
+
+	// LeadingZeroPolicy controls how strings holding integers with leading
+	// zeros, like "007", are interpreted when they are decoded into integer
+	// types. The default is to ignore the leading zeros and parse the integers
+	// as decimal numbers.
+	LeadingZeroPolicy LeadingZeroPolicy
+
+	// ControlCharPolicy controls how control characters (like NUL, escape, or
+	// line feeds) are handled in decoded strings, which is useful to sanitize
+	// untrusted input before displaying or logging it. It applies to strings
+	// decoded into string types, empty interfaces, and the values of maps of
+	// type map[string]string. The default is to allow them.
+	ControlCharPolicy ControlCharPolicy
+
+	// BytesEncoding is the encoding of strings decoded into byte slices, which
+	// lets programs decode binary data from formats that have no native
+	// representation for it. The default is to use the bytes of the strings
+	// as-is, unless the parser applies its own transformation (the json parser
+	// expects byte slices to be encoded in standard base64 for example).
+	//
+	// The encoding only applies to string values, values that the parser
+	// reports as bytes are always used as-is.
+	BytesEncoding BytesEncoding
+
+	// UseNumber makes the decoder produce values of type Number instead of
+	// int64, uint64 or float64 when decoding numbers into empty interfaces,
+	// which preserves their textual representation. It takes precedence over
+	// PromoteOverflowToFloat.
+	UseNumber bool
+
+	// DetectBase64 makes the decoder produce byte slices instead of strings
+	// when decoding strings that look like base64 into empty interfaces. This
+	// is useful to recover binary data embedded in formats like json, which
+	// have no native representation for it, when the schema is unknown.
+	//
+	// The detection is a heuristic: only strings of at least 16 characters
+	// which are valid base64 with the standard alphabet and strict padding are
+	// converted. Ordinary strings may match these rules (for example any word
+	// of 16 letters does), so this option must only be enabled when false
+	// positives are acceptable.
+	DetectBase64 bool
+
+	// RegexpPOSIX makes the decoder compile *regexp.Regexp values with
+	// regexp.CompilePOSIX instead of regexp.Compile, restricting the patterns
+	// to the POSIX ERE syntax and using leftmost-longest matching.
+	RegexpPOSIX bool
+
+	// WrapMapErrors makes DecodeMap and DecodeMapN wrap the errors returned by
+	// their callbacks in DecodeError values carrying the key of the entry that
+	// failed, like the errors of maps decoded into Go values. The key is the
+	// value successfully decoded by the Decode method of the key decoder,
+	// errors are returned unchanged when the callback fails before that.
+	WrapMapErrors bool
+
+	off      int          // offset of the value when decoding a map
+	depth    int          // nesting depth of the value being decoded
+	warnings *[]string    // warnings collected for the struct being decoded
+	mapKey   *interface{} // receives the key decoded with WrapMapErrors
+
+	// discriminator which selected the type of the union being decoded
+	discriminator string
 }
 
+// LeadingZeroPolicy is an enumeration of the ways integers with leading zeros
+// can be interpreted when they are decoded from strings.
+type LeadingZeroPolicy int
+
+const (
+	// LeadingZeroDecimal ignores the leading zeros and parses the integers as
+	// decimal numbers, "007" and "010" are decoded as 7 and 10.
+	LeadingZeroDecimal LeadingZeroPolicy = iota
+
+	// LeadingZeroOctal parses integers with leading zeros as octal numbers,
+	// "007" and "010" are decoded as 7 and 8.
+	LeadingZeroOctal
+
+	// LeadingZeroError rejects integers with leading zeros, which matches the
+	// grammar of numbers in JSON.
+	LeadingZeroError
+)
+
+// ControlCharPolicy is an enumeration of the ways control characters can be
+// handled in decoded strings. Control characters are the unicode characters of
+// the Cc category, which includes tabs and line feeds.
+type ControlCharPolicy int
+
+const (
+	// ControlCharAllow leaves the control characters in the strings.
+	ControlCharAllow ControlCharPolicy = iota
+
+	// ControlCharReject makes decoding strings with control characters fail.
+	ControlCharReject
+
+	// ControlCharStrip removes the control characters from the strings.
+	ControlCharStrip
+
+	// ControlCharEscape replaces control characters with their escaped form
+	// in Go syntax, for example "\n" or "\x00".
+	ControlCharEscape
+)
+
+// BytesEncoding is an enumeration of the encodings of binary data in strings
+// that decoders support.
+type BytesEncoding int
+
+const (
+	// BytesRaw uses the bytes of the strings as-is.
+	BytesRaw BytesEncoding = iota
+
+	// BytesBase64 decodes strings with the standard base64 alphabet, with or
+	// without padding.
+	BytesBase64
+
+	// BytesBase64URL decodes strings with the URL-safe base64 alphabet, with
+	// or without padding.
+	BytesBase64URL
+
+	// BytesHex decodes strings of hexadecimal digits.
+	BytesHex
+)
+
 // NewDecoder returns a decoder object that uses p, will panic if p is nil.
 func NewDecoder(p Parser) *Decoder {
 	if p == nil {
@@ -35,12 +490,48 @@ func NewDecoder(p Parser) *Decoder {
 	return &Decoder{Parser: p}
 }
 
+// Reset makes the decoder read values from p, so it can be reused to decode
+// another input (for example a decoder retrieved from a sync.Pool). The
+// internal state of the decoder is cleared, its exported fields which
+// configure the decoding algorithms are preserved.
+//
+// The method panics if p is nil.
+func (d *Decoder) Reset(p Parser) {
+	if p == nil {
+		panic("objconv: the parser is nil")
+	}
+	d.Parser = p
+	d.off = 0
+	d.depth = 0
+	d.warnings = nil
+	d.mapKey = nil
+	d.discriminator = ""
+}
+
 // Decode expects v to be a pointer to a value in which the decoder will load
 // the next parsed data.
 //
 // The method panics if v is neither a pointer type nor implements the
 // ValueDecoder interface, or if v is a nil pointer.
 func (d Decoder) Decode(v interface{}) error {
+	if d.mapKey != nil {
+		return d.decodeMapKey(v)
+	}
+
+	// The position is only added to the errors of top-level values, so the
+	// decode errors of nested values always appear within the position error.
+	if p, ok := d.Parser.(PositionParser); ok && d.depth == 0 {
+		if err := d.decodeValue(v); err != nil {
+			return positionError(err, p)
+		}
+		return nil
+	}
+
+	return d.decodeValue(v)
+}
+
+// decodeValue implements Decode, without adding positions to the errors.
+func (d Decoder) decodeValue(v interface{}) error {
 	to := reflect.ValueOf(v)
 
 	if d.off != 0 {
@@ -60,6 +551,8 @@ func (d Decoder) Decode(v interface{}) error {
 	// Optimization for ValueDecoder, in practice tho it's also handled in the
 	// methods that are based on reflection.
 	switch x := v.(type) {
+	case TypedValueDecoder:
+		return d.decodeTypedValue(x)
 	case ValueDecoder:
 		return x.DecodeValue(d)
 	}
@@ -77,13 +570,265 @@ func (d Decoder) Decode(v interface{}) error {
 	return err
 }
 
+// decodeMapKey decodes v with the key decoder of a map configured with
+// WrapMapErrors, recording the key when it was decoded successfully.
+func (d Decoder) decodeMapKey(v interface{}) error {
+	k := d.mapKey
+	d.mapKey = nil
+
+	if err := d.Decode(v); err != nil {
+		return err
+	}
+
+	*k = v
+	return nil
+}
+
+// DecodeExact is like Decode but also verifies that the value was the last one
+// of the input, returning an error if some data follows it. This is useful to
+// validate that inputs hold complete documents.
+//
+// The check relies on the parser implementing EOFParser, it is skipped when
+// it's not the case or the parser returns ErrEOFUnsupported.
+func (d Decoder) DecodeExact(v interface{}) error {
+	if err := d.Decode(v); err != nil {
+		return err
+	}
+
+	if p, ok := d.Parser.(EOFParser); ok {
+		if err := p.ParseEOF(); err != nil && err != ErrEOFUnsupported {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PeekType returns the type of the next value without consuming it, which is
+// useful to ValueDecoder implementations that dispatch on the type of values:
+//
+//	func (v *T) DecodeValue(d objconv.Decoder) error {
+//		t, err := d.PeekType()
+//		if err != nil {
+//			return err
+//		}
+//		if t == objconv.Map {
+//			return d.Decode(&v.fields)
+//		}
+//		return d.Decode(&v.name)
+//	}
+//
+// Parsers are required to implement ParseType idempotently so the type isn't
+// cached, the decoding methods called after PeekType ask the parser again.
+// The method takes a pointer receiver because when d is the decoder of a map
+// value, the separator between the key and the value has to be parsed first,
+// and d must remember that it was.
+//
+// Like other decoder methods, PeekType must not be called by multiple
+// goroutines.
+func (d *Decoder) PeekType() (Type, error) {
+	if d.off != 0 {
+		var err error
+		if d.off, err = 0, d.Parser.ParseMapValue(d.off-1); err != nil {
+			return Unknown, err
+		}
+	}
+	return d.Parser.ParseType()
+}
+
+// DecodeReflect is like Decode but loads the next parsed data into v, which
+// is useful to programs that already manipulate values with reflection.
+//
+// The method panics if v cannot be set, for example if it wasn't obtained
+// from a pointer or a field of an addressable struct.
+func (d Decoder) DecodeReflect(v reflect.Value) (err error) {
+	if !v.CanSet() {
+		panic(fmt.Sprintf("objconv: DecodeReflect called with a value that cannot be set (%s)", v.Kind()))
+	}
+
+	if d.off != 0 {
+		d.off, err = 0, d.Parser.ParseMapValue(d.off-1)
+	}
+
+	if err == nil {
+		_, err = d.decode(v)
+	}
+
+	// Like Decode, the position is only added to the errors of top-level
+	// values.
+	if p, ok := d.Parser.(PositionParser); ok && d.depth == 0 && err != nil {
+		err = positionError(err, p)
+	}
+	return
+}
+
 func (d Decoder) decode(to reflect.Value) (Type, error) {
+	if d.decodeDirect(to) {
+		return Unknown /* just needs to not be Nil */, nil
+	}
 	return decodeFuncOf(to.Type())(d, to)
 }
 
+// decodeDirect assigns the value exposed by the parser to `to` if the parser is
+// a DirectParser and the value can be assigned to `to`. The method returns true
+// if the value was assigned.
+func (d Decoder) decodeDirect(to reflect.Value) bool {
+	// The hooks and the control character policy apply to each decoded value,
+	// which cannot be done when assigning values exposed by the parser. Maps
+	// are merged into the existing ones entry by entry, assigning the maps of
+	// the parser would also share them with the decoded value. The same goes
+	// for the slices which backing arrays are reused, and for the null values
+	// nested in the exposed value, which would not be replaced by pointers to
+	// zero values or by the values returned by DefaultFunc.
+	if d.StringHook != nil || d.ValueHook != nil || d.ControlCharPolicy != ControlCharAllow ||
+		d.MergeMaps || d.ReuseSlices || d.NilPointersAsZeroValue || d.DefaultFunc != nil {
+		return false
+	}
+
+	p, ok := d.Parser.(DirectParser)
+	if !ok || !to.IsValid() || !to.CanSet() {
+		return false
+	}
+
+	v, ok := p.ParseValue()
+	if !ok || v == nil {
+		return false
+	}
+
+	x := reflect.ValueOf(v)
+	if !x.Type().AssignableTo(to.Type()) {
+		return false
+	}
+
+	to.Set(x)
+	return true
+}
+
+// decodeField decodes the next value into v, the value of the struct field f.
+// Fields with a decoder registered by RegisterFieldDecoder always go through
+// it, and so do the fields deduplicated in place by the uniqueby option, which
+// would modify the slices of the parser. The other fields may be assigned the
+// value exposed by the parser.
+func (d Decoder) decodeField(f *structField, v reflect.Value) (err error) {
+	if f.registered || f.uniqueIndex != nil || !d.decodeDirect(v) {
+		_, err = f.decode(d, v)
+	}
+	return
+}
+
+// valueHook calls the ValueHook of the decoder with the value that was just
+// decoded into to, which is skipped when the value is discarded.
+func (d Decoder) valueHook(t Type, to reflect.Value) error {
+	if !to.IsValid() {
+		return nil
+	}
+	return d.ValueHook(t, to)
+}
+
+// warn records a warning for the struct being decoded, if it has a field to
+// receive them.
+func (d Decoder) warn(format string, args ...interface{}) {
+	if d.warnings != nil {
+		*d.warnings = append(*d.warnings, fmt.Sprintf(format, args...))
+	}
+}
+
+// reset sets to to the value returned by DefaultFunc for its type, or to its
+// zero value. It is used when decoding null values or when decoding failed.
+func (d Decoder) reset(to reflect.Value) {
+	if d.DefaultFunc != nil {
+		if v, ok := d.DefaultFunc(to.Type()); ok {
+			to.Set(v)
+			return
+		}
+	}
+	to.Set(zeroValueOf(to.Type()))
+}
+
+// discard moves past the next value, with SkipValue when the parser implements
+// SkipParser, or by decoding it into nothing otherwise.
+func (d Decoder) discard() (err error) {
+	if p, ok := d.Parser.(SkipParser); ok {
+		return p.SkipValue()
+	}
+	_, err = d.decodeInterface(reflect.Value{})
+	return
+}
+
+// decodeOrSkip decodes an array element into to with f, calling the
+// ErrorHandler of the decoder if it fails. The method returns false when the
+// element was skipped.
+//
+// When the parser implements RawParser and Reparser the element is read with
+// ParseRaw and decoded from a parser returned by Reparse, and when it's a
+// DirectParser exposing the element as a Go value the element is decoded from
+// this value then discarded, so the parser is positioned after the element even
+// when decoding fails. With other parsers the position of the parser is unknown
+// after an error, the error handler is still called but decoding is aborted
+// with the original error if it returns nil.
+func (d Decoder) decodeOrSkip(index int, to reflect.Value, f decodeFunc) (ok bool, err error) {
+	e := d
+	e.off = 0
+
+	if p, ok1 := d.Parser.(RawParser); ok1 {
+		if rp, ok2 := d.Parser.(Reparser); ok2 {
+			var b []byte
+
+			if b, err = p.ParseRaw(); err != nil {
+				return // the end of the element cannot be found
+			}
+
+			// The bytes are fully decoded before the parser is used again, so
+			// they don't need to be copied.
+			e.Parser = rp.Reparse(b)
+			return d.skipOnError(index, to, e, f)
+		}
+	}
+
+	if p, ok1 := d.Parser.(DirectParser); ok1 {
+		if v, ok2 := p.ParseValue(); ok2 {
+			e.Parser = replayParser{ValueParser: NewValueParser(v), parser: d.Parser}
+
+			if ok, err = d.skipOnError(index, to, e, f); err == nil {
+				err = d.discard()
+			}
+			return
+		}
+	}
+
+	if _, err = f(d, to); err == nil {
+		return true, nil
+	}
+
+	if herr := d.ErrorHandler(index, err); herr != nil {
+		err = herr
+	}
+	return
+}
+
+// skipOnError decodes an array element into to with f and the decoder e, which
+// reads a copy of the element, calling the ErrorHandler of d if it fails.
+func (d Decoder) skipOnError(index int, to reflect.Value, e Decoder, f decodeFunc) (ok bool, err error) {
+	if _, err = f(e, to); err == nil {
+		return true, nil
+	}
+
+	to.Set(zeroValueOf(to.Type()))
+	return false, d.ErrorHandler(index, err)
+}
+
+// lossy calls the OnLossyConversion function of the decoder, if any.
+func (d Decoder) lossy(from Type, to reflect.Type, format string, args ...interface{}) {
+	if d.OnLossyConversion != nil {
+		d.OnLossyConversion(from, to, fmt.Sprintf(format, args...))
+	}
+}
+
 func (d Decoder) decodeBool(to reflect.Value) (t Type, err error) {
 	if t, err = d.Parser.ParseType(); err == nil {
-		err = d.decodeBoolFromType(t, to)
+		if err = d.decodeBoolFromType(t, to); err == nil && d.ValueHook != nil {
+			err = d.valueHook(t, to)
+		}
 	}
 	return
 }
@@ -98,6 +843,42 @@ func (d Decoder) decodeBoolFromType(t Type, to reflect.Value) (err error) {
 	case Bool:
 		v, err = d.Parser.ParseBool()
 
+	case String, Bytes:
+		if !d.WeaklyTypedInput && d.BoolStrings == nil {
+			err = typeConversionError(t, Bool)
+			break
+		}
+
+		var b []byte
+
+		if _, b, err = d.decodeTypeAndString(); err != nil {
+			break
+		}
+
+		if v, err = d.parseBoolString(b); err == nil {
+			d.warn("converted %s to %s", t, Bool)
+		}
+
+	case Int, Uint:
+		if !d.WeaklyTypedInput {
+			err = typeConversionError(t, Bool)
+			break
+		}
+
+		var i int64
+		var u uint64
+
+		if t == Int {
+			i, err = d.Parser.ParseInt()
+		} else {
+			u, err = d.Parser.ParseUint()
+		}
+
+		if err == nil {
+			v = i != 0 || u != 0
+			d.warn("converted %s to %s", t, Bool)
+		}
+
 	default:
 		err = typeConversionError(t, Bool)
 	}
@@ -112,9 +893,52 @@ func (d Decoder) decodeBoolFromType(t Type, to reflect.Value) (err error) {
 	return
 }
 
+// DefaultBoolStrings returns a table of the common spellings of booleans, which
+// can be used as the BoolStrings of decoders. Each call returns a new table, it
+// can be modified without affecting other decoders.
+func DefaultBoolStrings() map[string]bool {
+	return map[string]bool{
+		"true":  true,
+		"t":     true,
+		"1":     true,
+		"yes":   true,
+		"y":     true,
+		"on":    true,
+		"false": false,
+		"f":     false,
+		"0":     false,
+		"no":    false,
+		"n":     false,
+		"off":   false,
+	}
+}
+
+func (d Decoder) parseBoolString(b []byte) (bool, error) {
+	table := d.BoolStrings
+	if table == nil {
+		return strconv.ParseBool(string(b))
+	}
+
+	// strings.ToLower returns its argument when it has no upper case letters,
+	// so the lookup doesn't allocate in the common case.
+	if v, ok := table[strings.ToLower(unsafeString(b))]; ok {
+		return v, nil
+	}
+
+	accepted := make([]string, 0, len(table))
+	for k := range table {
+		accepted = append(accepted, strconv.Quote(k))
+	}
+	sort.Strings(accepted)
+
+	return false, fmt.Errorf("objconv: %q cannot be decoded into a boolean, the accepted values are %s", b, strings.Join(accepted, ", "))
+}
+
 func (d Decoder) decodeInt(to reflect.Value) (t Type, err error) {
 	if t, err = d.Parser.ParseType(); err == nil {
-		err = d.decodeIntFromType(t, to)
+		if err = d.decodeIntFromType(t, to); err == nil && d.ValueHook != nil {
+			err = d.valueHook(t, to)
+		}
 	}
 	return
 }
@@ -134,16 +958,7 @@ func (d Decoder) decodeIntFromType(t Type, to reflect.Value) (err error) {
 		}
 
 		if valid {
-			switch t := to.Type(); t.Kind() {
-			case reflect.Int:
-				err = objutil.CheckInt64Bounds(i, int64(objutil.IntMin), uint64(objutil.IntMax), t)
-			case reflect.Int8:
-				err = objutil.CheckInt64Bounds(i, objutil.Int8Min, objutil.Int8Max, t)
-			case reflect.Int16:
-				err = objutil.CheckInt64Bounds(i, objutil.Int16Min, objutil.Int16Max, t)
-			case reflect.Int32:
-				err = objutil.CheckInt64Bounds(i, objutil.Int32Min, objutil.Int32Max, t)
-			}
+			err = checkIntBounds(i, to.Type())
 		}
 
 	case Uint:
@@ -175,11 +990,7 @@ func (d Decoder) decodeIntFromType(t Type, to reflect.Value) (err error) {
 			return
 		}
 
-		i, err = strconv.ParseInt(unsafeString(b), 10, 64)
-		// if an error is received, reparse with a "safe" string in case it is retained in the error
-		if err != nil {
-			_, err = strconv.ParseInt(string(b), 10, 64)
-		}
+		i, err = d.parseIntString(b)
 
 	case Bytes:
 		var b []byte
@@ -188,12 +999,56 @@ func (d Decoder) decodeIntFromType(t Type, to reflect.Value) (err error) {
 			return
 		}
 
-		i, err = strconv.ParseInt(unsafeString(b), 10, 64)
-		// if an error is received, reparse with a "safe" string in case it is retained in the error
-		if err != nil {
-			_, err = strconv.ParseInt(string(b), 10, 64)
+		i, err = d.parseIntString(b)
+
+	case Bool:
+		if !d.WeaklyTypedInput {
+			err = typeConversionError(t, Int)
+			break
+		}
+
+		var b bool
+
+		if b, err = d.Parser.ParseBool(); err != nil {
+			return
+		}
+
+		if b {
+			i = 1
+		}
+
+		d.warn("converted %s to %s", t, Int)
+
+	case Float:
+		if !d.WeaklyTypedInput {
+			err = typeConversionError(t, Int)
+			break
+		}
+
+		var f float64
+
+		if f, err = d.Parser.ParseFloat(); err != nil {
+			return
+		}
+
+		// The upper bound is exclusive because float64(math.MaxInt64) is 2^63.
+		if math.IsNaN(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+			err = fmt.Errorf("objconv: value %g overflows int64", f)
+			return
+		}
+
+		if i = int64(f); valid {
+			if err = checkIntBounds(i, to.Type()); err != nil {
+				return
+			}
 		}
 
+		if float64(i) != f && valid {
+			d.lossy(t, to.Type(), "truncated %g to %d", f, i)
+		}
+
+		d.warn("converted %s to %s", t, Int)
+
 	default:
 		err = typeConversionError(t, Int)
 	}
@@ -202,15 +1057,109 @@ func (d Decoder) decodeIntFromType(t Type, to reflect.Value) (err error) {
 		return
 	}
 
+	if t == String || t == Bytes {
+		if valid {
+			if err = checkIntBounds(i, to.Type()); err != nil {
+				return
+			}
+		}
+		d.warn("converted %s to %s", t, Int)
+	}
+
 	if valid {
 		to.SetInt(i)
 	}
 	return
 }
 
+func checkIntBounds(i int64, t reflect.Type) error {
+	switch t.Kind() {
+	case reflect.Int:
+		return objutil.CheckInt64Bounds(i, int64(objutil.IntMin), uint64(objutil.IntMax), t)
+	case reflect.Int8:
+		return objutil.CheckInt64Bounds(i, objutil.Int8Min, objutil.Int8Max, t)
+	case reflect.Int16:
+		return objutil.CheckInt64Bounds(i, objutil.Int16Min, objutil.Int16Max, t)
+	case reflect.Int32:
+		return objutil.CheckInt64Bounds(i, objutil.Int32Min, objutil.Int32Max, t)
+	default:
+		return nil
+	}
+}
+
+func checkUintBounds(u uint64, t reflect.Type) error {
+	switch t.Kind() {
+	case reflect.Uint:
+		return objutil.CheckUint64Bounds(u, uint64(objutil.UintMax), t)
+	case reflect.Uint8:
+		return objutil.CheckUint64Bounds(u, objutil.Uint8Max, t)
+	case reflect.Uint16:
+		return objutil.CheckUint64Bounds(u, objutil.Uint16Max, t)
+	case reflect.Uint32:
+		return objutil.CheckUint64Bounds(u, objutil.Uint32Max, t)
+	default:
+		return nil
+	}
+}
+
+func (d Decoder) parseIntString(b []byte) (i int64, err error) {
+	var base int
+
+	if base, err = d.integerBase(b); err != nil {
+		return
+	}
+
+	i, err = strconv.ParseInt(unsafeString(b), base, 64)
+	// if an error is received, reparse with a "safe" string in case it is retained in the error
+	if err != nil {
+		_, err = strconv.ParseInt(string(b), base, 64)
+	}
+	return
+}
+
+func (d Decoder) parseUintString(b []byte) (u uint64, err error) {
+	var base int
+
+	if base, err = d.integerBase(b); err != nil {
+		return
+	}
+
+	u, err = strconv.ParseUint(unsafeString(b), base, 64)
+	// if an error is received, reparse with a "safe" string in case it is retained in the error
+	if err != nil {
+		_, err = strconv.ParseUint(string(b), base, 64)
+	}
+	return
+}
+
+// integerBase returns the base in which the integer in b must be parsed
+// according to the leading zero policy of the decoder.
+func (d Decoder) integerBase(b []byte) (int, error) {
+	s := b
+
+	if len(s) != 0 && (s[0] == '-' || s[0] == '+') {
+		s = s[1:]
+	}
+
+	if len(s) < 2 || s[0] != '0' {
+		return 10, nil
+	}
+
+	switch d.LeadingZeroPolicy {
+	case LeadingZeroOctal:
+		return 8, nil
+	case LeadingZeroError:
+		return 0, fmt.Errorf("objconv: leading zeros are not allowed in the integer %q", b)
+	default:
+		return 10, nil
+	}
+}
+
 func (d Decoder) decodeUint(to reflect.Value) (t Type, err error) {
 	if t, err = d.Parser.ParseType(); err == nil {
-		err = d.decodeUintFromType(t, to)
+		if err = d.decodeUintFromType(t, to); err == nil && d.ValueHook != nil {
+			err = d.valueHook(t, to)
+		}
 	}
 	return
 }
@@ -252,16 +1201,7 @@ func (d Decoder) decodeUintFromType(t Type, to reflect.Value) (err error) {
 		}
 
 		if valid {
-			switch t := to.Type(); t.Kind() {
-			case reflect.Uint:
-				err = objutil.CheckUint64Bounds(u, uint64(objutil.UintMax), t)
-			case reflect.Uint8:
-				err = objutil.CheckUint64Bounds(u, objutil.Uint8Max, t)
-			case reflect.Uint16:
-				err = objutil.CheckUint64Bounds(u, objutil.Uint16Max, t)
-			case reflect.Uint32:
-				err = objutil.CheckUint64Bounds(u, objutil.Uint32Max, t)
-			}
+			err = checkUintBounds(u, to.Type())
 		}
 
 	case String:
@@ -271,11 +1211,7 @@ func (d Decoder) decodeUintFromType(t Type, to reflect.Value) (err error) {
 			return
 		}
 
-		u, err = strconv.ParseUint(unsafeString(b), 10, 64)
-		// if an error is received, reparse with a "safe" string in case it is retained in the error
-		if err != nil {
-			_, err = strconv.ParseUint(string(b), 10, 64)
-		}
+		u, err = d.parseUintString(b)
 
 	case Bytes:
 		var b []byte
@@ -284,13 +1220,57 @@ func (d Decoder) decodeUintFromType(t Type, to reflect.Value) (err error) {
 			return
 		}
 
-		u, err = strconv.ParseUint(unsafeString(b), 10, 64)
-		// if an error is received, reparse with a "safe" string in case it is retained in the error
-		if err != nil {
-			_, err = strconv.ParseUint(string(b), 10, 64)
+		u, err = d.parseUintString(b)
+
+	case Bool:
+		if !d.WeaklyTypedInput {
+			err = typeConversionError(t, Uint)
+			break
 		}
 
-	default:
+		var b bool
+
+		if b, err = d.Parser.ParseBool(); err != nil {
+			return
+		}
+
+		if b {
+			u = 1
+		}
+
+		d.warn("converted %s to %s", t, Uint)
+
+	case Float:
+		if !d.WeaklyTypedInput {
+			err = typeConversionError(t, Uint)
+			break
+		}
+
+		var f float64
+
+		if f, err = d.Parser.ParseFloat(); err != nil {
+			return
+		}
+
+		// The upper bound is exclusive because float64(math.MaxUint64) is 2^64.
+		if math.IsNaN(f) || f <= -1 || f >= math.MaxUint64 {
+			err = fmt.Errorf("objconv: value %g overflows uint64", f)
+			return
+		}
+
+		if u = uint64(f); valid {
+			if err = checkUintBounds(u, to.Type()); err != nil {
+				return
+			}
+		}
+
+		if float64(u) != f && valid {
+			d.lossy(t, to.Type(), "truncated %g to %d", f, u)
+		}
+
+		d.warn("converted %s to %s", t, Uint)
+
+	default:
 		err = typeConversionError(t, Uint)
 	}
 
@@ -298,6 +1278,15 @@ func (d Decoder) decodeUintFromType(t Type, to reflect.Value) (err error) {
 		return
 	}
 
+	if t == String || t == Bytes {
+		if valid {
+			if err = checkUintBounds(u, to.Type()); err != nil {
+				return
+			}
+		}
+		d.warn("converted %s to %s", t, Uint)
+	}
+
 	if valid {
 		to.SetUint(u)
 	}
@@ -306,7 +1295,9 @@ func (d Decoder) decodeUintFromType(t Type, to reflect.Value) (err error) {
 
 func (d Decoder) decodeFloat(to reflect.Value) (t Type, err error) {
 	if t, err = d.Parser.ParseType(); err == nil {
-		err = d.decodeFloatFromType(t, to)
+		if err = d.decodeFloatFromType(t, to); err == nil && d.ValueHook != nil {
+			err = d.valueHook(t, to)
+		}
 	}
 	return
 }
@@ -371,15 +1362,101 @@ func (d Decoder) decodeFloatFromType(t Type, to reflect.Value) (err error) {
 		return
 	}
 
+	if t == String || t == Bytes {
+		d.warn("converted %s to %s", t, Float)
+	}
+
 	if to.IsValid() {
+		if to.OverflowFloat(f) {
+			err = fmt.Errorf("objconv: value %g overflows %s", f, to.Type())
+			return
+		}
+		if x := float64(float32(f)); to.Kind() == reflect.Float32 && x != f && !math.IsNaN(f) {
+			d.lossy(t, to.Type(), "%g rounded to %g", f, x)
+		}
 		to.SetFloat(f)
 	}
 	return
 }
 
+// decodeComplex decodes complex numbers from arrays of two numbers holding the
+// real and imaginary parts, or from strings like "3+4i".
+//
+// The Type enumeration has no complex numbers, so decoding into an empty
+// interface produces the array or the string that the value was encoded as.
+func (d Decoder) decodeComplex(to reflect.Value) (t Type, err error) {
+	var c complex128
+
+	if t, err = d.Parser.ParseType(); err != nil {
+		return
+	}
+
+	switch t {
+	case Nil:
+		err = d.Parser.ParseNil()
+
+	case Array:
+		var parts [2]float64
+		var i int
+
+		if err = d.decodeArrayImpl(t, func(d Decoder) error {
+			if i++; i > len(parts) {
+				return d.Decode(nil) // reported after the whole array was read
+			}
+			return d.Decode(&parts[i-1])
+		}); err != nil {
+			break
+		}
+
+		if i != len(parts) {
+			err = fmt.Errorf("objconv: complex numbers must be decoded from arrays of 2 elements but the array has %d", i)
+			break
+		}
+
+		c = complex(parts[0], parts[1])
+
+	case String, Bytes:
+		var b []byte
+
+		if t == String {
+			b, err = d.Parser.ParseString()
+		} else {
+			b, err = d.Parser.ParseBytes()
+		}
+
+		if err != nil {
+			break
+		}
+
+		c, err = strconv.ParseComplex(unsafeString(b), 128)
+		// if an error is received, reparse with a "safe" string in case it is retained in the error
+		if err != nil {
+			_, err = strconv.ParseComplex(string(b), 128)
+		}
+
+	default:
+		err = typeConversionError(t, Array)
+	}
+
+	if err != nil {
+		return
+	}
+
+	if to.IsValid() {
+		if to.OverflowComplex(c) {
+			err = fmt.Errorf("objconv: value %v overflows %s", c, to.Type())
+			return
+		}
+		to.SetComplex(c)
+	}
+	return
+}
+
 func (d Decoder) decodeString(to reflect.Value) (t Type, err error) {
 	if t, err = d.Parser.ParseType(); err == nil {
-		err = d.decodeStringFromType(t, to)
+		if err = d.decodeStringFromType(t, to); err == nil && d.ValueHook != nil {
+			err = d.valueHook(t, to)
+		}
 	}
 	return
 }
@@ -452,15 +1529,41 @@ func (d Decoder) decodeStringFromType(t Type, to reflect.Value) (err error) {
 		return
 	}
 
+	switch t {
+	case Nil:
+	case String, Bytes:
+		if d.StringHook != nil {
+			if b, err = d.StringHook(b); err != nil {
+				return
+			}
+		}
+		if d.SizeProfile != nil {
+			d.SizeProfile.Strings.Observe(len(b))
+		}
+		if d.ControlCharPolicy != ControlCharAllow {
+			if b, err = d.controlChars(b); err != nil {
+				return
+			}
+		}
+	default:
+		d.warn("converted %s to %s", t, String)
+	}
+
 	if to.IsValid() {
-		to.SetString(string(b))
+		if d.Arena != nil {
+			to.SetString(arenaString(d.Arena, b))
+		} else {
+			to.SetString(string(b))
+		}
 	}
 	return
 }
 
 func (d Decoder) decodeBytes(to reflect.Value) (t Type, err error) {
 	if t, err = d.Parser.ParseType(); err == nil {
-		err = d.decodeBytesFromType(t, to)
+		if err = d.decodeBytesFromType(t, to); err == nil && d.ValueHook != nil {
+			err = d.valueHook(t, to)
+		}
 	}
 	return
 }
@@ -486,15 +1589,31 @@ func (d Decoder) decodeBytesFromType(t Type, to reflect.Value) (err error) {
 		return
 	}
 
-	if bd, ok := d.Parser.(bytesDecoder); ok {
+	if d.StringHook != nil && t != Nil {
+		if b, err = d.StringHook(b); err != nil {
+			return
+		}
+	}
+
+	if t == String && d.BytesEncoding != BytesRaw {
+		if b, err = d.decodeBytesEncoding(b); err != nil {
+			return
+		}
+	} else if bd, ok := d.Parser.(bytesDecoder); ok {
 		if b, err = bd.DecodeBytes(b); err != nil {
 			return
 		}
 	}
 
+	if d.SizeProfile != nil && t != Nil {
+		d.SizeProfile.Strings.Observe(len(b))
+	}
+
 	if to.IsValid() {
 		if t == Nil {
 			to.SetBytes(nil)
+		} else if d.Arena != nil {
+			to.SetBytes(arenaBytes(d.Arena, b))
 		} else {
 			v := make([]byte, len(b))
 			copy(v, b)
@@ -504,121 +1623,640 @@ func (d Decoder) decodeBytesFromType(t Type, to reflect.Value) (err error) {
 	return
 }
 
-func (d Decoder) decodeTime(to reflect.Value) (t Type, err error) {
-	if t, err = d.Parser.ParseType(); err == nil {
-		err = d.decodeTimeFromType(t, to)
+// decodeBytesEncoding decodes b with the bytes encoding of the decoder. The
+// bytes are decoded to a new slice because b may be read-only memory.
+func (d Decoder) decodeBytesEncoding(b []byte) (v []byte, err error) {
+	var n int
+	var name string
+
+	switch d.BytesEncoding {
+	case BytesBase64, BytesBase64URL:
+		enc := base64.StdEncoding
+		name = "base64"
+		if d.BytesEncoding == BytesBase64URL {
+			enc, name = base64.URLEncoding, "base64url"
+		}
+		if len(b)%4 != 0 && bytes.IndexByte(b, '=') < 0 {
+			enc = enc.WithPadding(base64.NoPadding)
+		}
+		v = make([]byte, enc.DecodedLen(len(b)))
+		n, err = enc.Decode(v, b)
+
+	case BytesHex:
+		name = "hex"
+		v = make([]byte, hex.DecodedLen(len(b)))
+		n, err = hex.Decode(v, b)
+
+	default:
+		return nil, fmt.Errorf("objconv: unsupported bytes encoding (%d)", d.BytesEncoding)
 	}
-	return
+
+	if err != nil {
+		const max = 16
+		prefix := string(b)
+		if len(prefix) > max {
+			prefix = prefix[:max] + "..."
+		}
+		return nil, fmt.Errorf("objconv: invalid %s bytes in the string %q: %s", name, prefix, err)
+	}
+
+	return v[:n], nil
 }
 
-func (d Decoder) decodeTimeFromType(t Type, to reflect.Value) (err error) {
-	var s []byte
-	var v time.Time
+// decodeBigInt decodes big.Int values from numbers or strings of decimal
+// digits.
+//
+// Note that the encoder doesn't special-case big.Int, *big.Int values are
+// serialized as strings because the type implements encoding.TextMarshaler,
+// which this function accepts.
+func (d Decoder) decodeBigInt(to reflect.Value) (t Type, err error) {
+	var b []byte
+	var i int64
+	var u uint64
+	var f float64
+	var z big.Int
+
+	if t, err = d.Parser.ParseType(); err != nil {
+		return
+	}
 
 	switch t {
 	case Nil:
 		err = d.Parser.ParseNil()
 
+	case Int:
+		if b, err = d.parseBigNumber(); err != nil || b != nil {
+			break
+		}
+		if i, err = d.Parser.ParseInt(); err == nil {
+			z.SetInt64(i)
+		}
+
+	case Uint:
+		if u, err = d.Parser.ParseUint(); err == nil {
+			z.SetUint64(u)
+		}
+
+	case Float:
+		if b, err = d.parseBigNumber(); err != nil || b != nil {
+			break
+		}
+		if f, err = d.Parser.ParseFloat(); err != nil {
+			break
+		}
+		if math.IsInf(f, 0) || math.IsNaN(f) || f != math.Trunc(f) {
+			err = fmt.Errorf("objconv: %g cannot be decoded into a big.Int because it is not an integer", f)
+			break
+		}
+		new(big.Float).SetFloat64(f).Int(&z)
+
 	case String:
-		s, err = d.Parser.ParseString()
+		b, err = d.Parser.ParseString()
 
 	case Bytes:
-		s, err = d.Parser.ParseBytes()
+		b, err = d.Parser.ParseBytes()
 
-	case Time:
-		v, err = d.Parser.ParseTime()
+	default:
+		err = typeConversionError(t, Int)
 	}
 
 	if err != nil {
 		return
 	}
 
-	if to.IsValid() {
-		if t == String || t == Bytes {
-			v, err = time.Parse(time.RFC3339Nano, unsafeString(s))
-			// if an error is received, reparse with a "safe" string in case it is retained in the error
-			if err != nil {
-				_, err = time.Parse(time.RFC3339Nano, string(t))
+	if b != nil {
+		ok := false
+
+		if t == Float {
+			// Numbers with exponents may still be integers, like 1e3.
+			var x big.Float
+			x.SetPrec(uint(math.Ceil(float64(len(b))*math.Log2(10))) + 64)
+
+			if _, ok = x.SetString(string(b)); ok && x.IsInt() {
+				x.Int(&z)
+			} else {
+				ok = false
 			}
+		} else {
+			_, ok = z.SetString(string(b), 10)
+		}
+
+		if !ok {
+			err = fmt.Errorf("objconv: %q cannot be decoded into a big.Int because it is not an integer", b)
+			return
 		}
-		*(to.Addr().Interface().(*time.Time)) = v
 	}
-	return
-}
 
-func (d Decoder) decodeDuration(to reflect.Value) (t Type, err error) {
-	if t, err = d.Parser.ParseType(); err == nil {
-		err = d.decodeDurationFromType(t, to)
+	if to.IsValid() {
+		to.Set(reflect.ValueOf(z))
 	}
 	return
 }
 
-func (d Decoder) decodeDurationFromType(t Type, to reflect.Value) (err error) {
-	var s []byte
-	var v time.Duration
+// decodeBigFloat decodes big.Float values from numbers or strings. When the
+// destination has no precision set, the precision is chosen so that all the
+// digits of strings are preserved.
+//
+// The same note about encoding than for decodeBigInt applies to big.Float.
+func (d Decoder) decodeBigFloat(to reflect.Value) (t Type, err error) {
+	var b []byte
+	var i int64
+	var u uint64
+	var f float64
+	var z big.Float
+
+	if to.IsValid() {
+		z.SetPrec(to.Addr().Interface().(*big.Float).Prec())
+	}
+
+	if t, err = d.Parser.ParseType(); err != nil {
+		return
+	}
 
 	switch t {
 	case Nil:
 		err = d.Parser.ParseNil()
 
+	case Int:
+		if b, err = d.parseBigNumber(); err != nil || b != nil {
+			break
+		}
+		if i, err = d.Parser.ParseInt(); err == nil {
+			z.SetInt64(i)
+		}
+
+	case Uint:
+		if u, err = d.Parser.ParseUint(); err == nil {
+			z.SetUint64(u)
+		}
+
+	case Float:
+		if b, err = d.parseBigNumber(); err != nil || b != nil {
+			break
+		}
+		if f, err = d.Parser.ParseFloat(); err != nil {
+			break
+		}
+		if math.IsNaN(f) {
+			err = errors.New("objconv: NaN cannot be decoded into a big.Float")
+			break
+		}
+		z.SetFloat64(f)
+
 	case String:
-		s, err = d.Parser.ParseString()
+		b, err = d.Parser.ParseString()
 
 	case Bytes:
-		s, err = d.Parser.ParseBytes()
+		b, err = d.Parser.ParseBytes()
 
-	case Duration:
-		v, err = d.Parser.ParseDuration()
+	default:
+		err = typeConversionError(t, Float)
 	}
 
 	if err != nil {
 		return
 	}
 
-	if t == String || t == Bytes {
-		v, err = time.ParseDuration(unsafeString(s))
-		// if an error is received, reparse with a "safe" string in case it is retained in the error
-		if err != nil {
-			_, err = time.ParseDuration(string(s))
+	if b != nil {
+		if z.Prec() == 0 {
+			if prec := uint(math.Ceil(float64(len(b)) * math.Log2(10))); prec > 64 {
+				z.SetPrec(prec)
+			}
+		}
+		if _, ok := z.SetString(string(b)); !ok {
+			err = fmt.Errorf("objconv: %q cannot be decoded into a big.Float because it is not a number", b)
+			return
 		}
 	}
 
 	if to.IsValid() {
-		to.SetInt(int64(v))
+		to.Set(reflect.ValueOf(z))
 	}
 	return
 }
 
-func (d Decoder) decodeError(to reflect.Value) (t Type, err error) {
-	if t, err = d.Parser.ParseType(); err == nil {
-		err = d.decodeErrorFromType(t, to)
+// decodeIP decodes net.IP values from strings holding the textual
+// representation of an address, or from raw 4 or 16 bytes addresses.
+//
+// The encoder doesn't need a special case, net.IP implements
+// encoding.TextMarshaler so values are serialized as strings.
+func (d Decoder) decodeIP(to reflect.Value) (t Type, err error) {
+	var b []byte
+	var ip net.IP
+
+	if t, b, err = d.decodeTypeAndString(); err != nil {
+		return
+	}
+
+	switch {
+	case len(b) == 0:
+	case t == Bytes && (len(b) == net.IPv4len || len(b) == net.IPv6len):
+		ip = make(net.IP, len(b))
+		copy(ip, b)
+	default:
+		if ip = net.ParseIP(unsafeString(b)); ip == nil {
+			err = fmt.Errorf("objconv: %q is not a valid IP address", string(b))
+			return
+		}
+	}
+
+	if to.IsValid() {
+		to.Set(reflect.ValueOf(ip))
 	}
 	return
 }
 
-func (d Decoder) decodeErrorFromType(t Type, to reflect.Value) (err error) {
-	var s []byte
-	var v error
+// decodeIPNet decodes net.IPNet values from strings in CIDR notation, like
+// "192.0.2.0/24". Empty strings produce the zero value.
+func (d Decoder) decodeIPNet(to reflect.Value) (t Type, err error) {
+	var b []byte
+	var n net.IPNet
 
-	switch t {
-	case Nil:
-		err = d.Parser.ParseNil()
+	if t, b, err = d.decodeTypeAndString(); err != nil {
+		return
+	}
 
-	case String:
-		s, err = d.Parser.ParseString()
+	if t != Nil && len(b) != 0 {
+		var p *net.IPNet
 
-	case Bytes:
-		s, err = d.Parser.ParseBytes()
+		if _, p, err = net.ParseCIDR(unsafeString(b)); err != nil {
+			err = fmt.Errorf("objconv: %q is not a valid CIDR network", string(b))
+			return
+		}
 
-	case Error:
-		v, err = d.Parser.ParseError()
+		n = *p
 	}
 
-	if err != nil {
-		return
+	if to.IsValid() {
+		to.Set(reflect.ValueOf(n))
 	}
+	return
+}
 
-	if to.IsValid() {
+// decodeURL decodes url.URL values from strings with url.Parse.
+//
+// Without this special case *url.URL would be matched as an
+// encoding.BinaryUnmarshaler, and url.URL would be decoded from a map.
+func (d Decoder) decodeURL(to reflect.Value) (t Type, err error) {
+	var b []byte
+	var u url.URL
+
+	if t, b, err = d.decodeTypeAndString(); err != nil {
+		return
+	}
+
+	if t != Nil {
+		var p *url.URL
+
+		if p, err = url.Parse(string(b)); err != nil {
+			err = fmt.Errorf("objconv: %q is not a valid URL: %s", string(b), err)
+			return
+		}
+
+		u = *p
+	}
+
+	if to.IsValid() {
+		to.Set(reflect.ValueOf(u))
+	}
+	return
+}
+
+// decodeRegexp decodes *regexp.Regexp values by compiling strings, a null value
+// produces a nil pointer.
+func (d Decoder) decodeRegexp(to reflect.Value) (t Type, err error) {
+	var b []byte
+	var r *regexp.Regexp
+
+	if t, b, err = d.decodeTypeAndString(); err != nil {
+		return
+	}
+
+	if t != Nil {
+		compile := regexp.Compile
+		if d.RegexpPOSIX {
+			compile = regexp.CompilePOSIX
+		}
+		if r, err = compile(string(b)); err != nil {
+			err = fmt.Errorf("objconv: %q is not a valid regular expression: %s", string(b), err)
+			return
+		}
+	}
+
+	if to.IsValid() {
+		to.Set(reflect.ValueOf(r))
+	}
+	return
+}
+
+// decodeAtomicValue decodes atomic.Value values like empty interfaces. Since an
+// atomic.Value cannot be cleared, or hold values of different types, decoding
+// null leaves it unchanged, and decoding a value of a different type than the
+// one it holds is an error.
+func (d Decoder) decodeAtomicValue(to reflect.Value) (t Type, err error) {
+	var v interface{}
+
+	if t, err = d.decodeInterface(reflect.ValueOf(&v).Elem()); err != nil || v == nil || !to.IsValid() {
+		return
+	}
+
+	a := to.Addr().Interface().(*atomic.Value)
+
+	if old := a.Load(); old != nil && reflect.TypeOf(old) != reflect.TypeOf(v) {
+		err = fmt.Errorf("objconv: cannot store a value of type %T in an atomic.Value holding a value of type %T", v, old)
+		return
+	}
+
+	a.Store(v)
+	return
+}
+
+// parseBigNumber returns the text representation of the next number if the
+// parser exposes it, which avoids losing digits of numbers that don't fit in
+// 64 bits. The method returns a nil slice if the representation is not
+// available.
+func (d Decoder) parseBigNumber() ([]byte, error) {
+	if p, ok := d.Parser.(RawParser); ok && isTextParser(d.Parser) {
+		return p.ParseRaw()
+	}
+	return nil, nil
+}
+
+func (d Decoder) decodeTime(to reflect.Value) (t Type, err error) {
+	if t, err = d.Parser.ParseType(); err == nil {
+		if err = d.decodeTimeFromType(t, to); err == nil && d.ValueHook != nil {
+			err = d.valueHook(t, to)
+		}
+	}
+	return
+}
+
+func (d Decoder) decodeTimeFromType(t Type, to reflect.Value) (err error) {
+	var s []byte
+	var v time.Time
+
+	switch t {
+	case Nil:
+		err = d.Parser.ParseNil()
+
+	case String:
+		s, err = d.Parser.ParseString()
+
+	case Bytes:
+		s, err = d.Parser.ParseBytes()
+
+	case Time:
+		v, err = d.Parser.ParseTime()
+
+	case Int:
+		var i int64
+		if i, err = d.Parser.ParseInt(); err == nil {
+			v = d.timeFromInt(i)
+		}
+
+	case Uint:
+		var u uint64
+		if u, err = d.Parser.ParseUint(); err == nil {
+			if err = objutil.CheckUint64Bounds(u, objutil.Int64Max, timeType); err == nil {
+				v = d.timeFromInt(int64(u))
+			}
+		}
+
+	case Float:
+		var f float64
+		if f, err = d.Parser.ParseFloat(); err == nil {
+			v, err = d.timeFromFloat(f)
+		}
+
+	default:
+		err = typeConversionError(t, Time)
+	}
+
+	if err != nil {
+		return
+	}
+
+	if to.IsValid() {
+		if t == String || t == Bytes {
+			if v, err = d.parseTime(s); err != nil {
+				return
+			}
+		}
+		*(to.Addr().Interface().(*time.Time)) = v
+	}
+	return
+}
+
+func (d Decoder) timeUnit() time.Duration {
+	if d.TimeUnit > 0 {
+		return d.TimeUnit
+	}
+	return time.Second
+}
+
+// timeFromInt returns the time at i units of time since the Unix epoch.
+func (d Decoder) timeFromInt(i int64) time.Time {
+	unit := d.timeUnit()
+
+	if unit >= time.Second {
+		return time.Unix(i*int64(unit/time.Second), 0).UTC()
+	}
+
+	n := int64(time.Second / unit)
+	return time.Unix(i/n, (i%n)*int64(unit)).UTC()
+}
+
+// timeFromFloat returns the time at f units of time since the Unix epoch, the
+// fractional part of f gives the sub-unit precision.
+func (d Decoder) timeFromFloat(f float64) (time.Time, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return time.Time{}, fmt.Errorf("objconv: %g cannot be decoded into a time.Time", f)
+	}
+
+	sec, frac := math.Modf(f * d.timeUnit().Seconds())
+
+	if sec < math.MinInt64 || sec > math.MaxInt64 {
+		return time.Time{}, fmt.Errorf("objconv: %g x %s since the Unix epoch cannot be represented as a time.Time", f, d.timeUnit())
+	}
+
+	return time.Unix(int64(sec), int64(math.Round(frac*1e9))).UTC(), nil
+}
+
+func (d Decoder) parseTime(s []byte) (v time.Time, err error) {
+	if len(d.TimeLayouts) == 0 {
+		v, err = d.parseTimeLayout(time.RFC3339Nano, unsafeString(s))
+		// if an error is received, reparse with a "safe" string in case it is retained in the error
+		if err != nil {
+			_, err = d.parseTimeLayout(time.RFC3339Nano, string(s))
+		}
+		return
+	}
+
+	for _, layout := range d.TimeLayouts {
+		var e error
+
+		switch layout {
+		case "unix", "unixmilli":
+			var n int64
+
+			if n, e = strconv.ParseInt(unsafeString(s), 10, 64); e == nil {
+				if layout == "unix" {
+					v = time.Unix(n, 0).UTC()
+				} else {
+					v = time.Unix(n/1000, (n%1000)*int64(time.Millisecond)).UTC()
+				}
+			}
+
+		default:
+			v, e = d.parseTimeLayout(layout, unsafeString(s))
+		}
+
+		if e == nil {
+			return
+		}
+	}
+
+	err = fmt.Errorf("objconv: %q does not match any of the time layouts %q", string(s), d.TimeLayouts)
+	return
+}
+
+// parseTimeLayout parses s with layout, in the location configured on the
+// decoder if AssumeLocalTime is set.
+func (d Decoder) parseTimeLayout(layout string, s string) (time.Time, error) {
+	if !d.AssumeLocalTime {
+		return time.Parse(layout, s)
+	}
+	loc := d.TimeLocation
+	if loc == nil {
+		loc = time.Local
+	}
+	return time.ParseInLocation(layout, s, loc)
+}
+
+func (d Decoder) decodeDuration(to reflect.Value) (t Type, err error) {
+	if t, err = d.Parser.ParseType(); err == nil {
+		if err = d.decodeDurationFromType(t, to); err == nil && d.ValueHook != nil {
+			err = d.valueHook(t, to)
+		}
+	}
+	return
+}
+
+func (d Decoder) decodeDurationFromType(t Type, to reflect.Value) (err error) {
+	var s []byte
+	var v time.Duration
+
+	switch t {
+	case Nil:
+		err = d.Parser.ParseNil()
+
+	case String:
+		s, err = d.Parser.ParseString()
+
+	case Bytes:
+		s, err = d.Parser.ParseBytes()
+
+	case Duration:
+		v, err = d.Parser.ParseDuration()
+
+	case Int:
+		var i int64
+		if i, err = d.Parser.ParseInt(); err == nil {
+			v, err = d.durationFromInt(i)
+		}
+
+	case Uint:
+		var u uint64
+		if u, err = d.Parser.ParseUint(); err == nil {
+			if err = objutil.CheckUint64Bounds(u, objutil.Int64Max, durationType); err == nil {
+				v, err = d.durationFromInt(int64(u))
+			}
+		}
+
+	case Float:
+		var f float64
+		if f, err = d.Parser.ParseFloat(); err == nil {
+			v, err = d.durationFromFloat(f)
+		}
+
+	default:
+		err = typeConversionError(t, Duration)
+	}
+
+	if err != nil {
+		return
+	}
+
+	if t == String || t == Bytes {
+		v, err = time.ParseDuration(unsafeString(s))
+		// if an error is received, reparse with a "safe" string in case it is retained in the error
+		if err != nil {
+			_, err = time.ParseDuration(string(s))
+		}
+	}
+
+	if to.IsValid() {
+		to.SetInt(int64(v))
+	}
+	return
+}
+
+func (d Decoder) durationUnit() time.Duration {
+	if d.DurationUnit > 0 {
+		return d.DurationUnit
+	}
+	return time.Second
+}
+
+func (d Decoder) durationFromInt(i int64) (time.Duration, error) {
+	unit := d.durationUnit()
+	if v := time.Duration(i) * unit; v/unit == time.Duration(i) {
+		return v, nil
+	}
+	return 0, fmt.Errorf("objconv: %d x %s cannot be represented as a time.Duration", i, unit)
+}
+
+func (d Decoder) durationFromFloat(f float64) (time.Duration, error) {
+	unit := d.durationUnit()
+	if x := f * float64(unit); x >= math.MinInt64 && x < math.MaxInt64 {
+		return time.Duration(math.Round(x)), nil
+	}
+	return 0, fmt.Errorf("objconv: %g x %s cannot be represented as a time.Duration", f, unit)
+}
+
+func (d Decoder) decodeError(to reflect.Value) (t Type, err error) {
+	if t, err = d.Parser.ParseType(); err == nil {
+		err = d.decodeErrorFromType(t, to)
+	}
+	return
+}
+
+func (d Decoder) decodeErrorFromType(t Type, to reflect.Value) (err error) {
+	var s []byte
+	var v error
+
+	switch t {
+	case Nil:
+		err = d.Parser.ParseNil()
+
+	case String:
+		s, err = d.Parser.ParseString()
+
+	case Bytes:
+		s, err = d.Parser.ParseBytes()
+
+	case Error:
+		v, err = d.Parser.ParseError()
+
+	default:
+		err = typeConversionError(t, Error)
+	}
+
+	if err != nil {
+		return
+	}
+
+	if to.IsValid() {
 		if t == String || t == Bytes {
 			v = errors.New(string(s))
 		}
@@ -647,9 +2285,17 @@ func (d Decoder) decodeSliceFromType(typ Type, to reflect.Value) (err error) {
 }
 
 func (d Decoder) decodeSliceFromTypeWith(typ Type, to reflect.Value, f decodeFunc) (err error) {
+	if d.ScalarToSlice && typ != Array && typ != Nil {
+		return d.decodeSliceFromScalar(to, f)
+	}
+
 	if !to.IsValid() {
+		i := 0
 		return d.decodeArrayImpl(typ, func(d Decoder) (err error) {
-			_, err = f(d, reflect.Value{})
+			if _, err = f(d, reflect.Value{}); err != nil {
+				return decodeErrorWithIndex(err, i)
+			}
+			i++
 			return
 		})
 	}
@@ -658,19 +2304,46 @@ func (d Decoder) decodeSliceFromTypeWith(typ Type, to reflect.Value, f decodeFun
 	s := reflect.MakeSlice(t, 0, 0)
 	i := 0
 	n := 0
+	k := 0 // index of the element in the input, including skipped elements
+	reuse := d.ReuseSlices && !to.IsNil()
+	zero := reflect.Zero(t.Elem())
+
+	if reuse {
+		n = to.Cap()
+		s = to.Slice(0, n)
+	}
 
 	if err = d.decodeArrayImpl(typ, func(d Decoder) (err error) {
 		if i == n {
 			if n *= 5; n == 0 {
 				n = 10
 			}
-			sc := reflect.MakeSlice(t, n, n)
+			sc := d.makeSlice(t, n)
 			reflect.Copy(sc, s)
 			s = sc
+			reuse = false
+		}
+		e := s.Index(i)
+		if reuse {
+			e.Set(zero)
+		}
+		if k++; d.decodeDirect(e) {
+			i++
+			return
 		}
-		if _, err = f(d, s.Index(i)); err != nil {
+		if d.ErrorHandler != nil {
+			var ok bool
+			if ok, err = d.decodeOrSkip(k-1, e, f); err != nil {
+				return decodeErrorWithIndex(err, k-1)
+			}
+			if ok {
+				i++
+			}
 			return
 		}
+		if _, err = f(d, e); err != nil {
+			return decodeErrorWithIndex(err, i)
+		}
 		i++
 		return
 	}); err != nil {
@@ -678,9 +2351,12 @@ func (d Decoder) decodeSliceFromTypeWith(typ Type, to reflect.Value, f decodeFun
 	}
 
 	if typ == Nil {
-		to.Set(zeroValueOf(t))
+		d.reset(to)
 	} else {
 		if i != n {
+			if reuse {
+				clearSlice(s.Slice(i, n))
+			}
 			s = s.Slice(0, i)
 		}
 		to.Set(s)
@@ -688,14 +2364,47 @@ func (d Decoder) decodeSliceFromTypeWith(typ Type, to reflect.Value, f decodeFun
 	return
 }
 
-func (d Decoder) decodeArray(to reflect.Value) (t Type, err error) {
-	return d.decodeArrayWith(to, decodeFuncOf(to.Type().Elem()))
+func clearSlice(s reflect.Value) {
+	for i, n, z := 0, s.Len(), reflect.Zero(s.Type().Elem()); i != n; i++ {
+		s.Index(i).Set(z)
+	}
 }
 
-func (d Decoder) decodeArrayWith(to reflect.Value, f decodeFunc) (t Type, err error) {
-	if t, err = d.Parser.ParseType(); err == nil {
-		err = d.decodeArrayFromTypeWith(t, to, f)
-	}
+func (d Decoder) decodeSliceFromScalar(to reflect.Value, f decodeFunc) (err error) {
+	if !to.IsValid() {
+		_, err = f(d, reflect.Value{})
+		return
+	}
+
+	s := d.makeSlice(to.Type(), 1)
+
+	if e := s.Index(0); !d.decodeDirect(e) {
+		if _, err = f(d, e); err != nil {
+			return
+		}
+	}
+
+	to.Set(s)
+	return
+}
+
+func (d Decoder) makeSlice(t reflect.Type, n int) reflect.Value {
+	if d.Arena != nil {
+		if s := arenaSlice(d.Arena, t, n); s.IsValid() {
+			return s
+		}
+	}
+	return reflect.MakeSlice(t, n, n)
+}
+
+func (d Decoder) decodeArray(to reflect.Value) (t Type, err error) {
+	return d.decodeArrayWith(to, decodeFuncOf(to.Type().Elem()))
+}
+
+func (d Decoder) decodeArrayWith(to reflect.Value, f decodeFunc) (t Type, err error) {
+	if t, err = d.Parser.ParseType(); err == nil {
+		err = d.decodeArrayFromTypeWith(t, to, f)
+	}
 	return
 }
 
@@ -713,8 +2422,16 @@ func (d Decoder) decodeArrayFromTypeWith(typ Type, to reflect.Value, f decodeFun
 
 	if err = d.decodeArrayImpl(typ, func(d Decoder) (err error) {
 		if i < n {
-			if _, err = f(d, to.Index(i)); err != nil {
-				return
+			if e := to.Index(i); !d.decodeDirect(e) {
+				if _, err = f(d, e); err != nil {
+					return decodeErrorWithIndex(err, i)
+				}
+			}
+		} else {
+			// The elements that don't fit in the array are discarded, or
+			// reported after the whole array was read with StrictArrayLength.
+			if err = d.Decode(nil); err != nil {
+				return decodeErrorWithIndex(err, i)
 			}
 		}
 		i++
@@ -723,10 +2440,13 @@ func (d Decoder) decodeArrayFromTypeWith(typ Type, to reflect.Value, f decodeFun
 		return
 	}
 
-	if typ == Nil {
-		to.Set(zeroValueOf(t))
-	} else if i != n {
+	switch {
+	case typ == Nil:
+		d.reset(to)
+	case i < n:
 		err = fmt.Errorf("objconv: array length mismatch, expected %d but only %d elements were decoded", n, i)
+	case i > n && d.StrictArrayLength:
+		err = fmt.Errorf("objconv: array length mismatch, expected %d elements but the array has %d", n, i)
 	}
 
 	return
@@ -734,7 +2454,7 @@ func (d Decoder) decodeArrayFromTypeWith(typ Type, to reflect.Value, f decodeFun
 
 func (d Decoder) decodeMap(to reflect.Value) (Type, error) {
 	t := to.Type()
-	return d.decodeMapWith(to, decodeFuncOf(t.Key()), decodeFuncOf(t.Elem()))
+	return d.decodeMapWith(to, makeDecodeMapKeyFunc(t.Key(), decodeFuncOf(t.Key())), decodeFuncOf(t.Elem()))
 }
 
 func (d Decoder) decodeMapWith(to reflect.Value, kf decodeFunc, vf decodeFunc) (t Type, err error) {
@@ -749,16 +2469,26 @@ func (d Decoder) decodeMapFromType(typ Type, to reflect.Value) (err error) {
 	vf := Decoder.decodeInterface
 	if to.IsValid() {
 		t := to.Type()
-		kf = decodeFuncOf(t.Key())
+		kf = makeDecodeMapKeyFunc(t.Key(), decodeFuncOf(t.Key()))
 		vf = decodeFuncOf(t.Elem())
 	}
 	return d.decodeMapFromTypeWith(typ, to, kf, vf)
 }
 
 func (d Decoder) decodeMapFromTypeWith(typ Type, to reflect.Value, kf decodeFunc, vf decodeFunc) (err error) {
+	keys := d.sortedKeys()
+
 	if !to.IsValid() {
 		return d.decodeMapImpl(typ, func(kd Decoder, vd Decoder) (err error) {
-			if _, err = d.decodeInterface(reflect.Value{}); err != nil {
+			if keys == nil {
+				_, err = d.decodeInterface(reflect.Value{})
+			} else {
+				var k interface{}
+				if _, err = d.decodeInterface(reflect.ValueOf(&k).Elem()); err == nil {
+					err = keys.check(k)
+				}
+			}
+			if err != nil {
 				return
 			}
 			if err = d.Parser.ParseMapValue(vd.off - 1); err != nil {
@@ -771,6 +2501,10 @@ func (d Decoder) decodeMapFromTypeWith(typ Type, to reflect.Value, kf decodeFunc
 
 	t := to.Type() // map[K]V
 
+	if typ == Array && d.PairArraysAsMaps {
+		return d.decodeMapFromPairsWith(to, kf, vf)
+	}
+
 	switch t {
 	case mapInterfaceInterfaceType:
 		return d.decodeMapInterfaceInterface(typ, to)
@@ -780,9 +2514,12 @@ func (d Decoder) decodeMapFromTypeWith(typ Type, to reflect.Value, kf decodeFunc
 
 	case mapStringStringType:
 		return d.decodeMapStringString(typ, to)
+
+	case mapStringIntType:
+		return d.decodeMapStringInt(typ, to)
 	}
 
-	m := reflect.MakeMap(t) // make(map[K]V)
+	m := d.makeMap(to) // make(map[K]V)
 
 	kt := t.Key()                // K
 	kz := zeroValueOf(kt)        // K{}
@@ -792,162 +2529,929 @@ func (d Decoder) decodeMapFromTypeWith(typ Type, to reflect.Value, kf decodeFunc
 	vz := zeroValueOf(vt)        // V{}
 	vv := reflect.New(vt).Elem() // &V{}
 
+	nums := numericKeys(nil)
+	seen := d.duplicateKeys()
+
 	if err = d.decodeMapImpl(typ, func(kd Decoder, vd Decoder) (err error) {
 		kv.Set(kz) // reset the key to its zero-value
 		vv.Set(vz) // reset the value to its zero-value
 		if _, err = kf(d, kv); err != nil {
 			return
 		}
+		if keys != nil {
+			if err = keys.check(kv.Interface()); err != nil {
+				return
+			}
+		}
+		if seen != nil {
+			if err = seen.check(kv.Interface()); err != nil {
+				return
+			}
+		}
+		if err = d.Parser.ParseMapValue(vd.off - 1); err != nil {
+			return
+		}
+		if !d.decodeDirect(vv) {
+			if _, err = vf(d, vv); err != nil {
+				return decodeErrorWithKey(err, kv.Interface())
+			}
+		}
+		nums.setMapIndex(m, kv, vv)
+		return
+	}); err != nil {
+		return
+	}
+
+	if typ == Nil {
+		d.reset(to)
+	} else {
+		to.Set(m)
+	}
+	return
+}
+
+// makeMap returns the map that the entries decoded into to are set in, which is
+// the existing map when MergeMaps is enabled, or a new map otherwise.
+func (d Decoder) makeMap(to reflect.Value) reflect.Value {
+	if d.MergeMaps && !to.IsNil() {
+		return to
+	}
+	return reflect.MakeMap(to.Type())
+}
+
+func (d Decoder) decodeMapFromPairsWith(to reflect.Value, kf decodeFunc, vf decodeFunc) (err error) {
+	t := to.Type()     // map[K]V
+	m := d.makeMap(to) // make(map[K]V)
+
+	kt := t.Key()                // K
+	kz := zeroValueOf(kt)        // K{}
+	kv := reflect.New(kt).Elem() // &K{}
+
+	vt := t.Elem()               // V
+	vz := zeroValueOf(vt)        // V{}
+	vv := reflect.New(vt).Elem() // &V{}
+
+	nums := numericKeys(nil)
+	seen := d.duplicateKeys()
+
+	if err = d.decodeArrayImpl(Array, func(d Decoder) (err error) {
+		var typ Type
+		var n int
+
+		if typ, err = d.Parser.ParseType(); err != nil {
+			return
+		}
+
+		kv.Set(kz) // reset the key to its zero-value
+		vv.Set(vz) // reset the value to its zero-value
+
+		if err = d.decodeArrayImpl(typ, func(d Decoder) (err error) {
+			switch n++; n {
+			case 1:
+				_, err = kf(d, kv)
+			case 2:
+				if _, err = vf(d, vv); err != nil {
+					err = decodeErrorWithKey(err, kv.Interface())
+				}
+			default:
+				err = errors.New("objconv: expected a [key, value] pair but found an array of more than 2 elements")
+			}
+			return
+		}); err != nil {
+			return
+		}
+
+		if n != 2 {
+			return fmt.Errorf("objconv: expected a [key, value] pair but found an array of %d elements", n)
+		}
+
+		if seen != nil {
+			if err = seen.check(kv.Interface()); err != nil {
+				return
+			}
+		}
+
+		nums.setMapIndex(m, kv, vv)
+		return
+	}); err != nil {
+		return
+	}
+
+	to.Set(m)
+	return
+}
+
+func (d Decoder) decodeMapInterfaceInterface(typ Type, to reflect.Value) error {
+	m := to.Interface().(map[interface{}]interface{})
+
+	if m == nil {
+		m = make(map[interface{}]interface{})
+		to.Set(reflect.ValueOf(m))
+	}
+
+	if !d.MergeMaps {
+		for k := range m {
+			delete(m, k)
+		}
+	}
+
+	keys := d.sortedKeys()
+	nums := numericKeys(nil)
+	seen := d.duplicateKeys()
+
+	return d.decodeMapImpl(typ, func(kd Decoder, vd Decoder) (err error) {
+		var k interface{}
+		var v interface{}
+
+		if err = kd.Decode(&k); err != nil {
+			return
+		}
+		if keys != nil {
+			if err = keys.check(k); err != nil {
+				return
+			}
+		}
+		if seen != nil {
+			if err = seen.check(k); err != nil {
+				return
+			}
+		}
+		if err = vd.Decode(&v); err != nil {
+			return decodeErrorWithKey(err, k)
+		}
+
+		if prev, ok := nums.replace(k); ok && prev != k {
+			delete(m, prev)
+		}
+		m[k] = v
+		return
+	})
+}
+
+// numericKeys tracks the numeric keys of maps that have interface keys, so
+// that keys of different types which are numerically equal (like int64(0),
+// uint64(0) and -0.0) collapse into a single map entry, the last one wins.
+type numericKeys map[interface{}]interface{}
+
+// replace records k as the key holding its numeric value, returning the key
+// that previously held it, if any.
+func (nums *numericKeys) replace(k interface{}) (prev interface{}, ok bool) {
+	c, isNumber := canonicalNumber(k)
+	if !isNumber {
+		return
+	}
+	if *nums == nil {
+		*nums = make(numericKeys)
+	}
+	prev, ok = (*nums)[c]
+	(*nums)[c] = k
+	return
+}
+
+// setMapIndex sets the value v for key k in m, removing the entry of a key
+// numerically equal to k if the keys of m are interfaces.
+func (nums *numericKeys) setMapIndex(m reflect.Value, k reflect.Value, v reflect.Value) {
+	if k.Kind() == reflect.Interface {
+		if prev, ok := nums.replace(k.Interface()); ok && prev != k.Interface() {
+			m.SetMapIndex(reflect.ValueOf(prev), reflect.Value{})
+		}
+	}
+	m.SetMapIndex(k, v)
+}
+
+// canonicalNumber returns a representation of the number k which is equal for
+// all numbers with the same value, regardless of their type.
+func canonicalNumber(k interface{}) (interface{}, bool) {
+	switch x := k.(type) {
+	case int64:
+		return x, true
+
+	case uint64:
+		if x <= objutil.Int64Max {
+			return int64(x), true
+		}
+		return x, true
+
+	case float64:
+		if x == math.Trunc(x) {
+			switch {
+			case x >= -(1<<63) && x < (1<<63):
+				return int64(x), true
+			case x >= 0 && x < (1<<64):
+				return uint64(x), true
+			}
+		}
+		return x, true
+
+	default:
+		return nil, false
+	}
+}
+
+func (d Decoder) decodeMapStringInterface(typ Type, to reflect.Value) (err error) {
+	m := to.Interface().(map[string]interface{})
+
+	if m == nil {
+		m = make(map[string]interface{})
+		to.Set(reflect.ValueOf(m))
+	}
+
+	if !d.MergeMaps {
+		for k := range m {
+			delete(m, k)
+		}
+	}
+
+	keys := d.sortedKeys()
+	seen := d.duplicateKeys()
+
+	return d.decodeMapImpl(typ, func(kd Decoder, vd Decoder) (err error) {
+		var k string
+		var v interface{}
+
+		if k, err = d.decodeMapKeyString(); err != nil {
+			return
+		}
+
+		if keys != nil {
+			if err = keys.check(k); err != nil {
+				return
+			}
+		}
+		if seen != nil {
+			if err = seen.check(k); err != nil {
+				return
+			}
+		}
+
+		if d.Lazy {
+			var lv LazyValue
+			if err = vd.Decode(&lv); err != nil {
+				return decodeErrorWithKey(err, k)
+			}
+			m[k] = lv
+			return
+		}
+
+		if err = vd.Decode(&v); err != nil {
+			return decodeErrorWithKey(err, k)
+		}
+
+		m[k] = v
+		return
+	})
+}
+
+func (d Decoder) decodeMapStringString(typ Type, to reflect.Value) (err error) {
+	m := to.Interface().(map[string]string)
+
+	if m == nil {
+		m = make(map[string]string)
+		to.Set(reflect.ValueOf(m))
+	}
+
+	if !d.MergeMaps {
+		for k := range m {
+			delete(m, k)
+		}
+	}
+
+	keys := d.sortedKeys()
+	seen := d.duplicateKeys()
+
+	return d.decodeMapImpl(typ, func(kd Decoder, vd Decoder) (err error) {
+		var b []byte
+		var k string
+		var v string
+		var t Type
+
+		if k, err = d.decodeMapKeyString(); err != nil {
+			return
+		}
+
+		if keys != nil {
+			if err = keys.check(k); err != nil {
+				return
+			}
+		}
+		if seen != nil {
+			if err = seen.check(k); err != nil {
+				return
+			}
+		}
+
+		if err = d.Parser.ParseMapValue(vd.off - 1); err != nil {
+			return
+		}
+
+		if t, b, err = d.decodeTypeAndString(); err != nil {
+			return decodeErrorWithKey(err, k)
+		}
+		if d.ControlCharPolicy != ControlCharAllow {
+			if b, err = d.controlChars(b); err != nil {
+				return decodeErrorWithKey(err, k)
+			}
+		}
+		v = string(b)
+
+		if d.ValueHook != nil {
+			if err = d.ValueHook(t, reflect.ValueOf(&v).Elem()); err != nil {
+				return decodeErrorWithKey(err, k)
+			}
+		}
+
+		m[k] = v
+		return
+	})
+}
+
+func (d Decoder) decodeMapStringInt(typ Type, to reflect.Value) (err error) {
+	m := to.Interface().(map[string]int)
+
+	if m == nil {
+		m = make(map[string]int)
+		to.Set(reflect.ValueOf(m))
+	}
+
+	if !d.MergeMaps {
+		for k := range m {
+			delete(m, k)
+		}
+	}
+
+	keys := d.sortedKeys()
+	seen := d.duplicateKeys()
+
+	return d.decodeMapImpl(typ, func(kd Decoder, vd Decoder) (err error) {
+		var k string
+		var v int64
+		var u uint64
+		var t Type
+
+		if k, err = d.decodeMapKeyString(); err != nil {
+			return
+		}
+
+		if keys != nil {
+			if err = keys.check(k); err != nil {
+				return
+			}
+		}
+		if seen != nil {
+			if err = seen.check(k); err != nil {
+				return
+			}
+		}
+
+		if err = d.Parser.ParseMapValue(vd.off - 1); err != nil {
+			return
+		}
+
+		if t, err = d.Parser.ParseType(); err != nil {
+			return
+		}
+
+		switch t {
+		case Int:
+			if v, err = d.Parser.ParseInt(); err == nil {
+				err = objutil.CheckInt64Bounds(v, int64(objutil.IntMin), uint64(objutil.IntMax), intType)
+			}
+		case Uint:
+			if u, err = d.Parser.ParseUint(); err == nil {
+				err = objutil.CheckUint64Bounds(u, uint64(objutil.IntMax), intType)
+			}
+			v = int64(u)
+		default:
+			var x int
+			err = d.decodeIntFromType(t, reflect.ValueOf(&x).Elem())
+			v = int64(x)
+		}
+
+		if err != nil {
+			return decodeErrorWithKey(err, k)
+		}
+
+		n := int(v)
+
+		if d.ValueHook != nil {
+			if err = d.ValueHook(t, reflect.ValueOf(&n).Elem()); err != nil {
+				return decodeErrorWithKey(err, k)
+			}
+		}
+
+		m[k] = n
+		return
+	})
+}
+
+// decodeMapKeyString decodes a map key into a Go string for the fast paths of
+// maps with string keys, calling the ValueHook of the decoder like decoding the
+// key into a string value would.
+func (d Decoder) decodeMapKeyString() (k string, err error) {
+	var t Type
+	var b []byte
+
+	if t, b, err = d.decodeTypeAndString(); err != nil {
+		return
+	}
+	k = string(b)
+
+	if d.ValueHook != nil {
+		err = d.ValueHook(t, reflect.ValueOf(&k).Elem())
+	}
+	return
+}
+
+func (d Decoder) decodeStruct(to reflect.Value) (Type, error) {
+	return d.decodeStructWith(to, structCache.lookup(to.Type()))
+}
+
+func (d Decoder) decodeStructWith(to reflect.Value, s *structType) (t Type, err error) {
+	if t, err = d.Parser.ParseType(); err == nil {
+		err = d.decodeStructFromTypeWith(t, to, s)
+	}
+	return
+}
+
+func (d Decoder) decodeStructFromTypeWith(typ Type, to reflect.Value, s *structType) (err error) {
+	if s.err != nil {
+		return s.err
+	}
+	switch {
+	case typ == Array && (d.ArrayToStruct || s.positional):
+		err = d.decodeStructFromArray(to, s)
+	case len(s.paths) != 0 && typ == Map:
+		err = d.decodeStructWithPaths(to, s)
+	case s.flat && d.KeyRewriter == nil && !d.FuzzyFieldMatch && !d.RequireSortedKeys && !d.RejectDuplicateKeys:
+		err = d.decodeFlatStruct(typ, to, s)
+	default:
+		err = d.decodeStructFields(typ, to, s)
+	}
+	return
+}
+
+// decodeStructFromArray decodes the elements of an array into the fields of a
+// struct by position, in the order of the struct fields. Fields decoded from
+// JSON pointers are not part of the positions.
+func (d Decoder) decodeStructFromArray(to reflect.Value, s *structType) (err error) {
+	var present fieldSet
+	if s.required || s.defaults || s.oneOf != nil {
+		present = makeFieldSet(len(s.fields))
+	}
+
+	d.discriminator = ""
+	i := 0 // index of the next element of the array
+	j := 0 // index of the next struct field
+
+	if err = d.decodeArrayImpl(Array, func(d Decoder) (err error) {
+		for j < len(s.fields) && len(s.fields[j].path) != 0 {
+			j++
+		}
+
+		if j == len(s.fields) {
+			if !d.ArrayToStructTruncate {
+				return fmt.Errorf("objconv: cannot decode an array of more than %d elements into %s", i, to.Type())
+			}
+			i++
+			return d.discard()
+		}
+
+		f := &s.fields[j]
+		v := fieldByIndex(to, f.index)
+
+		if present != nil {
+			present.add(f.position)
+		}
+
+		if err = d.decodeField(f, v); err != nil {
+			return decodeErrorWithIndex(err, i)
+		}
+
+		f.postDecode(v)
+		i++
+		j++
+		return
+	}); err != nil {
+		d.reset(to)
+		return
+	}
+
+	if s.defaults {
+		if err = d.applyDefaults(to, s, present); err != nil {
+			d.reset(to)
+			return
+		}
+	}
+
+	if present != nil {
+		if err = s.checkRequired(to.Type(), present); err == nil {
+			err = s.checkOneOf(to, present)
+		}
+		if err != nil {
+			d.reset(to)
+		}
+	}
+	return
+}
+
+// applyDefaults decodes the default values of the fields of s which are not in
+// seen into to, and the default values of the fields of nested structs when
+// these structs are not in seen. The default values are decoded like strings of
+// the input with WeaklyTypedInput set.
+func (d Decoder) applyDefaults(to reflect.Value, s *structType, seen fieldSet) error {
+	for i := range s.fields {
+		f := &s.fields[i]
+
+		if len(f.path) != 0 || seen.has(i) {
+			continue
+		}
+
+		switch {
+		case len(f.defaultValue) != 0:
+			r := d
+			r.Parser = NewValueParser(f.defaultValue)
+			r.off = 0
+			r.WeaklyTypedInput = true
+
+			if _, err := f.decode(r, fieldByIndex(to, f.index)); err != nil {
+				return decodeErrorWithKey(fmt.Errorf("objconv: invalid default value %q: %s", f.defaultValue, err), f.name)
+			}
+
+		case f.defaults != nil:
+			if err := d.applyDefaults(fieldByIndex(to, f.index), f.defaults, makeFieldSet(len(f.defaults.fields))); err != nil {
+				return decodeErrorWithKey(err, f.name)
+			}
+		}
+	}
+	return nil
+}
+
+// unknownField is called when key matches none of the fields of the struct
+// type t, captured is true if the value of the key is kept by the struct in a
+// field tagged with `extra`.
+func (d Decoder) unknownField(t reflect.Type, key []byte, captured bool) error {
+	if d.OnUnknownField != nil {
+		if err := d.OnUnknownField(string(key)); err != nil {
+			return err
+		}
+	}
+	if d.DisallowUnknownFields && !captured {
+		return fmt.Errorf("objconv: unknown field %q in %s", key, t)
+	}
+	return nil
+}
+
+// decodeFlatStruct is a specialization of decodeStructFields for the structs of
+// scalar fields detected by structType.isFlat. The fields are looked up by name
+// and accessed by their position in the struct, skipping the bookkeeping that
+// decodeStructFields does for the features that these structs don't use.
+func (d Decoder) decodeFlatStruct(typ Type, to reflect.Value, s *structType) (err error) {
+	d.discriminator = ""
+
+	if err = d.decodeMapImpl(typ, func(kd Decoder, vd Decoder) (err error) {
+		var b []byte
+
+		if _, b, err = d.decodeTypeAndString(); err != nil {
+			return
+		}
+
+		f := s.fieldsByName[string(b)]
+
+		if f == nil {
+			if err = d.unknownField(to.Type(), b, false); err != nil {
+				return
+			}
+			d.warn("discarded unknown key %q", b)
+		}
+
 		if err = d.Parser.ParseMapValue(vd.off - 1); err != nil {
 			return
 		}
-		if _, err = vf(d, vv); err != nil {
+
+		if f == nil {
+			err = d.discard()
 			return
 		}
-		m.SetMapIndex(kv, vv)
+
+		if err = d.decodeField(f, to.Field(f.index[0])); err != nil {
+			err = decodeErrorWithKey(err, f.name)
+		}
 		return
 	}); err != nil {
-		return
-	}
-
-	if typ == Nil {
-		to.Set(zeroValueOf(t))
-	} else {
-		to.Set(m)
+		d.reset(to)
 	}
 	return
 }
 
-func (d Decoder) decodeMapInterfaceInterface(typ Type, to reflect.Value) error {
-	m := to.Interface().(map[interface{}]interface{})
+// decodeStructWithPaths decodes structs that have fields located by JSON
+// pointers. The map is first loaded in memory so the pointers can be resolved,
+// the struct fields are then decoded from the in-memory representation.
+func (d Decoder) decodeStructWithPaths(to reflect.Value, s *structType) (err error) {
+	var m map[interface{}]interface{}
 
-	if m == nil {
-		m = make(map[interface{}]interface{})
-		to.Set(reflect.ValueOf(m))
+	if err = d.loader().decodeMapFromType(Map, reflect.ValueOf(&m).Elem()); err != nil {
+		return
 	}
 
-	for k := range m {
-		delete(m, k)
+	r := d
+	r.Parser = replayParser{ValueParser: NewValueParser(m), parser: d.Parser}
+	r.off = 0
+
+	if err = r.decodeStructFields(Map, to, s); err != nil {
+		return
 	}
 
-	return d.decodeMapImpl(typ, func(kd Decoder, vd Decoder) (err error) {
-		var k interface{}
-		var v interface{}
+	for _, i := range s.paths {
+		f := &s.fields[i]
+		v, ok := resolveJSONPointer(m, f.path)
 
-		if err = kd.Decode(&k); err != nil {
-			return
+		if !ok {
+			err = fmt.Errorf("objconv: the jsonpath %q of field %s of %s cannot be resolved", f.path, f.name, to.Type())
+		} else {
+			r.Parser = replayParser{ValueParser: NewValueParser(v), parser: d.Parser}
+			fv := fieldByIndex(to, f.index)
+			if _, err = f.decode(r, fv); err == nil {
+				f.postDecode(fv)
+			} else if s.fieldErrors == nil {
+				err = decodeErrorWithKey(err, f.name)
+			}
 		}
-		if err = vd.Decode(&v); err != nil {
+
+		if err != nil && s.fieldErrors != nil {
+			results := to.FieldByIndex(s.fieldErrors).Addr().Interface().(*FieldResult)
+			results.record(f, fieldByIndex(to, f.index), err)
+			err = nil
+		}
+
+		if err != nil {
+			d.reset(to)
 			return
 		}
+	}
 
-		m[k] = v
-		return
-	})
+	return
 }
 
-func (d Decoder) decodeMapStringInterface(typ Type, to reflect.Value) (err error) {
-	m := to.Interface().(map[string]interface{})
+func (d Decoder) decodeStructFields(typ Type, to reflect.Value, s *structType) (err error) {
+	var warnings []string
+	var unknown []string
+	var unknownRaw map[string]RawValue
+	var extra map[string]interface{}
+	var results FieldResult
+	var key string
 
-	if m == nil {
-		m = make(map[string]interface{})
-		to.Set(reflect.ValueOf(m))
+	if s.warnings != nil {
+		d.warnings = &warnings
 	}
 
-	for k := range m {
-		delete(m, k)
+	// The discriminator only applies to the struct selected by a union, not to
+	// the values nested within it.
+	discriminator := d.discriminator
+	d.discriminator = ""
+
+	keys := d.sortedKeys()
+	seen := d.duplicateKeys()
+
+	var present fieldSet
+	if s.required || s.defaults || s.oneOf != nil {
+		present = makeFieldSet(len(s.fields))
 	}
 
-	return d.decodeMapImpl(typ, func(kd Decoder, vd Decoder) (err error) {
+	if err = d.decodeMapImpl(typ, func(kd Decoder, vd Decoder) (err error) {
 		var b []byte
-		var k string
-		var v interface{}
 
 		if _, b, err = d.decodeTypeAndString(); err != nil {
 			return
 		}
-		k = string(b)
+		if keys != nil {
+			if err = keys.check(string(b)); err != nil {
+				return
+			}
+		}
+		if seen != nil {
+			if err = seen.check(string(b)); err != nil {
+				return
+			}
+		}
+		var f *structField
+		if d.KeyRewriter == nil {
+			if f = s.fieldsByName[string(b)]; f == nil && s.aliases != nil {
+				f = s.aliases[string(b)]
+			}
+		} else {
+			f = s.lookup(d.KeyRewriter(string(b)))
+		}
 
-		if err = vd.Decode(&v); err != nil {
+		if f == nil && s.pathKeys[string(b)] {
+			// The key holds values of fields decoded from JSON pointers.
+			if err = d.Parser.ParseMapValue(vd.off - 1); err == nil {
+				err = d.discard()
+			}
 			return
 		}
 
-		m[k] = v
-		return
-	})
-}
+		if f == nil && d.FuzzyFieldMatch {
+			if f, err = s.fuzzyLookup(d.rewriteKey(b), d.fuzzyFieldDistance()); err != nil {
+				return
+			}
+			if f != nil {
+				d.warn("matched key %q to field %q", b, f.name)
+			}
+		}
 
-func (d Decoder) decodeMapStringString(typ Type, to reflect.Value) (err error) {
-	m := to.Interface().(map[string]string)
+		if f == nil {
+			if err = d.unknownField(to.Type(), b, s.extra != nil); err != nil {
+				return
+			}
+			if s.unknown != nil || s.extra != nil {
+				key = string(b)
+			}
+			if !s.unknownRaw && s.extra == nil {
+				d.warn("discarded unknown key %q", b)
+			}
+		}
 
-	if m == nil {
-		m = make(map[string]string)
-		to.Set(reflect.ValueOf(m))
-	}
+		if err = d.Parser.ParseMapValue(vd.off - 1); err != nil {
+			return
+		}
 
-	for k := range m {
-		delete(m, k)
-	}
+		if f == nil {
+			if s.extra != nil {
+				var x interface{}
+				if _, err = d.decodeInterface(reflect.ValueOf(&x).Elem()); err != nil {
+					err = decodeErrorWithKey(err, key)
+					return
+				}
+				if extra == nil {
+					extra = make(map[string]interface{})
+				}
+				extra[key] = x
+				return
+			}
+			if s.unknownRaw {
+				var r RawValue
+				if err = r.DecodeValue(d); err != nil {
+					err = decodeErrorWithKey(err, key)
+					return
+				}
+				if unknownRaw == nil {
+					unknownRaw = make(map[string]RawValue)
+				}
+				unknownRaw[key] = r
+				return
+			}
+			if s.unknown != nil {
+				unknown = append(unknown, key)
+			}
+			err = d.discard()
+			return
+		}
 
-	return d.decodeMapImpl(typ, func(kd Decoder, vd Decoder) (err error) {
-		var b []byte
-		var k string
-		var v string
+		v := fieldByIndex(to, f.index)
 
-		if _, b, err = d.decodeTypeAndString(); err != nil {
-			return
+		if present != nil {
+			present.add(f.position)
 		}
-		k = string(b)
 
-		if err = d.Parser.ParseMapValue(vd.off - 1); err != nil {
-			return
+		if s.fieldErrors != nil {
+			return d.decodeFieldCollectingError(f, v, &results)
 		}
 
-		if _, b, err = d.decodeTypeAndString(); err != nil {
+		if err = d.decodeField(f, v); err != nil {
+			err = decodeErrorWithKey(err, f.name)
 			return
 		}
-		v = string(b)
 
-		m[k] = v
+		f.postDecode(v)
 		return
-	})
-}
+	}); err != nil {
+		d.reset(to)
+		return
+	}
 
-func (d Decoder) decodeStruct(to reflect.Value) (Type, error) {
-	return d.decodeStructWith(to, structCache.lookup(to.Type()))
+	if typ != Nil {
+		if s.defaults {
+			if err = d.applyDefaults(to, s, present); err != nil {
+				d.reset(to)
+				return
+			}
+		}
+		if present != nil {
+			if err = s.checkRequired(to.Type(), present); err == nil {
+				err = s.checkOneOf(to, present)
+			}
+			if err != nil {
+				d.reset(to)
+				return
+			}
+		}
+		if s.warnings != nil {
+			to.FieldByIndex(s.warnings).Set(reflect.ValueOf(warnings))
+		}
+		if s.unknownRaw {
+			to.FieldByIndex(s.unknown).Set(reflect.ValueOf(unknownRaw))
+		} else if s.unknown != nil {
+			to.FieldByIndex(s.unknown).Set(reflect.ValueOf(unknown))
+		}
+		if s.extra != nil {
+			to.FieldByIndex(s.extra).Set(reflect.ValueOf(extra))
+		}
+		if s.discriminant != nil && len(discriminator) != 0 {
+			to.FieldByIndex(s.discriminant).SetString(discriminator)
+		}
+		if s.format != nil {
+			if name := formatName(d.Parser); len(name) != 0 {
+				to.FieldByIndex(s.format).SetString(name)
+			}
+		}
+		if s.fieldErrors != nil {
+			to.FieldByIndex(s.fieldErrors).Set(reflect.ValueOf(results))
+		}
+	}
+	return
 }
 
-func (d Decoder) decodeStructWith(to reflect.Value, s *structType) (t Type, err error) {
-	if t, err = d.Parser.ParseType(); err == nil {
-		err = d.decodeStructFromTypeWith(t, to, s)
+// decodeFieldCollectingError decodes the value of the struct field f into v,
+// recording the error in results instead of returning it if the value cannot
+// be decoded into the field.
+//
+// The value is first loaded in memory, which guarantees that the parser is
+// positioned after it even if decoding the field fails, and it is then decoded
+// into the field from the in-memory representation.
+func (d Decoder) decodeFieldCollectingError(f *structField, v reflect.Value, results *FieldResult) (err error) {
+	var x interface{}
+
+	if _, err = d.loader().decodeInterface(reflect.ValueOf(&x).Elem()); err != nil {
+		return decodeErrorWithKey(err, f.name)
+	}
+
+	r := d
+	r.Parser = replayParser{ValueParser: NewValueParser(x), parser: d.Parser}
+	r.off = 0
+
+	if _, err = f.decode(r, v); err != nil {
+		results.record(f, v, err)
+		return nil
 	}
+
+	f.postDecode(v)
 	return
 }
 
-func (d Decoder) decodeStructFromTypeWith(typ Type, to reflect.Value, s *structType) (err error) {
-	if err = d.decodeMapImpl(typ, func(kd Decoder, vd Decoder) (err error) {
-		var b []byte
+// loader returns a copy of the decoder used to load values in memory before
+// they are replayed with d.
+//
+// The options that alter the types of values decoded into empty interfaces are
+// only meant to be applied to the destination of the replayed values, and the
+// hooks are called when the values are replayed, so they don't run twice.
+func (d Decoder) loader() Decoder {
+	d.UseNumber = false
+	d.DetectBase64 = false
+	d.AllowSpecialFloats = false
+	d.StringHook = nil
+	d.ValueHook = nil
+	return d
+}
 
-		if _, b, err = d.decodeTypeAndString(); err != nil {
-			return
-		}
-		f := s.fieldsByName[string(b)]
+// record sets v, the value of the struct field f, to its zero value and adds
+// err to the result.
+func (r *FieldResult) record(f *structField, v reflect.Value, err error) {
+	if *r == nil {
+		*r = make(FieldResult)
+	}
+	(*r)[f.name] = err
+	v.Set(zeroValueOf(v.Type()))
+}
 
-		if err = d.Parser.ParseMapValue(vd.off - 1); err != nil {
-			return
-		}
+// rewriteKey returns the key b after applying the key rewriter of the decoder.
+func (d Decoder) rewriteKey(b []byte) string {
+	if d.KeyRewriter == nil {
+		return string(b)
+	}
+	return d.KeyRewriter(string(b))
+}
 
-		if f == nil {
-			_, err = d.decodeInterface(reflect.Value{}) // discard
-			return
-		}
+// enter returns an error if decoding an array or a map at the current depth
+// would exceed the maximum depth configured on the decoder.
+func (d Decoder) enter() error {
+	if d.MaxDepth > 0 && d.depth >= d.MaxDepth {
+		return fmt.Errorf("objconv: maximum nesting depth %d exceeded", d.MaxDepth)
+	}
+	return nil
+}
 
-		_, err = f.decode(d, to.FieldByIndex(f.index))
-		return
-	}); err != nil {
-		to.Set(zeroValueOf(to.Type()))
+func (d Decoder) progressInterval() int {
+	if d.ProgressInterval > 0 {
+		return d.ProgressInterval
 	}
-	return
+	return 1000
+}
+
+func (d Decoder) fuzzyFieldDistance() int {
+	if d.FuzzyFieldDistance > 0 {
+		return d.FuzzyFieldDistance
+	}
+	return 2
 }
 
 func (d Decoder) decodePointer(to reflect.Value) (Type, error) {
@@ -969,8 +3473,10 @@ func (d Decoder) decodePointerWith(to reflect.Value, f decodeFunc) (typ Type, er
 	}
 
 	switch {
+	case typ == Nil && d.NilPointersAsZeroValue:
+		to.Set(reflect.New(t.Elem()))
 	case typ == Nil:
-		to.Set(zeroValueOf(t))
+		d.reset(to)
 	case to.IsNil():
 		to.Set(v)
 	}
@@ -986,15 +3492,38 @@ func (d Decoder) decodeDecoder(to reflect.Value) (Type, error) {
 	return Unknown /* just needs to not be Nil */, to.Interface().(ValueDecoder).DecodeValue(d)
 }
 
+func (d Decoder) decodeTypedDecoderPointer(to reflect.Value) (Type, error) {
+	return d.decodeTypedDecoder(to.Addr())
+}
+
+func (d Decoder) decodeTypedDecoder(to reflect.Value) (Type, error) {
+	return Unknown /* just needs to not be Nil */, d.decodeTypedValue(to.Interface().(TypedValueDecoder))
+}
+
+func (d Decoder) decodeTypedValue(v TypedValueDecoder) error {
+	t, err := d.Parser.ParseType()
+	if err != nil {
+		return err
+	}
+	return v.DecodeTypedValue(d, t)
+}
+
 func (d Decoder) decodeUnmarshalerPointer(to reflect.Value) (Type, error) {
 	return d.decodeUnmarshaler(to.Addr())
 }
 
-func (d Decoder) decodeUnmarshaler(to reflect.Value) (Type, error) {
-	if isTextParser(d.Parser) {
-		return d.decodeTextUnmarshaler(to)
+// decodeUnmarshaler is used for types that implement both the
+// encoding.BinaryUnmarshaler and encoding.TextUnmarshaler interfaces, the
+// binary form is only used when the parser produces a byte slice, any other
+// value is decoded with the text form.
+func (d Decoder) decodeUnmarshaler(to reflect.Value) (t Type, err error) {
+	if t, err = d.Parser.ParseType(); err != nil {
+		return
 	}
-	return d.decodeBinaryUnmarshaler(to)
+	if t == Bytes && !isTextParser(d.Parser) {
+		return d.decodeBinaryUnmarshaler(to)
+	}
+	return d.decodeTextUnmarshaler(to)
 }
 
 func (d Decoder) decodeBinaryUnmarshalerPointer(to reflect.Value) (Type, error) {
@@ -1017,6 +3546,45 @@ func (d Decoder) decodeBinaryUnmarshaler(to reflect.Value) (t Type, err error) {
 	return
 }
 
+// scanner is the interface of the sql.Scanner type, it is declared here to avoid
+// a dependency on the database/sql package.
+type scanner interface {
+	Scan(src interface{}) error
+}
+
+// decodeScannerPointer decodes the next value into an empty interface, then
+// passes it to the Scan method of to, which must be addressable. This is how
+// values of nullable types like sql.NullString are decoded from scalars, the
+// nil values being passed as Scan(nil).
+//
+// Arrays and maps are not scalars, they are decoded with f, the decode function
+// of the kind of to, so scanners like slices keep being decoded from arrays.
+func (d Decoder) decodeScannerPointer(to reflect.Value, f decodeFunc) (t Type, err error) {
+	var v interface{}
+
+	if t, err = d.Parser.ParseType(); err != nil {
+		return
+	}
+
+	if t == Array || t == Map {
+		if err = d.loader().decodeInterfaceFromType(t, reflect.ValueOf(&v).Elem()); err != nil {
+			return
+		}
+		r := d
+		r.Parser = replayParser{ValueParser: NewValueParser(v), parser: d.Parser}
+		r.off = 0
+		_, err = f(r, to)
+		return
+	}
+
+	if err = d.decodeInterfaceFromType(t, reflect.ValueOf(&v).Elem()); err != nil {
+		return
+	}
+
+	err = to.Addr().Interface().(scanner).Scan(v)
+	return
+}
+
 func (d Decoder) decodeTextUnmarshalerPointer(to reflect.Value) (Type, error) {
 	return d.decodeTextUnmarshaler(to.Addr())
 }
@@ -1050,22 +3618,40 @@ func (d Decoder) decodeInterfaceFromType(t Type, to reflect.Value) (err error) {
 		err = d.decodeInterfaceFromNil(to)
 	case Bool:
 		err = d.decodeInterfaceFrom(boolType, t, to, Decoder.decodeBoolFromType)
-	case Int:
-		err = d.decodeInterfaceFrom(int64Type, t, to, Decoder.decodeIntFromType)
-	case Uint:
-		err = d.decodeInterfaceFrom(uint64Type, t, to, Decoder.decodeUintFromType)
+	case Int, Uint:
+		if d.UseNumber {
+			err = d.decodeInterfaceFrom(numberType, t, to, Decoder.decodeNumberFromType)
+		} else if d.PromoteOverflowToFloat {
+			err = d.decodeInterfaceFromPromotedInt(t, to)
+		} else if t == Int {
+			err = d.decodeInterfaceFrom(int64Type, t, to, Decoder.decodeIntFromType)
+		} else {
+			err = d.decodeInterfaceFrom(uint64Type, t, to, Decoder.decodeUintFromType)
+		}
 	case Float:
-		err = d.decodeInterfaceFrom(float64Type, t, to, Decoder.decodeFloatFromType)
+		if d.UseNumber {
+			err = d.decodeInterfaceFrom(numberType, t, to, Decoder.decodeNumberFromType)
+		} else {
+			err = d.decodeInterfaceFrom(float64Type, t, to, Decoder.decodeFloatFromType)
+		}
 	case String:
-		err = d.decodeInterfaceFrom(stringType, t, to, Decoder.decodeStringFromType)
+		if d.DetectBase64 || d.AllowSpecialFloats {
+			err = d.decodeInterfaceFromString(t, to)
+		} else {
+			err = d.decodeInterfaceFrom(stringType, t, to, Decoder.decodeStringFromType)
+		}
 	case Bytes:
 		err = d.decodeInterfaceFrom(bytesType, t, to, Decoder.decodeBytesFromType)
-	case Time:
-		err = d.decodeInterfaceFrom(timeType, t, to, Decoder.decodeTimeFromType)
-	case Duration:
-		err = d.decodeInterfaceFrom(durationType, t, to, Decoder.decodeDurationFromType)
-	case Error:
-		err = d.decodeInterfaceFrom(errorInterface, t, to, Decoder.decodeErrorFromType)
+	case Time, Duration, Error:
+		if d.InterfaceTimeAsString {
+			err = d.decodeInterfaceFrom(stringType, t, to, Decoder.decodeStringFromType)
+		} else if t == Time {
+			err = d.decodeInterfaceFrom(timeType, t, to, Decoder.decodeTimeFromType)
+		} else if t == Duration {
+			err = d.decodeInterfaceFrom(durationType, t, to, Decoder.decodeDurationFromType)
+		} else {
+			err = d.decodeInterfaceFrom(errorInterface, t, to, Decoder.decodeErrorFromType)
+		}
 	case Array:
 		err = d.decodeInterfaceFrom(sliceInterfaceType, t, to, Decoder.decodeSliceFromType)
 	case Map:
@@ -1082,13 +3668,95 @@ func (d Decoder) decodeInterfaceFromType(t Type, to reflect.Value) (err error) {
 	return
 }
 
-func (d Decoder) decodeInterfaceFromNil(to reflect.Value) (err error) {
-	if err = d.Parser.ParseNil(); err == nil {
-		if to.IsValid() {
-			to.Set(zeroValueOf(to.Type()))
+func (d Decoder) decodeInterfaceFromPromotedInt(t Type, to reflect.Value) (err error) {
+	var v interface{}
+
+	if t == Int {
+		var i int64
+
+		if i, err = d.Parser.ParseInt(); err == nil {
+			v = i
+		} else {
+			// The integer may be too large to be represented with 64 bits,
+			// attempt to parse it as a float instead and report the original
+			// error if it's not a valid number either.
+			var f float64
+			var e error
+
+			if f, e = d.Parser.ParseFloat(); e != nil {
+				return
+			}
+
+			d.warn("promoted %s to float64", t)
+			d.lossy(t, float64Type, "integer overflowing 64 bits rounded to %g", f)
+			v, err = f, nil
+		}
+	} else {
+		var u uint64
+
+		if u, err = d.Parser.ParseUint(); err != nil {
+			return
+		}
+
+		if u > objutil.Int64Max {
+			d.warn("promoted %s to float64", t)
+			d.lossy(t, float64Type, "integer %d rounded to %g", u, float64(u))
+			v = float64(u)
+		} else {
+			v = u
+		}
+	}
+
+	if to.IsValid() {
+		err = d.setInterface(t, to, v)
+	}
+	return
+}
+
+func (d Decoder) decodeInterfaceFromNil(to reflect.Value) (err error) {
+	if err = d.Parser.ParseNil(); err == nil {
+		if to.IsValid() {
+			d.reset(to)
+		}
+	}
+	return
+}
+
+// minBase64Length is the minimum length of strings that DetectBase64 considers
+// to be base64, shorter strings are too likely to be false positives.
+const minBase64Length = 16
+
+// specialFloats maps the strings that AllowSpecialFloats decodes into empty
+// interfaces as float64 values to these values.
+var specialFloats = map[string]float64{
+	"NaN":       math.NaN(),
+	"Infinity":  math.Inf(+1),
+	"-Infinity": math.Inf(-1),
+}
+
+// decodeInterfaceFromString decodes a string into the empty interface to,
+// converting it to a special float64 value with AllowSpecialFloats or to a
+// byte slice with DetectBase64.
+func (d Decoder) decodeInterfaceFromString(t Type, to reflect.Value) (err error) {
+	var s string
+
+	if err = d.decodeStringFromType(t, reflect.ValueOf(&s).Elem()); err != nil || !to.IsValid() {
+		return
+	}
+
+	if f, ok := specialFloats[s]; ok && d.AllowSpecialFloats {
+		d.warn("converted %s to %s", String, Float)
+		return d.setInterface(t, to, f)
+	}
+
+	if d.DetectBase64 && len(s) >= minBase64Length && len(s)%4 == 0 {
+		if b, e := base64.StdEncoding.Strict().DecodeString(s); e == nil {
+			d.warn("converted %s to %s", String, Bytes)
+			return d.setInterface(t, to, b)
 		}
 	}
-	return
+
+	return d.setInterface(t, to, s)
 }
 
 func (d Decoder) decodeInterfaceFrom(from reflect.Type, t Type, to reflect.Value, decode func(Decoder, Type, reflect.Value) error) (err error) {
@@ -1102,14 +3770,81 @@ func (d Decoder) decodeInterfaceFrom(from reflect.Type, t Type, to reflect.Value
 		return
 	}
 
+	// The hook is called with the scalar value before it's stored in the
+	// interface, like when decoding into a value of this type.
+	if d.ValueHook != nil && from != sliceInterfaceType && from != mapInterfaceInterfaceType && from != errorInterface {
+		if err = d.ValueHook(t, v); err != nil {
+			return
+		}
+	}
+
 	to.Set(v)
 	return
 }
 
+// setInterface stores the scalar value v decoded from a value of type t in the
+// empty interface to, after calling the ValueHook of the decoder with it.
+func (d Decoder) setInterface(t Type, to reflect.Value, v interface{}) error {
+	x := reflect.ValueOf(v)
+
+	if d.ValueHook != nil {
+		x = reflect.New(x.Type()).Elem()
+		x.Set(reflect.ValueOf(v))
+
+		if err := d.ValueHook(t, x); err != nil {
+			return err
+		}
+	}
+
+	to.Set(x)
+	return nil
+}
+
 func (d Decoder) decodeUnsupported(to reflect.Value) (Type, error) {
 	return Nil, fmt.Errorf("objconv: the decoder doesn't support values of type %s", to.Type())
 }
 
+// controlChars applies the control character policy of the decoder to b. The
+// returned slice is b itself if it contained no control characters.
+func (d Decoder) controlChars(b []byte) ([]byte, error) {
+	i := 0
+
+	for i < len(b) {
+		if c := b[i]; c < 0x20 || c == 0x7f || (c == 0xc2 && i+1 < len(b) && b[i+1] < 0xa0) {
+			break
+		}
+		i++
+	}
+
+	if i == len(b) {
+		return b, nil
+	}
+
+	if d.ControlCharPolicy == ControlCharReject {
+		return nil, fmt.Errorf("objconv: control characters are not allowed in the string %q", b)
+	}
+
+	s := make([]byte, 0, len(b)+8)
+	s = append(s, b[:i]...)
+
+	for i < len(b) {
+		r, n := utf8.DecodeRune(b[i:])
+
+		switch {
+		case !unicode.IsControl(r):
+			s = append(s, b[i:i+n]...) // invalid sequences are copied as-is
+		case d.ControlCharPolicy == ControlCharStrip:
+		default:
+			q := strconv.QuoteRune(r) // for example '\n' or '\x00'
+			s = append(s, q[1:len(q)-1]...)
+		}
+
+		i += n
+	}
+
+	return s, nil
+}
+
 func (d Decoder) decodeTypeAndString() (t Type, b []byte, err error) {
 	if t, err = d.Parser.ParseType(); err == nil {
 		// This algorithm is the same than the one used in
@@ -1124,6 +3859,9 @@ func (d Decoder) decodeTypeAndString() (t Type, b []byte, err error) {
 		default:
 			err = typeConversionError(t, String)
 		}
+		if err == nil && t != Nil && d.StringHook != nil {
+			b, err = d.StringHook(b)
+		}
 	}
 	return
 }
@@ -1156,7 +3894,9 @@ func (d Decoder) decodeArrayImpl(t Type, f func(Decoder) error) (err error) {
 		return
 
 	case Array:
-		n, err = d.Parser.ParseArrayBegin()
+		if err = d.enter(); err == nil {
+			n, err = d.Parser.ParseArrayBegin()
+		}
 
 	default:
 		err = typeConversionError(t, Array)
@@ -1166,7 +3906,11 @@ func (d Decoder) decodeArrayImpl(t Type, f func(Decoder) error) (err error) {
 		return
 	}
 
+	d.depth++
+
 	i := 0
+	progress, every := d.ProgressFunc, d.progressInterval()
+	d.ProgressFunc = nil // nested arrays don't report progress
 
 	for n < 0 || i < n {
 		if n < 0 || i != 0 {
@@ -1181,7 +3925,17 @@ func (d Decoder) decodeArrayImpl(t Type, f func(Decoder) error) (err error) {
 		if err = f(d); err != nil {
 			return
 		}
-		i++
+		if i++; progress != nil && i%every == 0 {
+			progress(i)
+		}
+	}
+
+	if progress != nil && i%every != 0 {
+		progress(i)
+	}
+
+	if d.SizeProfile != nil {
+		d.SizeProfile.Arrays.Observe(i)
 	}
 
 	err = d.Parser.ParseArrayEnd(i)
@@ -1207,11 +3961,70 @@ func (d Decoder) DecodeMap(f func(Decoder, Decoder) error) (err error) {
 		return
 	}
 
+	if d.WrapMapErrors {
+		f0 := f
+		f = func(kd Decoder, vd Decoder) error {
+			var key interface{}
+			kd.mapKey = &key
+			return wrapMapError(f0(kd, vd), key)
+		}
+	}
+
 	err = d.decodeMapImpl(typ, f)
 	return
 }
 
-func (d Decoder) decodeMapImpl(t Type, f func(Decoder, Decoder) error) (err error) {
+// DecodeMapN is like DecodeMap but f also receives the number of entries in
+// the map, which lets it pre-size the destination of the entries.
+//
+// The count is the one announced by the parser when the map begins, it is
+// negative when the parser doesn't know the length of the map in advance (for
+// example with formats that stream maps until a terminator is found).
+func (d Decoder) DecodeMapN(f func(Decoder, Decoder, int) error) (err error) {
+	var typ Type
+
+	if d.off != 0 {
+		if d.off, err = 0, d.Parser.ParseMapValue(d.off-1); err != nil {
+			return
+		}
+	}
+
+	if typ, err = d.Parser.ParseType(); err != nil {
+		return
+	}
+
+	if d.WrapMapErrors {
+		f0 := f
+		f = func(kd Decoder, vd Decoder, n int) error {
+			var key interface{}
+			kd.mapKey = &key
+			return wrapMapError(f0(kd, vd, n), key)
+		}
+	}
+
+	err = d.decodeMapImplN(typ, nil, f)
+	return
+}
+
+// wrapMapError wraps err with the map key that was decoded into key, which is
+// usually a pointer to the key value.
+func wrapMapError(err error, key interface{}) error {
+	if err == nil || key == nil {
+		return err
+	}
+	if v := reflect.ValueOf(key); v.Kind() == reflect.Ptr && !v.IsNil() {
+		key = v.Elem().Interface()
+	}
+	return decodeErrorWithKey(err, key)
+}
+
+func (d Decoder) decodeMapImpl(t Type, f func(Decoder, Decoder) error) error {
+	return d.decodeMapImplN(t, f, nil)
+}
+
+// decodeMapImplN is the implementation of DecodeMap and DecodeMapN, only one of
+// f and fn is expected to be set.
+func (d Decoder) decodeMapImplN(t Type, f func(Decoder, Decoder) error, fn func(Decoder, Decoder, int) error) (err error) {
 	var n int
 
 	switch t {
@@ -1220,7 +4033,9 @@ func (d Decoder) decodeMapImpl(t Type, f func(Decoder, Decoder) error) (err erro
 		return
 
 	case Map:
-		n, err = d.Parser.ParseMapBegin()
+		if err = d.enter(); err == nil {
+			n, err = d.Parser.ParseMapBegin()
+		}
 
 	default:
 		err = typeConversionError(t, Map)
@@ -1230,6 +4045,7 @@ func (d Decoder) decodeMapImpl(t Type, f func(Decoder, Decoder) error) (err erro
 		return
 	}
 
+	d.depth++
 	i := 0
 
 	for n < 0 || i < n {
@@ -1247,13 +4063,23 @@ func (d Decoder) decodeMapImpl(t Type, f func(Decoder, Decoder) error) (err erro
 		d2 := d
 		d2.off = i + 1
 
-		if err = f(d1, d2); err != nil {
+		if fn != nil {
+			err = fn(d1, d2, n)
+		} else {
+			err = f(d1, d2)
+		}
+
+		if err != nil {
 			return
 		}
 
 		i++
 	}
 
+	if d.SizeProfile != nil {
+		d.SizeProfile.Maps.Observe(i)
+	}
+
 	err = d.Parser.ParseMapEnd(i)
 	return
 }
@@ -1270,115 +4096,419 @@ type StreamDecoder struct {
 	// there is not destination type (when decoding to an empty interface).
 	MapType reflect.Type
 
-	err error
-	typ Type
-	cnt int
-	max int
-}
+	// DisallowUnknownFields makes the decoder return an error when decoding a
+	// map into a struct and a key doesn't match any of the struct fields.
+	DisallowUnknownFields bool
+
+	// OnUnknownField is called with the keys of maps decoded into structs that
+	// don't match any of the struct fields, see Decoder.OnUnknownField.
+	OnUnknownField func(key string) error
+
+	// IdleTimeout, when not zero, is the maximum amount of time that Decode and
+	// DecodeMapEntry wait for the next value. When it expires the methods
+	// return ErrIdleTimeout, which lets programs detect stream producers that
+	// went silent, and the stream cannot be decoded anymore.
+	//
+	// The timeout is cooperative, it relies on the parser implementing the
+	// DeadlineParser interface and on its source supporting deadlines (which is
+	// the case of network connections). Decoding fails if it's not the case.
+	IdleTimeout time.Duration
+
+	// RawEmitter is used by DecodeWithRaw to produce the raw bytes of values
+	// when the parser doesn't support capturing them (see RawParser and
+	// Reparser). The values are re-encoded with the emitter returned by the
+	// function, which must produce the same format than the one read by the
+	// parser.
+	RawEmitter func(io.Writer) Emitter
+
+	err     error
+	typ     Type
+	cnt     int
+	max     int
+	entries bool // decoding the entries of a map with DecodeMapEntry
+}
+
+// NewStreamDecoder returns a new stream decoder that takes input from p.
+//
+// The function panics if p is nil.
+func NewStreamDecoder(p Parser) *StreamDecoder {
+	if p == nil {
+		panic("objconv: the parser is nil")
+	}
+	return &StreamDecoder{Parser: p}
+}
+
+// Len returns the number of values remaining to be read from the stream, which
+// may be -1 if the underlying format doesn't provide this information. If an
+// error occurred while decoding the stream the method returns zero because no
+// more values can be read.
+func (d *StreamDecoder) Len() int {
+	if d.err != nil {
+		return 0
+	}
+
+	if d.typ == Unknown {
+		if d.init() != nil {
+			return 0
+		}
+	}
+
+	return d.max - d.cnt
+}
+
+// Remaining returns the number of values remaining to be read from the stream,
+// with known set to false if the underlying format doesn't provide the length
+// of the stream (like json arrays).
+//
+// Unlike Len, the method never reads from the parser, so it is only meaningful
+// after the first call to Decode has read the header of the array; before that
+// it reports an unknown length.
+func (d *StreamDecoder) Remaining() (n int, known bool) {
+	if d.typ == Unknown || d.max < 0 {
+		return 0, false
+	}
+	return d.max - d.cnt, true
+}
+
+// Err returns the last error returned by the Decode method.
+//
+// The method returns nil if the stream reached its natural end.
+func (d *StreamDecoder) Err() error {
+	if d.err == End {
+		return nil
+	}
+	return d.err
+}
+
+// Decodes the next value from the stream into v.
+func (d *StreamDecoder) Decode(v interface{}) error {
+	if d.err != nil {
+		return d.err
+	}
+
+	if d.entries {
+		return errors.New("objconv: Decode called on a stream decoder which is decoding map entries")
+	}
+
+	return d.next(func(dec Decoder) error { return dec.Decode(v) })
+}
+
+// next consumes the next value of the stream with decode, keeping track of the
+// position of the decoder in the stream.
+func (d *StreamDecoder) next(decode func(Decoder) error) error {
+	if err := d.setDeadline(); err != nil {
+		d.err = err
+		return err
+	}
+	defer d.clearDeadline()
+
+	err := error(nil)
+	cnt := d.cnt
+	max := d.max
+	dec := d.decoder()
+
+	switch d.typ {
+	case Unknown:
+		err = d.init()
+		max = d.max
+	case Array:
+		if cnt == max {
+			err = dec.Parser.ParseArrayEnd(cnt)
+		} else if cnt != 0 {
+			err = dec.Parser.ParseArrayNext(cnt)
+		}
+	}
+
+	if err == nil {
+		if cnt == max {
+			err = End
+		} else {
+			switch err = decode(dec); err {
+			case nil:
+				cnt++
+			case End:
+				cnt++
+				max = cnt
+			default:
+				if max < 0 && dec.Parser.ParseArrayEnd(cnt) == nil {
+					err = End
+				}
+			}
+		}
+	}
+
+	err = d.idleError(err)
+	d.err = err
+	d.cnt = cnt
+	d.max = max
+	return err
+}
+
+// DecodeMapEntry decodes the next entry of a stream holding a map, loading the
+// key into k and the value into v. This makes it possible to consume the map
+// one entry at a time, without loading it fully in memory.
+//
+// The method returns End when all entries of the map have been decoded. Once
+// DecodeMapEntry was called, the Decode method cannot be used anymore on the
+// stream decoder.
+func (d *StreamDecoder) DecodeMapEntry(k interface{}, v interface{}) error {
+	if d.err != nil {
+		return d.err
+	}
+
+	if err := d.setDeadline(); err != nil {
+		d.err = err
+		return err
+	}
+	defer d.clearDeadline()
+
+	err := error(nil)
+	cnt := d.cnt
+	max := d.max
+	dec := d.decoder()
+
+	switch {
+	case d.typ == Unknown:
+		err = d.initMap()
+		max = d.max
+	case !d.entries:
+		return errors.New("objconv: DecodeMapEntry called on a stream decoder which is decoding values")
+	}
+
+	if err == nil {
+		if cnt == max {
+			err = End
+		} else if max < 0 || cnt != 0 {
+			err = dec.Parser.ParseMapNext(cnt)
+		}
+	}
+
+	if err == nil {
+		if err = dec.Decode(k); err == nil {
+			vd := dec
+			vd.off = cnt + 1 // the value is preceded by a call to ParseMapValue
+			if err = vd.Decode(v); err == nil {
+				cnt++
+			}
+		}
+	}
+
+	if err == End {
+		if e := dec.Parser.ParseMapEnd(cnt); e != nil {
+			err = e
+		}
+		max = cnt
+	}
+
+	err = d.idleError(err)
+	d.err = err
+	d.cnt = cnt
+	d.max = max
+	return err
+}
+
+// DecodeWithRaw decodes the next value from the stream into v like Decode does,
+// and returns the serialized bytes of the value as well. This is useful to
+// retain the original representation of the values, for example to store them
+// or verify their signatures.
+//
+// The exact bytes read from the stream are returned when the parser implements
+// both the RawParser and Reparser interfaces, which is the case of the json
+// parser. Otherwise the values are re-encoded with RawEmitter, and the method
+// returns an error if it is nil.
+func (d *StreamDecoder) DecodeWithRaw(v interface{}) ([]byte, error) {
+	r := &rawCapture{value: v}
+	err := d.Decode(r)
+	return r.raw, err
+}
+
+// rawCapture is a ValueDecoder which decodes values while capturing their raw
+// bytes, it is used to implement StreamDecoder.DecodeWithRaw.
+type rawCapture struct {
+	value interface{}
+	raw   []byte
+}
+
+func (r *rawCapture) DecodeValue(d Decoder) (err error) {
+	p, ok := d.Parser.(RawParser)
+	rp, ok2 := d.Parser.(Reparser)
+
+	if ok && ok2 {
+		var b []byte
+
+		if b, err = p.ParseRaw(); err != nil {
+			return
+		}
+
+		r.raw = append([]byte(nil), b...)
+		d.Parser = rp.Reparse(r.raw)
+		return d.Decode(r.value)
+	}
+
+	if d.RawEmitter == nil {
+		return errors.New("objconv: the parser doesn't support raw values and no RawEmitter was configured on the stream decoder")
+	}
+
+	var x interface{}
+	var buf bytes.Buffer
+
+	if err = d.loader().Decode(&x); err != nil {
+		return
+	}
+
+	if err = NewEncoder(d.RawEmitter(&buf)).Encode(x); err != nil {
+		return
+	}
+
+	r.raw = buf.Bytes()
+	d.Parser = replayParser{ValueParser: NewValueParser(x), parser: d.Parser}
+	return d.Decode(r.value)
+}
+
+// Skip discards the next n values of the stream without decoding them, the
+// parser moves past the values with SkipValue if it implements SkipParser.
+//
+// The method returns the number of values that were skipped, which may be less
+// than n if the end of the stream was reached, in which case the error is End.
+func (d *StreamDecoder) Skip(n int) (skipped int, err error) {
+	if d.err != nil {
+		return 0, d.err
+	}
+
+	if d.entries {
+		return 0, errors.New("objconv: Skip called on a stream decoder which is decoding map entries")
+	}
+
+	for skipped < n {
+		if err = d.next(Decoder.discard); err != nil {
+			return
+		}
+		skipped++
+	}
+	return
+}
+
+// DecodeRemaining decodes all the values remaining in the stream, appending them
+// to the slice that v points to. Each element is decoded like with Decode, so
+// values that were already read from the stream are not included, and when the
+// stream is not an array its single value is appended unless it was already
+// decoded.
+//
+// The method returns nil when it reaches the end of the stream. If an error
+// occurs, the slice holds the values decoded before it.
+//
+// The method panics if v is not a non-nil pointer to a slice.
+func (d *StreamDecoder) DecodeRemaining(v interface{}) error {
+	p := reflect.ValueOf(v)
+
+	if p.Kind() != reflect.Ptr || p.IsNil() || p.Elem().Kind() != reflect.Slice {
+		panic(fmt.Sprintf("objconv: DecodeRemaining called with a value which is not a pointer to a slice (%T)", v))
+	}
+
+	s := p.Elem()
+	t := s.Type().Elem()
+
+	for {
+		e := reflect.New(t)
 
-// NewStreamDecoder returns a new stream decoder that takes input from p.
-//
-// The function panics if p is nil.
-func NewStreamDecoder(p Parser) *StreamDecoder {
-	if p == nil {
-		panic("objconv: the parser is nil")
+		if err := d.Decode(e.Interface()); err != nil {
+			if err == End {
+				err = nil
+			}
+			return err
+		}
+
+		s.Set(reflect.Append(s, e.Elem()))
 	}
-	return &StreamDecoder{Parser: p}
 }
 
-// Len returns the number of values remaining to be read from the stream, which
-// may be -1 if the underlying format doesn't provide this information. If an
-// error occurred while decoding the stream the method returns zero because no
-// more values can be read.
-func (d *StreamDecoder) Len() int {
-	if d.err != nil {
-		return 0
+// Encoder returns a new StreamEncoder which can be used to re-encode the stream
+// decoded by d into e.
+//
+// When the stream is not an array, it holds a single value which is transcoded
+// to e right away (see Transcode), the decoder is then positioned at the end of
+// the stream and the returned encoder only needs to be closed.
+//
+// The method panics if e is nil.
+func (d *StreamDecoder) Encoder(e Emitter) (enc *StreamEncoder, err error) {
+	var typ Type
+
+	if typ, err = d.Parser.ParseType(); err != nil {
+		return
 	}
 
-	if d.typ == Unknown {
-		if d.init() != nil {
-			return 0
+	enc = NewStreamEncoder(e)
+	enc.oneshot = typ != Array
+
+	if enc.oneshot && d.typ == Unknown {
+		if err = Transcode(e, d.Parser); err != nil {
+			d.err = err
+			return
 		}
+		d.err, d.typ, d.cnt, d.max = End, typ, 1, 1
+		enc.opened, enc.closed, enc.max, enc.cnt = true, true, 1, 1
 	}
 
-	return d.max - d.cnt
+	return
 }
 
-// Err returns the last error returned by the Decode method.
-//
-// The method returns nil if the stream reached its natural end.
-func (d *StreamDecoder) Err() error {
-	if d.err == End {
+func (d *StreamDecoder) setDeadline() error {
+	if d.IdleTimeout <= 0 {
 		return nil
 	}
-	return d.err
+	p, ok := d.Parser.(DeadlineParser)
+	if !ok {
+		return fmt.Errorf("objconv: the stream decoder has an idle timeout but its parser (%T) does not support read deadlines", d.Parser)
+	}
+	return p.SetReadDeadline(time.Now().Add(d.IdleTimeout))
 }
 
-// Decodes the next value from the stream into v.
-func (d *StreamDecoder) Decode(v interface{}) error {
-	if d.err != nil {
-		return d.err
+func (d *StreamDecoder) clearDeadline() {
+	if d.IdleTimeout > 0 {
+		d.Parser.(DeadlineParser).SetReadDeadline(time.Time{})
 	}
+}
 
-	err := error(nil)
-	cnt := d.cnt
-	max := d.max
-	dec := Decoder{
-		Parser:  d.Parser,
-		MapType: d.MapType,
+// idleError converts err to ErrIdleTimeout if it was caused by the expiration
+// of the idle timeout.
+func (d *StreamDecoder) idleError(err error) error {
+	var t interface{ Timeout() bool }
+	if d.IdleTimeout > 0 && errors.As(err, &t) && t.Timeout() {
+		return ErrIdleTimeout
 	}
+	return err
+}
 
-	switch d.typ {
-	case Unknown:
-		err = d.init()
-		max = d.max
-	case Array:
-		if cnt == max {
-			err = dec.Parser.ParseArrayEnd(cnt)
-		} else if cnt != 0 {
-			err = dec.Parser.ParseArrayNext(cnt)
-		}
+func (d *StreamDecoder) decoder() Decoder {
+	return Decoder{
+		Parser:                d.Parser,
+		MapType:               d.MapType,
+		DisallowUnknownFields: d.DisallowUnknownFields,
+		OnUnknownField:        d.OnUnknownField,
+		RawEmitter:            d.RawEmitter,
 	}
+}
 
-	if err == nil {
-		if cnt == max {
-			err = End
+func (d *StreamDecoder) initMap() error {
+	err := error(nil)
+	typ := Unknown
+	max := 0
+
+	if typ, err = d.Parser.ParseType(); err == nil {
+		if typ != Map {
+			err = typeConversionError(typ, Map)
 		} else {
-			switch err = dec.Decode(v); err {
-			case nil:
-				cnt++
-			case End:
-				cnt++
-				max = cnt
-			default:
-				if max < 0 && dec.Parser.ParseArrayEnd(cnt) == nil {
-					err = End
-				}
-			}
+			max, err = d.Parser.ParseMapBegin()
 		}
 	}
 
 	d.err = err
-	d.cnt = cnt
+	d.typ = typ
 	d.max = max
+	d.entries = true
 	return err
 }
 
-// Encoder returns a new StreamEncoder which can be used to re-encode the stream
-// decoded by d into e.
-//
-// The method panics if e is nil.
-func (d *StreamDecoder) Encoder(e Emitter) (enc *StreamEncoder, err error) {
-	var typ Type
-
-	if typ, err = d.Parser.ParseType(); err == nil {
-		enc = NewStreamEncoder(e)
-		enc.oneshot = typ != Array
-	}
-
-	return
-}
-
 func (d *StreamDecoder) init() error {
 	err := error(nil)
 	typ := Unknown
@@ -1408,6 +4538,17 @@ type ValueDecoder interface {
 	DecodeValue(Decoder) error
 }
 
+// TypedValueDecoder is a variant of ValueDecoder for types which need to know
+// the type of the value being decoded, the decoder parses the type and passes
+// it to the DecodeTypedValue method. The method must then parse the value with
+// the method of the parser matching the type (for example ParseString for
+// String values), or decode it with the decoder.
+//
+// Types implementing both interfaces are decoded with DecodeTypedValue.
+type TypedValueDecoder interface {
+	DecodeTypedValue(Decoder, Type) error
+}
+
 // ValueDecoderFunc allows the use of regular functions or methods as value
 // decoders.
 type ValueDecoderFunc func(Decoder) error
@@ -1418,12 +4559,69 @@ func (f ValueDecoderFunc) DecodeValue(d Decoder) error { return f(d) }
 type decodeFuncOpts struct {
 	recurse bool
 	structs map[reflect.Type]*structType
+	// types holds the slice, array, map and pointer types which functions are
+	// being built with the recurse option. The structs map breaks the cycles of
+	// recursive struct types, but types like map[string]T where T is the map
+	// type itself never go through a struct, so the functions of those types
+	// fall back to the non-recursive implementation when they are seen again.
+	types map[reflect.Type]bool
+}
+
+// enter records that the function of t is being built, it returns false if t
+// was already entered, meaning that t is a recursive type.
+func (opts *decodeFuncOpts) enter(t reflect.Type) bool {
+	if opts.types[t] {
+		return false
+	}
+	if opts.types == nil {
+		opts.types = make(map[reflect.Type]bool)
+	}
+	opts.types[t] = true
+	return true
 }
 
+func (opts decodeFuncOpts) leave(t reflect.Type) { delete(opts.types, t) }
+
 type decodeFunc func(Decoder, reflect.Value) (Type, error)
 
+// decodeFuncOf returns the decode function of t, which is memoized so the
+// dispatch on the properties of the type is only done once.
+//
+// The functions are built without the recurse option, the functions of
+// composite types like slices or maps look up the decode functions of their
+// elements with decodeFuncOf when they are called. Building a function never
+// requires the function of another type, so recursive types need no special
+// handling here.
 func decodeFuncOf(t reflect.Type) decodeFunc {
-	return makeDecodeFunc(t, decodeFuncOpts{})
+	cache := decodeFuncCache.Load().(*sync.Map)
+
+	if f, ok := cache.Load(t); ok {
+		return f.(decodeFunc)
+	}
+
+	// The function is stored in the map that was loaded before building it,
+	// so it is dropped with this map if the cache is cleared in the meantime,
+	// since it may have been built from the adapters or enums being replaced.
+	f := makeDecodeFunc(t, decodeFuncOpts{})
+	cache.Store(t, f)
+	return f
+}
+
+// decodeFuncCache is the cache of decodeFuncOf, it holds a *sync.Map which
+// maps reflect.Type values to decodeFunc values. It must be cleared with
+// clearDecodeFuncCache when installing adapters, enums, or kind decoders, since
+// they change the functions of the types.
+var decodeFuncCache atomic.Value
+
+func init() {
+	clearDecodeFuncCache()
+}
+
+// clearDecodeFuncCache empties the cache of decodeFuncOf by replacing its map,
+// which acts as the generation of the cache: the functions built from an older
+// generation are never stored in the current one.
+func clearDecodeFuncCache() {
+	decodeFuncCache.Store(new(sync.Map))
 }
 
 func makeDecodeFunc(t reflect.Type, opts decodeFuncOpts) decodeFunc {
@@ -1435,6 +4633,14 @@ func makeDecodeFunc(t reflect.Type, opts decodeFuncOpts) decodeFunc {
 		}
 	}
 
+	if e, ok := enumOf(t); ok {
+		return e.decode
+	}
+
+	if f := makeAtomicDecodeFunc(t); f != nil {
+		return f
+	}
+
 	// fast path: check if it's a basic go type
 	switch t {
 	case boolType:
@@ -1452,6 +4658,50 @@ func makeDecodeFunc(t reflect.Type, opts decodeFuncOpts) decodeFunc {
 	case durationType:
 		return Decoder.decodeDuration
 
+	case bigIntType:
+		return Decoder.decodeBigInt
+
+	case bigFloatType:
+		return Decoder.decodeBigFloat
+
+	case bigIntPtrType:
+		return func(d Decoder, v reflect.Value) (Type, error) {
+			return d.decodePointerWith(v, Decoder.decodeBigInt)
+		}
+
+	case bigFloatPtrType:
+		return func(d Decoder, v reflect.Value) (Type, error) {
+			return d.decodePointerWith(v, Decoder.decodeBigFloat)
+		}
+
+	case ipType:
+		return Decoder.decodeIP
+
+	case ipNetType:
+		return Decoder.decodeIPNet
+
+	case ipNetPtrType:
+		return func(d Decoder, v reflect.Value) (Type, error) {
+			return d.decodePointerWith(v, Decoder.decodeIPNet)
+		}
+
+	case urlType:
+		return Decoder.decodeURL
+
+	case urlPtrType:
+		return func(d Decoder, v reflect.Value) (Type, error) {
+			return d.decodePointerWith(v, Decoder.decodeURL)
+		}
+
+	case regexpPtrType:
+		return Decoder.decodeRegexp
+
+	case jsonNumberType:
+		return Decoder.decodeJSONNumber
+
+	case atomicValueType:
+		return Decoder.decodeAtomicValue
+
 	case emptyInterface:
 		return Decoder.decodeInterface
 
@@ -1467,7 +4717,14 @@ func makeDecodeFunc(t reflect.Type, opts decodeFuncOpts) decodeFunc {
 
 	// check if it implements one of the special case interfaces, first on the
 	// plain type, then on the pointer type
+	//
+	// This must happen before looking at the kind of the type, so arrays like
+	// [16]byte UUIDs which implement encoding.TextUnmarshaler are decoded from
+	// their textual representation rather than element by element.
 	switch {
+	case t.Implements(typedValueDecoderInterface):
+		return Decoder.decodeTypedDecoder
+
 	case t.Implements(valueDecoderInterface):
 		return Decoder.decodeDecoder
 
@@ -1485,6 +4742,9 @@ func makeDecodeFunc(t reflect.Type, opts decodeFuncOpts) decodeFunc {
 	}
 
 	switch p := reflect.PtrTo(t); {
+	case p.Implements(typedValueDecoderInterface):
+		return Decoder.decodeTypedDecoderPointer
+
 	case p.Implements(valueDecoderInterface):
 		return Decoder.decodeDecoderPointer
 
@@ -1496,8 +4756,30 @@ func makeDecodeFunc(t reflect.Type, opts decodeFuncOpts) decodeFunc {
 
 	case p.Implements(textUnmarshalerInterface):
 		return Decoder.decodeTextUnmarshalerPointer
+
+	case p.Implements(scannerInterface):
+		return makeDecodeScannerFunc(t, opts)
+	}
+
+	f := makeDecodeKindFunc(t, opts)
+
+	// The named types of scalar kinds may be enums which parse functions are
+	// set in the EnumParser map of the decoder.
+	if len(t.PkgPath()) != 0 {
+		switch t.Kind() {
+		case reflect.Bool, reflect.String,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+			reflect.Float32, reflect.Float64:
+			return makeDecodeEnumParserFunc(t, f)
+		}
 	}
 
+	return f
+}
+
+// makeDecodeKindFunc returns the decode function of t based on its kind.
+func makeDecodeKindFunc(t reflect.Type, opts decodeFuncOpts) decodeFunc {
 	// check what kind is the type, potentially generate a decoder
 	switch t.Kind() {
 	case reflect.Struct:
@@ -1530,18 +4812,35 @@ func makeDecodeFunc(t reflect.Type, opts decodeFuncOpts) decodeFunc {
 	case reflect.Float32, reflect.Float64:
 		return Decoder.decodeFloat
 
+	case reflect.Complex64, reflect.Complex128:
+		return Decoder.decodeComplex
+
 	case reflect.String:
 		return Decoder.decodeString
 
+	case reflect.Interface:
+		return Decoder.decodeRegisteredInterface
+
 	default:
+		if f, ok := kindDecoderOf(t.Kind()); ok {
+			return f
+		}
 		return Decoder.decodeUnsupported
 	}
 }
 
+func makeDecodeScannerFunc(t reflect.Type, opts decodeFuncOpts) decodeFunc {
+	f := makeDecodeKindFunc(t, opts)
+	return func(d Decoder, v reflect.Value) (Type, error) {
+		return d.decodeScannerPointer(v, f)
+	}
+}
+
 func makeDecodeSliceFunc(t reflect.Type, opts decodeFuncOpts) decodeFunc {
-	if !opts.recurse {
+	if !opts.recurse || !opts.enter(t) {
 		return Decoder.decodeSlice
 	}
+	defer opts.leave(t)
 	f := makeDecodeFunc(t.Elem(), opts)
 	return func(d Decoder, v reflect.Value) (Type, error) {
 		return d.decodeSliceWith(v, f)
@@ -1549,9 +4848,10 @@ func makeDecodeSliceFunc(t reflect.Type, opts decodeFuncOpts) decodeFunc {
 }
 
 func makeDecodeArrayFunc(t reflect.Type, opts decodeFuncOpts) decodeFunc {
-	if !opts.recurse {
+	if !opts.recurse || !opts.enter(t) {
 		return Decoder.decodeArray
 	}
+	defer opts.leave(t)
 	f := makeDecodeFunc(t.Elem(), opts)
 	return func(d Decoder, v reflect.Value) (Type, error) {
 		return d.decodeArrayWith(v, f)
@@ -1559,16 +4859,55 @@ func makeDecodeArrayFunc(t reflect.Type, opts decodeFuncOpts) decodeFunc {
 }
 
 func makeDecodeMapFunc(t reflect.Type, opts decodeFuncOpts) decodeFunc {
-	if !opts.recurse {
+	if !opts.recurse || !opts.enter(t) {
 		return Decoder.decodeMap
 	}
-	kf := makeDecodeFunc(t.Key(), opts)
+	defer opts.leave(t)
+	kf := makeDecodeMapKeyFunc(t.Key(), makeDecodeFunc(t.Key(), opts))
 	vf := makeDecodeFunc(t.Elem(), opts)
 	return func(d Decoder, v reflect.Value) (Type, error) {
 		return d.decodeMapWith(v, kf, vf)
 	}
 }
 
+// makeDecodeMapKeyFunc wraps kf, the decode function of map keys of type t, to
+// reject keys parsed as strings (the only kind of keys in formats like json)
+// when they cannot be converted to t.
+//
+// Strings can be decoded into keys of string and numeric kinds, empty
+// interfaces, and types implementing encoding.TextUnmarshaler, like the
+// standard encoding/json package does.
+func makeDecodeMapKeyFunc(t reflect.Type, kf decodeFunc) decodeFunc {
+	switch t.Kind() {
+	case reflect.String, reflect.Interface,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return kf
+	}
+
+	if _, ok := AdapterOf(t); ok {
+		return kf
+	}
+
+	for _, i := range [...]reflect.Type{textUnmarshalerInterface, valueDecoderInterface, typedValueDecoderInterface} {
+		if t.Implements(i) || reflect.PtrTo(t).Implements(i) {
+			return kf
+		}
+	}
+
+	return func(d Decoder, v reflect.Value) (typ Type, err error) {
+		if typ, err = d.Parser.ParseType(); err != nil {
+			return
+		}
+		if typ == String {
+			err = fmt.Errorf("objconv: cannot decode map keys of type %s from strings, the key type must be a string, a number, or implement encoding.TextUnmarshaler", t)
+			return
+		}
+		return kf(d, v)
+	}
+}
+
 func makeDecodeStructFunc(t reflect.Type, opts decodeFuncOpts) decodeFunc {
 	if !opts.recurse {
 		return Decoder.decodeStruct
@@ -1580,15 +4919,211 @@ func makeDecodeStructFunc(t reflect.Type, opts decodeFuncOpts) decodeFunc {
 }
 
 func makeDecodePtrFunc(t reflect.Type, opts decodeFuncOpts) decodeFunc {
-	if !opts.recurse {
+	if !opts.recurse || !opts.enter(t) {
 		return Decoder.decodePointer
 	}
+	defer opts.leave(t)
 	f := makeDecodeFunc(t.Elem(), opts)
 	return func(d Decoder, v reflect.Value) (Type, error) {
 		return d.decodePointerWith(v, f)
 	}
 }
 
+// sortedKeys returns a key order checker if the decoder was configured to
+// require sorted keys, or nil otherwise.
+func (d Decoder) sortedKeys() *keyOrder {
+	if !d.RequireSortedKeys {
+		return nil
+	}
+	compare := d.KeyCompare
+	if compare == nil {
+		compare = CompareKeys
+	}
+	return &keyOrder{compare: compare}
+}
+
+// duplicateKeys returns a set used to detect duplicate keys if the decoder was
+// configured to reject them, or nil otherwise.
+func (d Decoder) duplicateKeys() keySet {
+	if !d.RejectDuplicateKeys {
+		return nil
+	}
+	return make(keySet)
+}
+
+// keySet is used to verify that the keys of a map are unique.
+type keySet map[interface{}]struct{}
+
+func (s keySet) check(k interface{}) error {
+	c := k
+	if n, ok := canonicalNumber(k); ok {
+		c = n
+	}
+	if _, dup := s[c]; dup {
+		return fmt.Errorf("objconv: duplicate map key %#v", k)
+	}
+	s[c] = struct{}{}
+	return nil
+}
+
+// keyOrder is used to verify that the keys of a map are sorted.
+type keyOrder struct {
+	compare func(interface{}, interface{}) int
+	prev    interface{}
+	init    bool
+}
+
+func (o *keyOrder) check(k interface{}) (err error) {
+	if o.init && o.compare(o.prev, k) >= 0 {
+		err = fmt.Errorf("objconv: map keys are not sorted, %#v was found after %#v", k, o.prev)
+	}
+	o.prev, o.init = k, true
+	return
+}
+
+// CompareKeys is the default function used to compare map keys when a decoder
+// requires them to be sorted.
+//
+// Strings and byte slices are compared lexicographically, numbers are compared
+// by value. Keys of other types, or of different types, are compared by their
+// string representations.
+func CompareKeys(a interface{}, b interface{}) int {
+	if x, ok := keyString(a); ok {
+		if y, ok := keyString(b); ok {
+			return strings.Compare(x, y)
+		}
+	}
+
+	if x, ok := keyNumber(a); ok {
+		if y, ok := keyNumber(b); ok {
+			return compareNumbers(x, y)
+		}
+	}
+
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// CompareKeysLengthFirst compares map keys by sorting the shortest strings or
+// byte slices first, then lexicographically for keys of equal length. This is
+// the ordering used by the canonical CBOR encoding described in RFC 7049.
+//
+// Keys which aren't strings or byte slices are compared with CompareKeys.
+func CompareKeysLengthFirst(a interface{}, b interface{}) int {
+	if x, ok := keyString(a); ok {
+		if y, ok := keyString(b); ok {
+			switch {
+			case len(x) < len(y):
+				return -1
+			case len(x) > len(y):
+				return 1
+			default:
+				return strings.Compare(x, y)
+			}
+		}
+	}
+	return CompareKeys(a, b)
+}
+
+func keyString(k interface{}) (string, bool) {
+	switch v := reflect.ValueOf(k); v.Kind() {
+	case reflect.String:
+		return v.String(), true
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return string(v.Bytes()), true
+		}
+	}
+	return "", false
+}
+
+func keyNumber(k interface{}) (reflect.Value, bool) {
+	switch v := reflect.ValueOf(k); v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return v, true
+	}
+	return reflect.Value{}, false
+}
+
+func compareNumbers(a reflect.Value, b reflect.Value) int {
+	ka, kb := numberKind(a), numberKind(b)
+
+	switch {
+	case ka == Int && kb == Int:
+		return compareInt64(a.Int(), b.Int())
+	case ka == Uint && kb == Uint:
+		return compareUint64(a.Uint(), b.Uint())
+	case ka == Int && kb == Uint:
+		if a.Int() < 0 {
+			return -1
+		}
+		return compareUint64(uint64(a.Int()), b.Uint())
+	case ka == Uint && kb == Int:
+		if b.Int() < 0 {
+			return 1
+		}
+		return compareUint64(a.Uint(), uint64(b.Int()))
+	default:
+		return compareFloat64(numberFloat(a), numberFloat(b))
+	}
+}
+
+func numberKind(v reflect.Value) Type {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return Int
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return Uint
+	default:
+		return Float
+	}
+}
+
+func numberFloat(v reflect.Value) float64 {
+	switch numberKind(v) {
+	case Int:
+		return float64(v.Int())
+	case Uint:
+		return float64(v.Uint())
+	default:
+		return v.Float()
+	}
+}
+
+func compareInt64(a int64, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareUint64(a uint64, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareFloat64(a float64, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
 // unsafeString returns a string that is only safe to use under the following conditions:
 // - b points to data on the heap
 // - the bytes pointed to by b will not be modified while the returned string exists
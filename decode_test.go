@@ -1,9 +1,21 @@
 package objconv
 
 import (
+	"bytes"
+	"database/sql"
 	"errors"
 	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"net/url"
 	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -420,3 +432,5072 @@ func TestStreamRencode(t *testing.T) {
 		})
 	}
 }
+
+func TestTranscode(t *testing.T) {
+	date := time.Date(2016, 12, 12, 1, 1, 1, 0, time.UTC)
+
+	tests := []interface{}{
+		nil,
+		true,
+		int64(-1),
+		uint64(1),
+		0.5,
+		"Hello World!",
+		[]byte("bytes"),
+		date,
+		time.Second,
+		errors.New("error"),
+		[]interface{}{int64(1), "2", []interface{}{}},
+		map[interface{}]interface{}{
+			"a": map[interface{}]interface{}{"b": []interface{}{nil}},
+			"c": int64(2),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprint(test), func(t *testing.T) {
+			out := NewValueEmitter()
+
+			if err := Transcode(out, NewValueParser(test)); err != nil {
+				t.Fatal(err)
+			}
+
+			if v := out.Value(); !reflect.DeepEqual(v, test) {
+				t.Errorf("%#v != %#v", v, test)
+			}
+		})
+	}
+}
+
+func TestStreamDecoderDecodeMapEntry(t *testing.T) {
+	in := struct {
+		A int
+		B []string
+		C map[string]int
+	}{1, []string{"x"}, map[string]int{"y": 2}}
+
+	dec := NewStreamDecoder(NewValueParser(in))
+
+	var keys []string
+	var values []interface{}
+
+	for {
+		var k string
+		var v interface{}
+
+		if err := dec.DecodeMapEntry(&k, &v); err != nil {
+			if err != End {
+				t.Fatal(err)
+			}
+			break
+		}
+
+		keys = append(keys, k)
+		values = append(values, v)
+	}
+
+	if err := dec.Err(); err != nil {
+		t.Error(err)
+	}
+
+	if !reflect.DeepEqual(keys, []string{"A", "B", "C"}) {
+		t.Errorf("%#v", keys)
+	}
+
+	expect := []interface{}{
+		int64(1),
+		[]interface{}{"x"},
+		map[interface{}]interface{}{"y": int64(2)},
+	}
+
+	if !reflect.DeepEqual(values, expect) {
+		t.Errorf("%#v != %#v", values, expect)
+	}
+
+	if err := dec.Decode(nil); err != End {
+		t.Error("expected End but got", err)
+	}
+
+	t.Run("not-a-map", func(t *testing.T) {
+		var k, v interface{}
+		if err := NewStreamDecoder(NewValueParser([]int{1})).DecodeMapEntry(&k, &v); err == nil {
+			t.Error("expected an error decoding map entries from an array")
+		}
+	})
+
+	t.Run("mixed", func(t *testing.T) {
+		var k, v interface{}
+		dec := NewStreamDecoder(NewValueParser(in))
+
+		if err := dec.DecodeMapEntry(&k, &v); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := dec.Decode(&v); err == nil {
+			t.Error("expected an error calling Decode after DecodeMapEntry")
+		}
+	})
+}
+
+func TestDecoderKeyRewriter(t *testing.T) {
+	type T struct {
+		UserName  string
+		UserEmail string
+		Unknown   []string `objconv:",unknownfields"`
+	}
+
+	camelCase := func(k string) string {
+		parts := strings.Split(k, "-")
+		for i, p := range parts {
+			if len(p) != 0 {
+				parts[i] = strings.ToUpper(p[:1]) + p[1:]
+			}
+		}
+		return strings.Join(parts, "")
+	}
+
+	in := map[string]interface{}{
+		"user-name":  "luke",
+		"user-email": "luke@example.com",
+		"user-age":   42,
+	}
+
+	var v T
+	d := Decoder{Parser: NewValueParser(in), KeyRewriter: camelCase}
+
+	if err := d.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Strings(v.Unknown)
+
+	if !reflect.DeepEqual(v, T{UserName: "luke", UserEmail: "luke@example.com", Unknown: []string{"user-age"}}) {
+		t.Errorf("%#v", v)
+	}
+
+	t.Run("maps", func(t *testing.T) {
+		var m map[string]string
+		d := Decoder{Parser: NewValueParser(map[string]string{"user-name": "luke"}), KeyRewriter: camelCase}
+
+		if err := d.Decode(&m); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(m, map[string]string{"user-name": "luke"}) {
+			t.Errorf("%#v", m)
+		}
+	})
+}
+
+func TestDecoderFuzzyFieldMatch(t *testing.T) {
+	type T struct {
+		Hostname string `objconv:"hostname"`
+		Port     int    `objconv:"port"`
+	}
+
+	t.Run("match", func(t *testing.T) {
+		var v T
+		dec := Decoder{Parser: NewValueParser(map[string]interface{}{"hostnme": "localhost", "prot": 8080}), FuzzyFieldMatch: true}
+
+		if err := dec.Decode(&v); err != nil {
+			t.Error(err)
+		}
+		if v != (T{"localhost", 8080}) {
+			t.Errorf("%#v", v)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		var v T
+		dec := Decoder{Parser: NewValueParser(map[string]interface{}{"hostnme": "localhost"})}
+
+		if err := dec.Decode(&v); err != nil {
+			t.Error(err)
+		}
+		if v != (T{}) {
+			t.Errorf("%#v", v)
+		}
+	})
+
+	t.Run("too far", func(t *testing.T) {
+		var v T
+		dec := Decoder{Parser: NewValueParser(map[string]interface{}{"hst": "localhost"}), FuzzyFieldMatch: true, FuzzyFieldDistance: 1}
+
+		if err := dec.Decode(&v); err != nil {
+			t.Error(err)
+		}
+		if v != (T{}) {
+			t.Errorf("%#v", v)
+		}
+	})
+
+	t.Run("ambiguous", func(t *testing.T) {
+		var v struct{ AB, AC int }
+		dec := Decoder{Parser: NewValueParser(map[string]interface{}{"AD": 1}), FuzzyFieldMatch: true}
+
+		if err := dec.Decode(&v); err == nil {
+			t.Error("expected an error for an ambiguous key")
+		}
+	})
+}
+
+func TestDecoderStructWarnings(t *testing.T) {
+	type Inner struct {
+		Port int
+	}
+
+	type T struct {
+		Name     string
+		Inner    Inner
+		Warnings []string `objconv:",warnings"`
+	}
+
+	var v T
+	dec := NewDecoder(NewValueParser(map[string]interface{}{
+		"Name":  42,
+		"Inner": map[string]interface{}{"Port": "8080", "Host": "localhost"},
+	}))
+
+	if err := dec.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Name != "42" || v.Inner.Port != 8080 {
+		t.Errorf("%#v", v)
+	}
+
+	sort.Strings(v.Warnings)
+	expect := []string{
+		`converted int to string`,
+		`converted string to int`,
+		`discarded unknown key "Host"`,
+	}
+
+	if !reflect.DeepEqual(v.Warnings, expect) {
+		t.Errorf("%#v != %#v", v.Warnings, expect)
+	}
+}
+
+func TestDecoderStructWarningsInvalidType(t *testing.T) {
+	var v struct {
+		Warnings string `objconv:",warnings"`
+	}
+
+	if err := NewDecoder(NewValueParser(map[string]interface{}{})).Decode(&v); err == nil {
+		t.Error("expected an error for a warnings field which isn't a []string")
+	}
+}
+
+func TestDecoderStructFieldErrors(t *testing.T) {
+	type Address struct {
+		Zip int
+	}
+
+	type Form struct {
+		Name    string
+		Age     int
+		Tags    []int
+		Address Address
+		Errors  FieldResult `objconv:",fielderrors"`
+	}
+
+	var v Form
+	dec := NewDecoder(NewValueParser(map[string]interface{}{
+		"Name":    "Luke",
+		"Age":     "old",
+		"Tags":    []interface{}{1, "two", 3},
+		"Address": map[string]interface{}{"Zip": true},
+	}))
+
+	if err := dec.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Name != "Luke" || v.Age != 0 || v.Tags != nil || v.Address != (Address{}) {
+		t.Errorf("%#v", v)
+	}
+
+	for _, name := range []string{"Age", "Tags", "Address"} {
+		if v.Errors[name] == nil {
+			t.Errorf("missing error for field %s", name)
+		}
+	}
+
+	if len(v.Errors) != 3 {
+		t.Errorf("%#v", v.Errors)
+	}
+
+	if _, ok := v.Errors["Name"]; ok {
+		t.Error("unexpected error recorded for a field that was successfully decoded")
+	}
+
+	t.Run("no errors", func(t *testing.T) {
+		var v Form
+
+		if err := NewDecoder(NewValueParser(map[string]interface{}{"Age": 42})).Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+		if v.Age != 42 || v.Errors != nil {
+			t.Errorf("%#v", v)
+		}
+	})
+
+	t.Run("invalid type", func(t *testing.T) {
+		var v struct {
+			Errors map[string]error `objconv:",fielderrors"`
+		}
+
+		if err := NewDecoder(NewValueParser(map[string]interface{}{})).Decode(&v); err == nil {
+			t.Error("expected an error for a fielderrors field which isn't a FieldResult")
+		}
+	})
+}
+
+func TestDecoderStructUnexportedFields(t *testing.T) {
+	type T struct {
+		Name   string
+		secret string
+		hidden int      `objconv:"hidden"`
+		tagged []string `objconv:",unknownfields"`
+	}
+
+	in := map[string]interface{}{
+		"Name":   "Luke",
+		"secret": "s3cr3t",
+		"hidden": 42,
+	}
+
+	var v T
+
+	if err := NewDecoder(NewValueParser(in)).Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	if expect := (T{Name: "Luke"}); !reflect.DeepEqual(v, expect) {
+		t.Errorf("%#v != %#v", v, expect)
+	}
+
+	if s := structCache.lookup(reflect.TypeOf(v)); len(s.fields) != 1 || s.fieldsByName["hidden"] != nil || s.unknown != nil {
+		t.Errorf("unexported fields must not be part of the struct fields: %#v", s.fields)
+	}
+
+	d := Decoder{Parser: NewValueParser(in), DisallowUnknownFields: true}
+
+	if err := d.Decode(&v); err == nil {
+		t.Error("expected the keys of unexported fields to be reported as unknown fields")
+	}
+}
+
+type EmbeddedAddress struct {
+	City string
+	Zip  string
+}
+
+type embeddedName struct {
+	Name string
+}
+
+type embeddedCity struct {
+	City string
+}
+
+func TestDecoderStructEmbedded(t *testing.T) {
+	type T struct {
+		*EmbeddedAddress
+		embeddedName
+		ID  int
+		Zip int
+	}
+
+	t.Run("present", func(t *testing.T) {
+		var v T
+		in := map[string]interface{}{"ID": 1, "City": "Paris", "Name": "Luke", "Zip": 75000}
+
+		if err := NewDecoder(NewValueParser(in)).Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+
+		expect := T{
+			EmbeddedAddress: &EmbeddedAddress{City: "Paris"},
+			embeddedName:    embeddedName{Name: "Luke"},
+			ID:              1,
+			Zip:             75000,
+		}
+
+		if !reflect.DeepEqual(v, expect) {
+			t.Errorf("%#v != %#v", v, expect)
+		}
+	})
+
+	t.Run("absent", func(t *testing.T) {
+		var v T
+
+		if err := NewDecoder(NewValueParser(map[string]interface{}{"ID": 2})).Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+
+		if v.EmbeddedAddress != nil || v.ID != 2 {
+			t.Errorf("%#v", v)
+		}
+	})
+
+	t.Run("encode", func(t *testing.T) {
+		var m map[string]interface{}
+
+		if err := NewDecoder(NewValueParser(T{ID: 3})).Decode(&m); err != nil {
+			t.Fatal(err)
+		}
+
+		// Promoted fields are only decoded, the output of the encoder is the
+		// same as if the fields of embedded structs were not promoted.
+		expect := map[string]interface{}{"ID": int64(3), "Zip": int64(0)}
+
+		if !reflect.DeepEqual(m, expect) {
+			t.Errorf("%#v != %#v", m, expect)
+		}
+	})
+
+	t.Run("ambiguous", func(t *testing.T) {
+		type Inner struct {
+			Name string
+		}
+		type Outer struct {
+			Inner
+		}
+		type Tagged struct {
+			Label string `objconv:"Zip"`
+		}
+		var v struct {
+			Outer
+			EmbeddedAddress
+			embeddedName
+			Tagged
+		}
+		in := map[string]interface{}{"Name": "Luke", "City": "Paris", "Zip": "75000"}
+
+		if err := NewDecoder(NewValueParser(in)).Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+
+		// Name is at the same depth in embeddedName and in Outer.Inner, the
+		// shallowest wins; Zip is at the same depth in EmbeddedAddress and
+		// Tagged, the one named by a tag wins.
+		if v.embeddedName.Name != "Luke" || v.Inner.Name != "" || v.City != "Paris" || v.Label != "75000" || v.Zip != "" {
+			t.Errorf("%#v", v)
+		}
+
+		var u struct {
+			EmbeddedAddress
+			embeddedCity
+		}
+
+		// City is at the same depth in both embedded structs and none has a
+		// tag, the key is unknown.
+		if err := NewDecoder(NewValueParser(in)).Decode(&u); err != nil {
+			t.Fatal(err)
+		}
+
+		if u.EmbeddedAddress.City != "" || u.embeddedCity.City != "" || u.Zip != "75000" {
+			t.Errorf("%#v", u)
+		}
+	})
+
+	t.Run("tagged", func(t *testing.T) {
+		var v struct {
+			EmbeddedAddress `objconv:"address"`
+		}
+		in := map[string]interface{}{"address": map[string]interface{}{"City": "Rome"}, "City": "Oslo"}
+
+		if err := NewDecoder(NewValueParser(in)).Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+
+		if v.City != "Rome" {
+			t.Errorf("%#v", v)
+		}
+	})
+}
+
+func TestDecoderStructRequired(t *testing.T) {
+	type T struct {
+		ID    int    `objconv:"id,required"`
+		Name  string `objconv:"name,required"`
+		Email string `objconv:"email,required,alias=mail"`
+		Note  string `objconv:"note"`
+	}
+
+	tests := []struct {
+		name string
+		in   map[string]interface{}
+		err  string
+	}{
+		{
+			name: "all present",
+			in:   map[string]interface{}{"id": 0, "name": "", "mail": "luke@example.com"},
+		},
+		{
+			name: "one missing",
+			in:   map[string]interface{}{"id": 1, "email": "luke@example.com", "note": "hi"},
+			err:  "the required field name of objconv.T is missing",
+		},
+		{
+			name: "several missing",
+			in:   map[string]interface{}{"note": "hi"},
+			err:  "the required fields id, name, email of objconv.T are missing",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var v T
+			err := NewDecoder(NewValueParser(test.in)).Decode(&v)
+
+			switch {
+			case len(test.err) == 0 && err != nil:
+				t.Error(err)
+			case len(test.err) != 0 && (err == nil || !strings.Contains(err.Error(), test.err)):
+				t.Errorf("expected an error containing %q but got %v", test.err, err)
+			case len(test.err) != 0 && v != (T{}):
+				t.Errorf("the struct must be zeroed on error: %#v", v)
+			}
+		})
+	}
+
+	t.Run("null", func(t *testing.T) {
+		var v *T
+
+		if err := NewDecoder(NewValueParser(nil)).Decode(&v); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestDecoderStructUnknownFields(t *testing.T) {
+	type T struct {
+		Name    string
+		Unknown []string `objconv:",unknownfields"`
+	}
+
+	var v T
+	dec := NewDecoder(NewValueParser(struct {
+		A    int
+		Name string
+		B    []int
+	}{1, "Luke", []int{1, 2}}))
+
+	if err := dec.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := T{Name: "Luke", Unknown: []string{"A", "B"}}
+
+	if !reflect.DeepEqual(v, expect) {
+		t.Errorf("%#v != %#v", v, expect)
+	}
+}
+
+func TestDecoderStructUnknownFieldsInvalidType(t *testing.T) {
+	var v struct {
+		Unknown map[string]interface{} `objconv:",unknownfields"`
+	}
+
+	if err := NewDecoder(NewValueParser(map[string]interface{}{})).Decode(&v); err == nil {
+		t.Error("expected an error for an unknownfields field which isn't a []string")
+	}
+}
+
+func TestDecoderStructExtra(t *testing.T) {
+	type T struct {
+		Name  string
+		Age   int
+		Extra map[string]interface{} `objconv:",extra"`
+	}
+
+	var v T
+	dec := NewDecoder(NewValueParser(map[string]interface{}{
+		"Name": "Luke",
+		"Age":  19,
+		"A":    1,
+		"B":    []interface{}{"x", "y"},
+	}))
+
+	if err := dec.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := T{
+		Name: "Luke",
+		Age:  19,
+		Extra: map[string]interface{}{
+			"A": int64(1),
+			"B": []interface{}{"x", "y"},
+		},
+	}
+
+	if !reflect.DeepEqual(v, expect) {
+		t.Errorf("%#v != %#v", v, expect)
+	}
+
+	t.Run("round-trip", func(t *testing.T) {
+		e := NewValueEmitter()
+
+		if err := (Encoder{Emitter: e, SortMapKeys: true}).Encode(expect); err != nil {
+			t.Fatal(err)
+		}
+
+		m := e.Value().(map[interface{}]interface{})
+
+		if len(m) != 4 || m["A"] != int64(1) || m["Name"] != "Luke" {
+			t.Errorf("bad encoded value: %#v", m)
+		}
+
+		var v T
+		if err := NewDecoder(NewValueParser(m)).Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(v, expect) {
+			t.Errorf("%#v != %#v", v, expect)
+		}
+	})
+
+	t.Run("shadowed", func(t *testing.T) {
+		e := NewValueEmitter()
+
+		if err := NewEncoder(e).Encode(T{Name: "Luke", Extra: map[string]interface{}{"Name": "Leia"}}); err != nil {
+			t.Fatal(err)
+		}
+
+		if m := e.Value().(map[interface{}]interface{}); len(m) != 2 || m["Name"] != "Luke" {
+			t.Errorf("bad encoded value: %#v", m)
+		}
+	})
+
+	t.Run("disallow-unknown-fields", func(t *testing.T) {
+		var v T
+		d := Decoder{Parser: NewValueParser(map[string]interface{}{"A": 1}), DisallowUnknownFields: true}
+
+		if err := d.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+
+		if v.Extra["A"] != int64(1) {
+			t.Errorf("%#v", v)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, test := range []interface{}{
+			&struct {
+				A map[string]interface{} `objconv:",extra"`
+				B map[string]interface{} `objconv:",extra"`
+			}{},
+			&struct {
+				A map[string]string `objconv:",extra"`
+			}{},
+			&struct {
+				A map[string]interface{} `objconv:",extra"`
+				B []string               `objconv:",unknownfields"`
+			}{},
+		} {
+			if err := NewDecoder(NewValueParser(map[string]interface{}{})).Decode(test); err == nil {
+				t.Errorf("expected an error decoding into %T", test)
+			}
+		}
+	})
+}
+
+func TestDecoderOnUnknownField(t *testing.T) {
+	type Inner struct {
+		Port int
+	}
+
+	type T struct {
+		Name  string
+		Inner Inner
+		List  []Inner
+	}
+
+	input := map[string]interface{}{
+		"Name":  "Luke",
+		"Age":   19,
+		"Inner": map[string]interface{}{"Port": 1, "Host": "localhost"},
+		"List": []interface{}{
+			map[string]interface{}{"Port": 2, "Host": "a"},
+			map[string]interface{}{"Port": 3, "Proto": "tcp"},
+		},
+	}
+
+	t.Run("count", func(t *testing.T) {
+		var v T
+		var unknown []string
+
+		d := Decoder{
+			Parser: NewValueParser(input),
+			OnUnknownField: func(key string) error {
+				unknown = append(unknown, key)
+				return nil
+			},
+		}
+
+		if err := d.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+
+		sort.Strings(unknown)
+
+		if expect := []string{"Age", "Host", "Host", "Proto"}; !reflect.DeepEqual(unknown, expect) {
+			t.Errorf("%#v != %#v", unknown, expect)
+		}
+
+		if v.Name != "Luke" || v.Inner.Port != 1 || len(v.List) != 2 || v.List[1].Port != 3 {
+			t.Errorf("%#v", v)
+		}
+	})
+
+	t.Run("abort", func(t *testing.T) {
+		var v T
+		fail := errors.New("unknown field")
+
+		d := Decoder{
+			Parser: NewValueParser(map[string]interface{}{"Inner": map[string]interface{}{"Host": "localhost"}}),
+			OnUnknownField: func(key string) error {
+				return fail
+			},
+		}
+
+		err := d.Decode(&v)
+		if e, ok := err.(*DecodeError); !ok || e.Path != "Inner" || e.Err != fail {
+			t.Errorf("bad error: %v", err)
+		}
+	})
+
+	t.Run("disallow-unknown-fields", func(t *testing.T) {
+		var v T
+		var calls int
+
+		d := Decoder{
+			Parser:                NewValueParser(map[string]interface{}{"Age": 19}),
+			DisallowUnknownFields: true,
+			OnUnknownField: func(string) error {
+				calls++
+				return nil
+			},
+		}
+
+		if err := d.Decode(&v); err == nil || calls != 1 {
+			t.Errorf("expected an error after calling the function once, got %v (%d calls)", err, calls)
+		}
+	})
+}
+
+type namedParser struct {
+	*ValueParser
+}
+
+func (p namedParser) FormatName() string {
+	return "test"
+}
+
+func TestDecoderStructFormat(t *testing.T) {
+	type T struct {
+		Name   string
+		Format string `objconv:",format"`
+	}
+
+	tests := []struct {
+		parser Parser
+		format string
+	}{
+		{NewValueParser(map[string]interface{}{"Name": "A"}), ""},
+		{namedParser{NewValueParser(map[string]interface{}{"Name": "A"})}, "test"},
+	}
+
+	for _, test := range tests {
+		var v T
+
+		if err := NewDecoder(test.parser).Decode(&v); err != nil {
+			t.Error(err)
+			continue
+		}
+
+		if v != (T{Name: "A", Format: test.format}) {
+			t.Errorf("%#v", v)
+		}
+	}
+
+	t.Run("invalid-type", func(t *testing.T) {
+		var v struct {
+			Format int `objconv:",format"`
+		}
+
+		if err := NewDecoder(NewValueParser(map[string]interface{}{})).Decode(&v); err == nil {
+			t.Error("expected an error for a format field which isn't a string")
+		}
+	})
+}
+
+func TestDecoderStructTrim(t *testing.T) {
+	type T struct {
+		User  string `objconv:"user,trimprefix=user:"`
+		Email string `objconv:"email,trimsuffix=@example.com"`
+		Tag   string `objconv:"tag,trimprefix=<,trimsuffix=>"`
+	}
+
+	in := map[string]interface{}{
+		"user":  "user:alice",
+		"email": "bob@example.com",
+		"tag":   "hello>",
+	}
+
+	var v T
+
+	if err := NewDecoder(NewValueParser(in)).Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	if v != (T{User: "alice", Email: "bob", Tag: "hello"}) {
+		t.Errorf("%#v", v)
+	}
+
+	e := &ValueEmitter{}
+
+	if err := NewEncoder(e).Encode(v); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := map[interface{}]interface{}{
+		"user":  "user:alice",
+		"email": "bob@example.com",
+		"tag":   "<hello>",
+	}
+
+	if !reflect.DeepEqual(e.Value(), expect) {
+		t.Errorf("%#v != %#v", e.Value(), expect)
+	}
+
+	t.Run("invalid-type", func(t *testing.T) {
+		var v struct {
+			ID int `objconv:"id,trimprefix=id-"`
+		}
+
+		if err := NewDecoder(NewValueParser(map[string]interface{}{})).Decode(&v); err == nil {
+			t.Error("expected an error for a trimprefix field which isn't a string")
+		}
+	})
+}
+
+func TestDecoderStructOneOf(t *testing.T) {
+	type T struct {
+		_     struct{} `objconv:",oneof=email|phone"`
+		Email string   `objconv:"email"`
+		Phone string   `objconv:"phone"`
+		Card  *int     `objconv:"card,exactlyoneof=card|iban"`
+		IBAN  string   `objconv:"iban"`
+	}
+
+	tests := []struct {
+		in  map[string]interface{}
+		err string
+	}{
+		{map[string]interface{}{"card": 1}, ""},
+		{map[string]interface{}{"email": "a@b.c", "iban": "FR76"}, ""},
+		{map[string]interface{}{"phone": "555", "card": 0}, ""},
+		{map[string]interface{}{"email": "a@b.c", "phone": "555", "card": 1},
+			"objconv: only one of the fields email, phone of objconv.T may be set but email and phone were"},
+		{map[string]interface{}{"card": 1, "iban": "FR76"},
+			"objconv: only one of the fields card, iban of objconv.T may be set but card and iban were"},
+		{map[string]interface{}{"email": "a@b.c"},
+			"objconv: one of the fields card, iban of objconv.T must be set"},
+		{map[string]interface{}{"email": "", "phone": "555", "card": nil, "iban": "FR76"}, ""},
+		{map[string]interface{}{"card": nil, "iban": ""},
+			"objconv: one of the fields card, iban of objconv.T must be set"},
+	}
+
+	for _, test := range tests {
+		var v T
+		err := NewDecoder(NewValueParser(test.in)).Decode(&v)
+
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("%v: %s", test.in, err)
+		case test.err != "" && (err == nil || err.Error() != test.err):
+			t.Errorf("%v: %v != %s", test.in, err, test.err)
+		}
+	}
+
+	t.Run("invalid-group", func(t *testing.T) {
+		var v struct {
+			A int
+			_ struct{} `objconv:",oneof=A|B"`
+		}
+
+		if err := NewDecoder(NewValueParser(map[string]interface{}{})).Decode(&v); err == nil {
+			t.Error("expected an error for a oneof group referencing a field which doesn't exist")
+		}
+	})
+
+	t.Run("zero-values", func(t *testing.T) {
+		var v struct {
+			_ struct{} `objconv:",oneof=a|b"`
+			A int      `objconv:"a"`
+			B int      `objconv:"b"`
+		}
+
+		for _, in := range []map[string]interface{}{
+			{"a": nil, "b": 1},
+			{"a": 0, "b": 1},
+		} {
+			if err := NewDecoder(NewValueParser(in)).Decode(&v); err != nil {
+				t.Errorf("%v: fields decoded to null or zero values must not be set: %s", in, err)
+			}
+		}
+	})
+}
+
+func TestDecoderStructOneOfGroup(t *testing.T) {
+	type Card struct{ Number string }
+	type Transfer struct{ IBAN string }
+
+	type T struct {
+		Card     *Card     `objconv:"card,oneof=payment"`
+		Transfer *Transfer `objconv:"transfer,oneof=payment"`
+		Email    *string   `objconv:"email,oneof=contact"`
+		Phone    *string   `objconv:"phone,oneof=contact"`
+	}
+
+	tests := []struct {
+		in  map[string]interface{}
+		err string
+	}{
+		{map[string]interface{}{"card": map[string]interface{}{"Number": "4242"}, "email": "a@b.c"}, ""},
+		{map[string]interface{}{"transfer": map[string]interface{}{"IBAN": "FR76"}, "phone": "555"}, ""},
+		{map[string]interface{}{"email": "a@b.c"},
+			"objconv: one of the fields card, transfer of objconv.T must be set"},
+		{map[string]interface{}{"card": nil, "transfer": nil, "email": "a@b.c"},
+			"objconv: one of the fields card, transfer of objconv.T must be set"},
+		{map[string]interface{}{"card": nil, "transfer": map[string]interface{}{}, "phone": ""}, ""},
+		{map[string]interface{}{"card": map[string]interface{}{}, "transfer": map[string]interface{}{}, "email": "a@b.c"},
+			"objconv: only one of the fields card, transfer of objconv.T may be set but card and transfer were"},
+		{map[string]interface{}{"card": map[string]interface{}{}, "email": "a@b.c", "phone": "555"},
+			"objconv: only one of the fields email, phone of objconv.T may be set but email and phone were"},
+	}
+
+	for _, test := range tests {
+		var v T
+		err := NewDecoder(NewValueParser(test.in)).Decode(&v)
+
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("%v: %s", test.in, err)
+		case test.err != "" && (err == nil || err.Error() != test.err):
+			t.Errorf("%v: %v != %s", test.in, err, test.err)
+		}
+	}
+
+	t.Run("prior-values", func(t *testing.T) {
+		email := "a@b.c"
+		v := T{Card: &Card{}, Email: &email}
+
+		if err := NewDecoder(NewValueParser(map[string]interface{}{"transfer": map[string]interface{}{}, "phone": "555"})).Decode(&v); err != nil {
+			t.Errorf("the values of the fields before decoding must be ignored: %s", err)
+		}
+	})
+
+	t.Run("jsonpath", func(t *testing.T) {
+		var v struct {
+			A string `objconv:"a,oneof=g"`
+			B string `objconv:"b,oneof=g,jsonpath=/x/b"`
+		}
+
+		if err := NewDecoder(NewValueParser(map[string]interface{}{"a": ""})).Decode(&v); err == nil {
+			t.Error("expected an error for a oneof group with a field located by a jsonpath")
+		}
+	})
+}
+
+func TestDecoderStructUniqueBy(t *testing.T) {
+	type Item struct {
+		Key   string
+		Value int
+	}
+
+	type T struct {
+		Items []Item  `objconv:"items,uniqueby=Key"`
+		Ptrs  []*Item `objconv:"ptrs,uniqueby=Key"`
+	}
+
+	in := map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{"Key": "a", "Value": 1},
+			map[string]interface{}{"Key": "b", "Value": 2},
+			map[string]interface{}{"Key": "a", "Value": 3},
+			map[string]interface{}{"Key": "c", "Value": 4},
+			map[string]interface{}{"Key": "b", "Value": 5},
+		},
+		"ptrs": []interface{}{
+			map[string]interface{}{"Key": "a", "Value": 1},
+			nil,
+			map[string]interface{}{"Key": "a", "Value": 2},
+		},
+	}
+
+	var v T
+
+	if err := NewDecoder(NewValueParser(in)).Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := T{
+		Items: []Item{{"a", 3}, {"b", 5}, {"c", 4}},
+		Ptrs:  []*Item{{"a", 2}, nil},
+	}
+
+	if !reflect.DeepEqual(v, expect) {
+		t.Errorf("%#v != %#v", v, expect)
+	}
+
+	t.Run("invalid-field", func(t *testing.T) {
+		var v struct {
+			Items []Item `objconv:"items,uniqueby=Name"`
+		}
+
+		if err := NewDecoder(NewValueParser(map[string]interface{}{})).Decode(&v); err == nil {
+			t.Error("expected an error for a uniqueby field which doesn't exist")
+		}
+	})
+
+	t.Run("nil-embedded-pointer", func(t *testing.T) {
+		type Base struct {
+			ID int
+		}
+
+		type Embed struct {
+			*Base
+			Name string
+		}
+
+		var v struct {
+			Items []Embed `objconv:"items,uniqueby=ID"`
+		}
+
+		in := map[string]interface{}{
+			"items": []interface{}{
+				map[string]interface{}{"Name": "a"},
+				map[string]interface{}{"Name": "b", "ID": 1},
+				map[string]interface{}{"Name": "c"},
+				map[string]interface{}{"Name": "d", "ID": 1},
+			},
+		}
+
+		if err := NewDecoder(NewValueParser(in)).Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+
+		expect := []Embed{{nil, "a"}, {&Base{1}, "d"}, {nil, "c"}}
+
+		if !reflect.DeepEqual(v.Items, expect) {
+			t.Errorf("%#v != %#v", v.Items, expect)
+		}
+	})
+}
+
+func TestDecoderStructUnknownFieldsRaw(t *testing.T) {
+	type T struct {
+		Name    string
+		Unknown map[string]RawValue `objconv:",unknownfields"`
+	}
+
+	var v T
+	d := Decoder{Parser: NewValueParser(map[string]interface{}{"Name": "Luke", "A": 1})}
+
+	if err := d.Decode(&v); err == nil {
+		t.Error("expected an error capturing raw values without a RawParser or RawEmitter")
+	}
+}
+
+func TestDecoderPromoteOverflowToFloat(t *testing.T) {
+	var v interface{}
+	d := Decoder{Parser: NewValueParser([]uint64{1, 1 << 63}), PromoteOverflowToFloat: true}
+
+	if err := d.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []interface{}{uint64(1), float64(1 << 63)}
+
+	if !reflect.DeepEqual(v, expect) {
+		t.Errorf("%#v != %#v", v, expect)
+	}
+}
+
+func TestDecoderDefaultFunc(t *testing.T) {
+	type Config struct {
+		Port int
+		Host string
+	}
+
+	type T struct {
+		Labels map[string]string
+		Tags   []string
+		Config *Config
+		Any    interface{}
+		Inner  Config
+	}
+
+	defaults := func(t reflect.Type) (reflect.Value, bool) {
+		switch t {
+		case reflect.TypeOf(map[string]string(nil)):
+			return reflect.ValueOf(map[string]string{}), true
+		case reflect.TypeOf([]string(nil)):
+			return reflect.ValueOf([]string{}), true
+		case reflect.TypeOf((*Config)(nil)):
+			return reflect.ValueOf(&Config{Port: 80}), true
+		case reflect.TypeOf(Config{}):
+			return reflect.ValueOf(Config{Port: 80, Host: "localhost"}), true
+		}
+		return reflect.Value{}, false
+	}
+
+	t.Run("nil", func(t *testing.T) {
+		v := T{Any: 42}
+		d := Decoder{
+			Parser: NewValueParser(map[string]interface{}{
+				"Labels": nil,
+				"Tags":   nil,
+				"Config": nil,
+				"Any":    nil,
+			}),
+			DefaultFunc: defaults,
+		}
+
+		if err := d.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+
+		expect := T{Labels: map[string]string{}, Tags: []string{}, Config: &Config{Port: 80}}
+
+		if !reflect.DeepEqual(v, expect) {
+			t.Errorf("%#v != %#v", v, expect)
+		}
+	})
+
+	t.Run("struct-error", func(t *testing.T) {
+		var v Config
+		d := Decoder{
+			Parser:      NewValueParser(map[string]interface{}{"Host": "example.com", "Port": "abc"}),
+			DefaultFunc: defaults,
+		}
+
+		if err := d.Decode(&v); err == nil {
+			t.Fatal("expected an error decoding an invalid port")
+		}
+
+		if expect := (Config{Port: 80, Host: "localhost"}); v != expect {
+			t.Errorf("%#v != %#v", v, expect)
+		}
+	})
+
+	t.Run("zero-value", func(t *testing.T) {
+		v := T{Tags: []string{"A"}, Inner: Config{Port: 1}}
+		d := Decoder{
+			Parser:      NewValueParser(map[string]interface{}{"Tags": nil, "Inner": nil}),
+			DefaultFunc: func(reflect.Type) (reflect.Value, bool) { return reflect.Value{}, false },
+		}
+
+		if err := d.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+
+		if v.Tags != nil || v.Inner.Port != 1 {
+			t.Errorf("%#v", v)
+		}
+	})
+}
+
+func TestDecoderOnLossyConversion(t *testing.T) {
+	type conversion struct {
+		from   Type
+		to     reflect.Type
+		detail string
+	}
+
+	var conversions []conversion
+	record := func(from Type, to reflect.Type, detail string) {
+		conversions = append(conversions, conversion{from, to, detail})
+	}
+
+	t.Run("big-int-to-float", func(t *testing.T) {
+		conversions = nil
+
+		var v interface{}
+		d := Decoder{
+			Parser:                 NewValueParser([]uint64{1, 1 << 63}),
+			PromoteOverflowToFloat: true,
+			OnLossyConversion:      record,
+		}
+
+		if err := d.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+
+		expect := []conversion{{Uint, float64Type, "integer 9223372036854775808 rounded to 9.223372036854776e+18"}}
+
+		if !reflect.DeepEqual(conversions, expect) {
+			t.Errorf("%#v != %#v", conversions, expect)
+		}
+	})
+
+	t.Run("float-to-int", func(t *testing.T) {
+		conversions = nil
+
+		var v struct {
+			A int
+			B uint8
+			C int64
+		}
+		d := Decoder{
+			Parser:            NewValueParser(map[string]interface{}{"A": 1.5, "B": 2.0, "C": -3.75}),
+			WeaklyTypedInput:  true,
+			OnLossyConversion: record,
+		}
+
+		if err := d.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+
+		if v.A != 1 || v.B != 2 || v.C != -3 {
+			t.Errorf("%#v", v)
+		}
+
+		sort.Slice(conversions, func(i, j int) bool { return conversions[i].detail < conversions[j].detail })
+
+		expect := []conversion{
+			{Float, int64Type, "truncated -3.75 to -3"},
+			{Float, intType, "truncated 1.5 to 1"},
+		}
+
+		if !reflect.DeepEqual(conversions, expect) {
+			t.Errorf("%#v != %#v", conversions, expect)
+		}
+	})
+
+	t.Run("float32", func(t *testing.T) {
+		conversions = nil
+
+		var v []float32
+		d := Decoder{
+			Parser:            NewValueParser([]interface{}{int64(1 << 24), int64(1<<24 + 1), uint64(1<<25 + 1), 0.5, 0.1}),
+			OnLossyConversion: record,
+		}
+
+		if err := d.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+
+		f32 := reflect.TypeOf(float32(0))
+		expect := []conversion{
+			{Int, f32, "1.6777217e+07 rounded to 1.6777216e+07"},
+			{Uint, f32, "3.3554433e+07 rounded to 3.3554432e+07"},
+			{Float, f32, "0.1 rounded to 0.10000000149011612"},
+		}
+
+		if !reflect.DeepEqual(conversions, expect) {
+			t.Errorf("%#v != %#v", conversions, expect)
+		}
+	})
+
+	t.Run("float-to-int-errors", func(t *testing.T) {
+		for _, test := range []struct {
+			in interface{}
+			to interface{}
+		}{
+			{1e20, new(int64)},
+			{-1.0, new(uint)},
+			{math.NaN(), new(int)},
+			{300.0, new(uint8)},
+		} {
+			d := Decoder{Parser: NewValueParser(test.in), WeaklyTypedInput: true}
+			if err := d.Decode(test.to); err == nil {
+				t.Errorf("expected an error decoding %g into %T", test.in, test.to)
+			}
+		}
+
+		var i int
+		if err := NewDecoder(NewValueParser(1.5)).Decode(&i); err == nil {
+			t.Error("expected an error decoding a float into an int when WeaklyTypedInput is not set")
+		}
+	})
+}
+
+func TestDecoderProgressFunc(t *testing.T) {
+	var calls []int
+	var v struct {
+		Items [][]int
+	}
+
+	d := Decoder{
+		Parser: NewValueParser(map[string]interface{}{
+			"Items": [][]int{{1, 2, 3}, {4}, {}, {5, 6}, {7}},
+		}),
+		ProgressFunc:     func(n int) { calls = append(calls, n) },
+		ProgressInterval: 2,
+	}
+
+	if err := d.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(v.Items) != 5 {
+		t.Errorf("%#v", v)
+	}
+
+	if expect := []int{2, 4, 5}; !reflect.DeepEqual(calls, expect) {
+		t.Errorf("%#v != %#v", calls, expect)
+	}
+}
+
+func TestDecoderControlCharPolicy(t *testing.T) {
+	const in = "a\x00b\tc\x1b[0m\u0085d\x7f\xffé"
+
+	tests := []struct {
+		policy ControlCharPolicy
+		out    string
+		ok     bool
+	}{
+		{ControlCharAllow, in, true},
+		{ControlCharReject, "", false},
+		{ControlCharStrip, "abc[0md\xffé", true},
+		{ControlCharEscape, `a\x00b\tc\x1b[0m\u0085d\x7f` + "\xffé", true},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprint(test.policy), func(t *testing.T) {
+			for _, out := range []interface{}{new(string), new(interface{}), new(map[string]string)} {
+				var src interface{} = in
+				if _, ok := out.(*map[string]string); ok {
+					src = map[string]string{"k": in}
+				}
+
+				d := Decoder{Parser: NewValueParser(src), ControlCharPolicy: test.policy}
+				err := d.Decode(out)
+
+				if !test.ok {
+					if err == nil {
+						t.Errorf("%T: expected an error", out)
+					}
+					continue
+				}
+
+				if err != nil {
+					t.Errorf("%T: %s", out, err)
+					continue
+				}
+
+				var s interface{}
+				switch v := out.(type) {
+				case *string:
+					s = *v
+				case *interface{}:
+					s = *v
+				case *map[string]string:
+					s = (*v)["k"]
+				}
+
+				if s != test.out {
+					t.Errorf("%T: %q != %q", out, s, test.out)
+				}
+			}
+		})
+	}
+
+	t.Run("clean", func(t *testing.T) {
+		var s string
+		d := Decoder{Parser: NewValueParser("hello, world!"), ControlCharPolicy: ControlCharReject}
+
+		if err := d.Decode(&s); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestDecoderBytesEncoding(t *testing.T) {
+	tests := []struct {
+		enc BytesEncoding
+		in  string
+		out string
+	}{
+		{BytesRaw, "aGVsbG8=", "aGVsbG8="},
+		{BytesBase64, "aGVsbG8=", "hello"},
+		{BytesBase64, "aGVsbG8", "hello"},
+		{BytesBase64, "+/+/", "\xfb\xff\xbf"},
+		{BytesBase64URL, "-_-_", "\xfb\xff\xbf"},
+		{BytesBase64URL, "aGk", "hi"},
+		{BytesHex, "68656c6c6f", "hello"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			var v []byte
+			d := Decoder{Parser: NewValueParser(test.in), BytesEncoding: test.enc}
+
+			if err := d.Decode(&v); err != nil {
+				t.Fatal(err)
+			}
+
+			if string(v) != test.out {
+				t.Errorf("%q != %q", v, test.out)
+			}
+		})
+	}
+
+	t.Run("bytes", func(t *testing.T) {
+		var v []byte
+		d := Decoder{Parser: NewValueParser([]byte("68")), BytesEncoding: BytesHex}
+
+		if err := d.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+
+		if string(v) != "68" {
+			t.Errorf("byte values must not be decoded: %q", v)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		var v []byte
+		d := Decoder{Parser: NewValueParser("not hexadecimal digits at all"), BytesEncoding: BytesHex}
+
+		err := d.Decode(&v)
+		if err == nil {
+			t.Fatal("expected an error decoding an invalid hex string")
+		}
+
+		if !strings.Contains(err.Error(), `"not hexadecimal ..."`) {
+			t.Error(err)
+		}
+	})
+}
+
+func TestDecoderDetectBase64(t *testing.T) {
+	tests := []struct {
+		in  string
+		out interface{}
+	}{
+		{"aGVsbG8gd29ybGQhIQ==", []byte("hello world!!")},
+		{"AAECAwQFBgcICQoLDA0ODw==", []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}},
+		{"aGVsbG8=", "aGVsbG8="},                         // too short
+		{"aGVsbG8gd29ybGQhIQ", "aGVsbG8gd29ybGQhIQ"},     // missing padding
+		{"aGVsbG8gd29ybGQhIR==", "aGVsbG8gd29ybGQhIR=="}, // non-zero padding bits
+		{"hello world, hi!", "hello world, hi!"},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			var v interface{}
+			d := Decoder{Parser: NewValueParser(test.in), DetectBase64: true}
+
+			if err := d.Decode(&v); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(v, test.out) {
+				t.Errorf("%#v != %#v", v, test.out)
+			}
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		var v interface{}
+
+		if err := NewDecoder(NewValueParser("aGVsbG8gd29ybGQhIQ==")).Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+
+		if v != "aGVsbG8gd29ybGQhIQ==" {
+			t.Errorf("%#v", v)
+		}
+	})
+
+	t.Run("typed", func(t *testing.T) {
+		var v struct{ S string }
+		d := Decoder{Parser: NewValueParser(map[string]interface{}{"S": "aGVsbG8gd29ybGQhIQ=="}), DetectBase64: true}
+
+		if err := d.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+
+		if v.S != "aGVsbG8gd29ybGQhIQ==" {
+			t.Errorf("%#v", v)
+		}
+	})
+}
+
+func TestDecoderConcurrentDecodeFuncs(t *testing.T) {
+	type Node struct {
+		Name  string
+		Tags  []string
+		Attrs map[string][]int
+		Next  *Node
+	}
+
+	in := map[string]interface{}{
+		"Name":  "a",
+		"Tags":  []interface{}{"x", "y"},
+		"Attrs": map[string]interface{}{"k": []interface{}{1, 2}},
+		"Next": map[string]interface{}{
+			"Name": "b",
+			"Next": map[string]interface{}{"Name": "c"},
+		},
+	}
+
+	expect := Node{
+		Name:  "a",
+		Tags:  []string{"x", "y"},
+		Attrs: map[string][]int{"k": {1, 2}},
+		Next:  &Node{Name: "b", Next: &Node{Name: "c"}},
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+
+	for i := 0; i != cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j != 100; j++ {
+				var v []Node
+				if err := NewDecoder(NewValueParser([]interface{}{in})).Decode(&v); err != nil {
+					errs <- err
+					return
+				}
+				if !reflect.DeepEqual(v, []Node{expect}) {
+					errs <- fmt.Errorf("%#v != %#v", v, expect)
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestDecoderDecodeReflect(t *testing.T) {
+	var v struct {
+		Name string
+		Tags []string
+	}
+
+	s := reflect.ValueOf(&v).Elem()
+	in := map[string]interface{}{"Name": "Luke", "Tags": []interface{}{"a"}}
+
+	if err := NewDecoder(NewValueParser(in)).DecodeReflect(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Name != "Luke" || !reflect.DeepEqual(v.Tags, []string{"a"}) {
+		t.Errorf("%#v", v)
+	}
+
+	if err := NewDecoder(NewValueParser("Leia")).DecodeReflect(s.Field(0)); err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Name != "Leia" {
+		t.Errorf("%#v", v)
+	}
+
+	for _, test := range []reflect.Value{reflect.ValueOf(42), reflect.ValueOf(&v), {}} {
+		t.Run(test.Kind().String(), func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected a panic decoding into a value that cannot be set")
+				}
+			}()
+			NewDecoder(NewValueParser(1)).DecodeReflect(test)
+		})
+	}
+}
+
+func TestDecoderReset(t *testing.T) {
+	d := Decoder{Parser: NewValueParser("1"), LeadingZeroPolicy: LeadingZeroError}
+	d.off = 2
+	d.depth = 3
+	d.discriminator = "A"
+
+	d.Reset(NewValueParser("42"))
+
+	if d.off != 0 || d.depth != 0 || d.warnings != nil || len(d.discriminator) != 0 {
+		t.Errorf("the internal state of the decoder was not cleared: %#v", d)
+	}
+
+	var x int
+
+	if err := d.Decode(&x); err != nil {
+		t.Fatal(err)
+	}
+
+	if x != 42 {
+		t.Error(x)
+	}
+
+	d.Reset(NewValueParser("007"))
+
+	if err := d.Decode(&x); err == nil {
+		t.Error("expected the options of the decoder to be preserved by Reset")
+	}
+}
+
+func TestDecoderNilPointersAsZeroValue(t *testing.T) {
+	type Point struct {
+		X int
+	}
+
+	in := map[string]interface{}{"a": nil}
+
+	t.Run("*int", func(t *testing.T) {
+		var m map[string]*int
+		d := Decoder{Parser: NewValueParser(in), NilPointersAsZeroValue: true}
+
+		if err := d.Decode(&m); err != nil {
+			t.Fatal(err)
+		}
+		if p := m["a"]; p == nil || *p != 0 {
+			t.Errorf("%#v", m)
+		}
+	})
+
+	t.Run("*string", func(t *testing.T) {
+		var m map[string]*string
+		d := Decoder{Parser: NewValueParser(in), NilPointersAsZeroValue: true}
+
+		if err := d.Decode(&m); err != nil {
+			t.Fatal(err)
+		}
+		if p := m["a"]; p == nil || *p != "" {
+			t.Errorf("%#v", m)
+		}
+	})
+
+	t.Run("*struct", func(t *testing.T) {
+		var m map[string]*Point
+		d := Decoder{Parser: NewValueParser(in), NilPointersAsZeroValue: true}
+
+		if err := d.Decode(&m); err != nil {
+			t.Fatal(err)
+		}
+		if p := m["a"]; p == nil || *p != (Point{}) {
+			t.Errorf("%#v", m)
+		}
+	})
+
+	t.Run("interface{}", func(t *testing.T) {
+		var m map[string]interface{}
+		d := Decoder{Parser: NewValueParser(in), NilPointersAsZeroValue: true}
+
+		if err := d.Decode(&m); err != nil {
+			t.Fatal(err)
+		}
+		if v, ok := m["a"]; !ok || v != nil {
+			t.Errorf("%#v", m)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		var m map[string]*int
+
+		if err := NewDecoder(NewValueParser(in)).Decode(&m); err != nil {
+			t.Fatal(err)
+		}
+		if p, ok := m["a"]; !ok || p != nil {
+			t.Errorf("%#v", m)
+		}
+	})
+}
+
+// flexibleID decodes identifiers that may be represented by integers or strings.
+type flexibleID string
+
+func (id *flexibleID) DecodeTypedValue(d Decoder, t Type) error {
+	switch t {
+	case Int:
+		i, err := d.Parser.ParseInt()
+		*id = flexibleID(strconv.FormatInt(i, 10))
+		return err
+	case String:
+		s, err := d.Parser.ParseString()
+		*id = flexibleID(s)
+		return err
+	default:
+		return fmt.Errorf("cannot decode an identifier from %s", t)
+	}
+}
+
+func (id *flexibleID) DecodeValue(d Decoder) error {
+	return errors.New("DecodeValue must not be called when DecodeTypedValue is implemented")
+}
+
+func TestDecoderTypedValueDecoder(t *testing.T) {
+	tests := []struct {
+		in  interface{}
+		out flexibleID
+	}{
+		{42, "42"},
+		{"A42", "A42"},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprint(test.in), func(t *testing.T) {
+			var v struct {
+				ID  flexibleID
+				IDs map[string]flexibleID
+			}
+			in := map[string]interface{}{"ID": test.in, "IDs": map[string]interface{}{"a": test.in}}
+
+			if err := NewDecoder(NewValueParser(in)).Decode(&v); err != nil {
+				t.Fatal(err)
+			}
+			if v.ID != test.out || v.IDs["a"] != test.out {
+				t.Errorf("%#v", v)
+			}
+
+			var id flexibleID
+
+			if err := NewDecoder(NewValueParser(test.in)).Decode(&id); err != nil {
+				t.Fatal(err)
+			}
+			if id != test.out {
+				t.Errorf("%q != %q", id, test.out)
+			}
+		})
+	}
+
+	var id flexibleID
+
+	if err := NewDecoder(NewValueParser(true)).Decode(&id); err == nil {
+		t.Error("expected the error of the typed value decoder to be returned")
+	}
+}
+
+func TestDecoderMergeMaps(t *testing.T) {
+	in := map[string]interface{}{"b": "2", "c": "3"}
+
+	tests := []struct {
+		name   string
+		init   interface{}
+		expect interface{}
+	}{
+		{
+			name:   "map[string]string",
+			init:   map[string]string{"a": "1", "b": "0"},
+			expect: map[string]string{"a": "1", "b": "2", "c": "3"},
+		},
+		{
+			name:   "map[string]interface{}",
+			init:   map[string]interface{}{"a": "1", "b": "0"},
+			expect: map[string]interface{}{"a": "1", "b": "2", "c": "3"},
+		},
+		{
+			name:   "map[interface{}]interface{}",
+			init:   map[interface{}]interface{}{"a": "1", "b": "0"},
+			expect: map[interface{}]interface{}{"a": "1", "b": "2", "c": "3"},
+		},
+		{
+			name:   "map[string]int",
+			init:   map[string]int{"a": 1, "b": 0},
+			expect: map[string]int{"a": 1, "b": 2, "c": 3},
+		},
+		{
+			name:   "map[string][]byte",
+			init:   map[string][]byte{"a": []byte("1"), "b": []byte("0")},
+			expect: map[string][]byte{"a": []byte("1"), "b": []byte("2"), "c": []byte("3")},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := reflect.New(reflect.TypeOf(test.init))
+			v.Elem().Set(reflect.ValueOf(test.init))
+
+			d := Decoder{Parser: NewValueParser(in), MergeMaps: true}
+
+			if err := d.Decode(v.Interface()); err != nil {
+				t.Fatal(err)
+			}
+
+			if res := v.Elem().Interface(); !reflect.DeepEqual(res, test.expect) {
+				t.Errorf("%#v != %#v", res, test.expect)
+			}
+		})
+	}
+
+	t.Run("DirectParser", func(t *testing.T) {
+		type T struct {
+			M map[string]interface{}
+		}
+
+		in := map[string]interface{}{"M": map[string]interface{}{"b": 2}}
+		v := T{M: map[string]interface{}{"a": 1}}
+
+		d := Decoder{Parser: &directParser{ValueParser: NewValueParser(in)}, MergeMaps: true}
+
+		if err := d.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+
+		if expect := map[string]interface{}{"a": 1, "b": int64(2)}; !reflect.DeepEqual(v.M, expect) {
+			t.Errorf("%#v != %#v", v.M, expect)
+		}
+
+		v.M["c"] = 3
+
+		if _, ok := in["M"].(map[string]interface{})["c"]; ok {
+			t.Error("the decoded map shares its memory with the input")
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		v := map[string]string{"a": "1"}
+
+		if err := NewDecoder(NewValueParser(in)).Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+
+		if expect := map[string]string{"b": "2", "c": "3"}; !reflect.DeepEqual(v, expect) {
+			t.Errorf("%#v != %#v", v, expect)
+		}
+	})
+}
+
+func TestDecoderMapStringInt(t *testing.T) {
+	in := map[string]interface{}{
+		"int":    int64(-1),
+		"uint":   uint64(2),
+		"string": "3",
+		"nil":    nil,
+	}
+
+	m := map[string]int{"old": 42}
+
+	if err := NewDecoder(NewValueParser(in)).Decode(&m); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(m, map[string]int{"int": -1, "uint": 2, "string": 3, "nil": 0}) {
+		t.Errorf("%#v", m)
+	}
+
+	for _, v := range []interface{}{uint64(1 << 63), 1.5, "A"} {
+		if err := NewDecoder(NewValueParser(map[string]interface{}{"k": v})).Decode(&m); err == nil {
+			t.Errorf("expected an error decoding %#v into an int", v)
+		}
+	}
+}
+
+type upperScanner string
+
+func (s *upperScanner) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*s = ""
+	case string:
+		*s = upperScanner(strings.ToUpper(v))
+	default:
+		return fmt.Errorf("cannot scan %T", src)
+	}
+	return nil
+}
+
+type tagsScanner []string
+
+func (s *tagsScanner) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*s = nil
+	case string:
+		*s = strings.Split(v, ",")
+	default:
+		return fmt.Errorf("cannot scan %T", src)
+	}
+	return nil
+}
+
+func TestDecoderScanner(t *testing.T) {
+	type T struct {
+		Name  sql.NullString
+		Age   sql.NullInt64
+		Score sql.NullFloat64
+		OK    sql.NullBool
+		Upper upperScanner
+		Ptr   *sql.NullString
+	}
+
+	tests := []struct {
+		in  map[string]interface{}
+		out T
+	}{
+		{
+			in:  map[string]interface{}{"Name": nil, "Age": nil, "Score": nil, "OK": nil, "Upper": nil, "Ptr": nil},
+			out: T{},
+		},
+		{
+			in: map[string]interface{}{"Name": "luke", "Age": int64(42), "Score": 0.5, "OK": true, "Upper": "abc", "Ptr": "x"},
+			out: T{
+				Name:  sql.NullString{String: "luke", Valid: true},
+				Age:   sql.NullInt64{Int64: 42, Valid: true},
+				Score: sql.NullFloat64{Float64: 0.5, Valid: true},
+				OK:    sql.NullBool{Bool: true, Valid: true},
+				Upper: "ABC",
+				Ptr:   &sql.NullString{String: "x", Valid: true},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		var v T
+
+		if err := NewDecoder(NewValueParser(test.in)).Decode(&v); err != nil {
+			t.Error(err)
+			continue
+		}
+
+		if !reflect.DeepEqual(v, test.out) {
+			t.Errorf("%#v != %#v", v, test.out)
+		}
+	}
+
+	var v upperScanner
+	if err := NewDecoder(NewValueParser(42)).Decode(&v); err == nil {
+		t.Error("expected the error of the Scan method to be returned")
+	}
+
+	t.Run("slice", func(t *testing.T) {
+		var v struct {
+			Tags  tagsScanner
+			Other tagsScanner
+		}
+
+		in := map[string]interface{}{"Tags": []interface{}{"a", "b"}, "Other": "c,d"}
+
+		if err := NewDecoder(NewValueParser(in)).Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(v.Tags, tagsScanner{"a", "b"}) || !reflect.DeepEqual(v.Other, tagsScanner{"c", "d"}) {
+			t.Errorf("bad value: %#v", v)
+		}
+	})
+}
+
+func TestInstallKindDecoder(t *testing.T) {
+	var c chan string
+
+	if err := NewDecoder(NewValueParser("hello")).Decode(&c); err == nil {
+		t.Fatal("expected an error decoding into a channel without a kind decoder")
+	}
+
+	// Decodes the next value into the element type of the channel, then sends
+	// it to a buffered channel allocated for the destination if it was nil.
+	InstallKindDecoder(reflect.Chan, func(d Decoder, to reflect.Value) (Type, error) {
+		v := reflect.New(to.Type().Elem())
+
+		t, err := d.Parser.ParseType()
+		if err != nil {
+			return t, err
+		}
+
+		if err = d.Decode(v.Interface()); err != nil {
+			return t, err
+		}
+
+		if to.IsNil() {
+			to.Set(reflect.MakeChan(to.Type(), 1))
+		}
+
+		to.Send(v.Elem())
+		return t, nil
+	})
+	defer InstallKindDecoder(reflect.Chan, nil)
+
+	if err := NewDecoder(NewValueParser("hello")).Decode(&c); err != nil {
+		t.Fatal(err)
+	}
+
+	if s := <-c; s != "hello" {
+		t.Errorf("%q", s)
+	}
+
+	var v struct{ C chan int }
+
+	if err := NewDecoder(NewValueParser(map[string]interface{}{"C": 42})).Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := <-v.C; n != 42 {
+		t.Errorf("%d", n)
+	}
+}
+
+func TestDecoderLeadingZeroPolicy(t *testing.T) {
+	tests := []struct {
+		policy LeadingZeroPolicy
+		in     string
+		out    interface{}
+		ok     bool
+	}{
+		{LeadingZeroDecimal, "007", int64(7), true},
+		{LeadingZeroDecimal, "010", uint(10), true},
+		{LeadingZeroDecimal, "-010", int32(-10), true},
+		{LeadingZeroOctal, "010", int64(8), true},
+		{LeadingZeroOctal, "-010", int(-8), true},
+		{LeadingZeroOctal, "017", uint64(15), true},
+		{LeadingZeroOctal, "10", int64(10), true},
+		{LeadingZeroOctal, "0", int64(0), true},
+		{LeadingZeroOctal, "09", int64(0), false},
+		{LeadingZeroError, "007", int64(0), false},
+		{LeadingZeroError, "-00", int64(0), false},
+		{LeadingZeroError, "00", uint(0), false},
+		{LeadingZeroError, "0", int64(0), true},
+		{LeadingZeroError, "-7", int64(-7), true},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%d:%s:%T", test.policy, test.in, test.out), func(t *testing.T) {
+			v := reflect.New(reflect.TypeOf(test.out))
+			d := Decoder{Parser: NewValueParser(test.in), LeadingZeroPolicy: test.policy}
+			err := d.Decode(v.Interface())
+
+			switch {
+			case !test.ok:
+				if err == nil {
+					t.Error("expected an error but got", v.Elem().Interface())
+				}
+			case err != nil:
+				t.Error(err)
+			case v.Elem().Interface() != test.out:
+				t.Errorf("%#v != %#v", v.Elem().Interface(), test.out)
+			}
+		})
+	}
+}
+
+func TestDecoderIntegerOverflow(t *testing.T) {
+	tests := []struct {
+		in  interface{}
+		out interface{}
+		err string
+	}{
+		{int64(127), new(int8), ""},
+		{int64(128), new(int8), "objconv: value 128 overflows int8 (range -128..127)"},
+		{int64(-129), new(int8), "objconv: value -129 overflows int8 (range -128..127)"},
+		{int64(32768), new(int16), "objconv: value 32768 overflows int16 (range -32768..32767)"},
+		{int64(-32769), new(int16), "objconv: value -32769 overflows int16 (range -32768..32767)"},
+		{int64(2147483648), new(int32), "objconv: value 2147483648 overflows int32 (range -2147483648..2147483647)"},
+		{int64(-2147483649), new(int32), "objconv: value -2147483649 overflows int32 (range -2147483648..2147483647)"},
+
+		// Uint -> signed
+		{uint64(127), new(int8), ""},
+		{uint64(128), new(int8), "objconv: value 128 overflows int8 (range -128..127)"},
+		{uint64(32768), new(int16), "objconv: value 32768 overflows int16 (range -32768..32767)"},
+		{uint64(2147483648), new(int32), "objconv: value 2147483648 overflows int32 (range -2147483648..2147483647)"},
+		{uint64(9223372036854775808), new(int64), "objconv: value 9223372036854775808 overflows int64 (range -9223372036854775808..9223372036854775807)"},
+
+		{uint64(255), new(uint8), ""},
+		{uint64(256), new(uint8), "objconv: value 256 overflows uint8 (range 0..255)"},
+		{uint64(65536), new(uint16), "objconv: value 65536 overflows uint16 (range 0..65535)"},
+		{uint64(4294967296), new(uint32), "objconv: value 4294967296 overflows uint32 (range 0..4294967295)"},
+
+		// Int -> unsigned
+		{int64(255), new(uint8), ""},
+		{int64(256), new(uint8), "objconv: value 256 overflows uint8 (range 0..255)"},
+		{int64(-1), new(uint8), "objconv: value -1 overflows uint8 (range 0..255)"},
+		{int64(-1), new(uint16), "objconv: value -1 overflows uint16 (range 0..65535)"},
+		{int64(4294967296), new(uint32), "objconv: value 4294967296 overflows uint32 (range 0..4294967295)"},
+		{int64(-1), new(uint64), "objconv: value -1 overflows uint64 (range 0..18446744073709551615)"},
+
+		// String -> integer
+		{"127", new(int8), ""},
+		{"128", new(int8), "objconv: value 128 overflows int8 (range -128..127)"},
+		{"-32769", new(int16), "objconv: value -32769 overflows int16 (range -32768..32767)"},
+		{"255", new(uint8), ""},
+		{"256", new(uint8), "objconv: value 256 overflows uint8 (range 0..255)"},
+		{"4294967296", new(uint32), "objconv: value 4294967296 overflows uint32 (range 0..4294967295)"},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%T(%v)->%T", test.in, test.in, test.out), func(t *testing.T) {
+			err := NewDecoder(NewValueParser(test.in)).Decode(test.out)
+
+			switch {
+			case test.err == "" && err != nil:
+				t.Error(err)
+			case test.err != "" && (err == nil || err.Error() != test.err):
+				t.Errorf("%v != %s", err, test.err)
+			}
+		})
+	}
+}
+
+func TestDecoderDurationFromNumbers(t *testing.T) {
+	tests := []struct {
+		unit time.Duration
+		in   interface{}
+		out  time.Duration
+	}{
+		{0, int64(2), 2 * time.Second},
+		{0, uint64(3), 3 * time.Second},
+		{0, 1.5, 1500 * time.Millisecond},
+		{0, -0.25, -250 * time.Millisecond},
+		{time.Millisecond, int64(1500), 1500 * time.Millisecond},
+		{time.Millisecond, 0.5, 500 * time.Microsecond},
+		{time.Nanosecond, int64(1234567890), 1234567890},
+		{time.Nanosecond, uint64(42), 42},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%v:%v", test.unit, test.in), func(t *testing.T) {
+			var v time.Duration
+			d := Decoder{Parser: NewValueParser(test.in), DurationUnit: test.unit}
+
+			if err := d.Decode(&v); err != nil {
+				t.Fatal(err)
+			}
+
+			if v != test.out {
+				t.Errorf("%s != %s", v, test.out)
+			}
+		})
+	}
+
+	for _, in := range []interface{}{int64(1) << 40, uint64(1) << 63, 1e20, math.NaN()} {
+		var v time.Duration
+		if err := NewDecoder(NewValueParser(in)).Decode(&v); err == nil {
+			t.Errorf("expected an error decoding %v seconds into a time.Duration but got %s", in, v)
+		}
+	}
+}
+
+func TestDecoderTimeTypesFromInvalidType(t *testing.T) {
+	tests := []struct {
+		v   interface{}
+		err string
+	}{
+		{&struct{ T time.Time }{}, "T: objconv: cannot convert from map to time"},
+		{&struct{ T time.Duration }{}, "T: objconv: cannot convert from map to duration"},
+		{&struct{ T error }{}, "T: objconv: cannot convert from map to error"},
+	}
+
+	for _, test := range tests {
+		t.Run(reflect.TypeOf(test.v).Elem().Field(0).Type.String(), func(t *testing.T) {
+			in := map[string]interface{}{"T": map[string]interface{}{"A": 1}}
+
+			if err := NewDecoder(NewValueParser(in)).Decode(test.v); err == nil || err.Error() != test.err {
+				t.Errorf("bad error: %v", err)
+			}
+		})
+	}
+}
+
+func TestDecoderAssumeLocalTime(t *testing.T) {
+	loc := time.FixedZone("UTC-5", -5*3600)
+	layouts := []string{"2006-01-02 15:04:05", time.RFC3339}
+
+	tests := []struct {
+		in  string
+		loc *time.Location
+		out time.Time
+	}{
+		{"2017-01-02 03:04:05", loc, time.Date(2017, 1, 2, 3, 4, 5, 0, loc)},
+		{"2017-01-02 03:04:05", nil, time.Date(2017, 1, 2, 3, 4, 5, 0, time.Local)},
+		{"2017-01-02T03:04:05Z", loc, time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"2017-01-02T03:04:05+02:00", loc, time.Date(2017, 1, 2, 1, 4, 5, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			var v time.Time
+			d := Decoder{
+				Parser:          NewValueParser(test.in),
+				TimeLayouts:     layouts,
+				AssumeLocalTime: true,
+				TimeLocation:    test.loc,
+			}
+
+			if err := d.Decode(&v); err != nil {
+				t.Fatal(err)
+			}
+
+			if !v.Equal(test.out) {
+				t.Errorf("%v != %v", v, test.out)
+			}
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		var v time.Time
+		d := Decoder{Parser: NewValueParser("2017-01-02 03:04:05"), TimeLayouts: layouts, TimeLocation: loc}
+
+		if err := d.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+
+		if expect := time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC); !v.Equal(expect) {
+			t.Errorf("%v != %v", v, expect)
+		}
+	})
+}
+
+func TestDecoderTimeLayouts(t *testing.T) {
+	layouts := []string{"2006-01-02 15:04:05", "unixmilli", time.RFC3339}
+
+	tests := []struct {
+		in  string
+		out time.Time
+	}{
+		{"2017-01-02 03:04:05", time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"1483326245123", time.Date(2017, 1, 2, 3, 4, 5, 123e6, time.UTC)},
+		{"2017-01-02T03:04:05Z", time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			var v time.Time
+			d := Decoder{Parser: NewValueParser(test.in), TimeLayouts: layouts}
+
+			if err := d.Decode(&v); err != nil {
+				t.Fatal(err)
+			}
+
+			if !v.Equal(test.out) {
+				t.Errorf("%v != %v", v, test.out)
+			}
+		})
+	}
+
+	t.Run("unix", func(t *testing.T) {
+		var v time.Time
+		d := Decoder{Parser: NewValueParser("1483326245"), TimeLayouts: []string{"unix"}}
+
+		if err := d.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+
+		if expect := time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC); !v.Equal(expect) {
+			t.Errorf("%v != %v", v, expect)
+		}
+	})
+
+	t.Run("no-match", func(t *testing.T) {
+		var v time.Time
+		d := Decoder{Parser: NewValueParser("yesterday"), TimeLayouts: layouts}
+
+		err := d.Decode(&v)
+		if err == nil {
+			t.Fatal("expected an error when no layouts match")
+		}
+
+		const expect = `objconv: "yesterday" does not match any of the time layouts ["2006-01-02 15:04:05" "unixmilli" "2006-01-02T15:04:05Z07:00"]`
+		if err.Error() != expect {
+			t.Errorf("%s != %s", err, expect)
+		}
+	})
+}
+
+func TestDecoderWeaklyTypedInput(t *testing.T) {
+	type T struct {
+		Enabled bool
+		Count   int
+		Ratio   float64
+		Name    string
+	}
+
+	in := map[string]interface{}{
+		"Enabled": "true",
+		"Count":   "42",
+		"Ratio":   "0.5",
+		"Name":    1234,
+	}
+
+	var v T
+	d := Decoder{Parser: NewValueParser(in), WeaklyTypedInput: true}
+
+	if err := d.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	if expect := (T{Enabled: true, Count: 42, Ratio: 0.5, Name: "1234"}); v != expect {
+		t.Errorf("%#v != %#v", v, expect)
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		var v T
+		d := Decoder{Parser: NewValueParser(map[string]interface{}{"Enabled": "yes"}), WeaklyTypedInput: true}
+
+		err := d.Decode(&v)
+		if err == nil {
+			t.Fatal("expected an error decoding an invalid boolean string")
+		}
+
+		var e *DecodeError
+		var n *strconv.NumError
+
+		if !errors.As(err, &e) || e.Path != "Enabled" || !errors.As(err, &n) {
+			t.Errorf("%#v", err)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		var b bool
+
+		if err := NewDecoder(NewValueParser("true")).Decode(&b); err == nil {
+			t.Error("expected an error decoding a string into a bool when WeaklyTypedInput is not set")
+		}
+	})
+
+	t.Run("bools-and-integers", func(t *testing.T) {
+		type T struct {
+			I  int
+			I8 int8
+			U  uint
+			U8 uint8
+			B1 bool
+			B2 bool
+			B3 bool
+			B4 bool
+		}
+
+		in := map[string]interface{}{
+			"I":  true,
+			"I8": false,
+			"U":  true,
+			"U8": false,
+			"B1": 0,
+			"B2": -1,
+			"B3": uint(0),
+			"B4": uint(42),
+		}
+
+		var v T
+		d := Decoder{Parser: NewValueParser(in), WeaklyTypedInput: true}
+
+		if err := d.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+
+		if expect := (T{I: 1, U: 1, B2: true, B4: true}); v != expect {
+			t.Errorf("%#v != %#v", v, expect)
+		}
+
+		for _, test := range []struct {
+			in interface{}
+			to interface{}
+		}{
+			{true, new(int)},
+			{true, new(uint)},
+			{1, new(bool)},
+			{uint(1), new(bool)},
+		} {
+			if err := NewDecoder(NewValueParser(test.in)).Decode(test.to); err == nil {
+				t.Errorf("expected an error decoding %#v into %T when WeaklyTypedInput is not set", test.in, test.to)
+			}
+		}
+	})
+}
+
+func TestDecoderBoolStrings(t *testing.T) {
+	tests := []struct {
+		table map[string]bool
+		in    interface{}
+		out   bool
+	}{
+		{DefaultBoolStrings(), "yes", true},
+		{DefaultBoolStrings(), "No", false},
+		{DefaultBoolStrings(), "ON", true},
+		{DefaultBoolStrings(), "off", false},
+		{DefaultBoolStrings(), "1", true},
+		{DefaultBoolStrings(), []byte("0"), false},
+		{DefaultBoolStrings(), true, true},
+		{map[string]bool{"enabled": true, "disabled": false}, "Enabled", true},
+		{map[string]bool{"enabled": true, "disabled": false}, "DISABLED", false},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprint(test.in), func(t *testing.T) {
+			var v bool
+			d := Decoder{Parser: NewValueParser(test.in), BoolStrings: test.table}
+
+			if err := d.Decode(&v); err != nil {
+				t.Fatal(err)
+			}
+
+			if v != test.out {
+				t.Errorf("%t != %t", v, test.out)
+			}
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		var v bool
+		d := Decoder{Parser: NewValueParser("true"), BoolStrings: map[string]bool{"on": true, "off": false}}
+
+		err := d.Decode(&v)
+		if err == nil || err.Error() != `objconv: "true" cannot be decoded into a boolean, the accepted values are "off", "on"` {
+			t.Errorf("bad error: %v", err)
+		}
+	})
+}
+
+func TestDecoderScalarToSlice(t *testing.T) {
+	type Point struct {
+		X int
+	}
+
+	type T struct {
+		Tags   []string
+		IDs    []int
+		Points []Point
+		Nested [][]string
+		Empty  []int
+		Data   []byte
+	}
+
+	in := map[string]interface{}{
+		"Tags":   "a",
+		"IDs":    []interface{}{1, 2},
+		"Points": map[string]interface{}{"X": 1},
+		"Nested": "b",
+		"Empty":  nil,
+		"Data":   "c",
+	}
+
+	var v T
+	d := Decoder{Parser: NewValueParser(in), ScalarToSlice: true}
+
+	if err := d.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := T{
+		Tags:   []string{"a"},
+		IDs:    []int{1, 2},
+		Points: []Point{{X: 1}},
+		Nested: [][]string{{"b"}},
+		Data:   []byte("c"),
+	}
+
+	if !reflect.DeepEqual(v, expect) {
+		t.Errorf("%#v != %#v", v, expect)
+	}
+
+	if err := NewDecoder(NewValueParser(in)).Decode(&v); err == nil {
+		t.Error("expected an error decoding a scalar into a slice when ScalarToSlice is not set")
+	}
+}
+
+func TestDecoderArrayToStruct(t *testing.T) {
+	type User struct {
+		ID      int
+		Ignored string `objconv:"-"`
+		Name    string
+		Admin   bool
+	}
+
+	type Row struct {
+		_     struct{} `objconv:",positional"`
+		ID    int
+		Email string `objconv:",required"`
+	}
+
+	row := []interface{}{1, "alice", true}
+
+	t.Run("option", func(t *testing.T) {
+		var u User
+		d := Decoder{Parser: NewValueParser(row), ArrayToStruct: true}
+
+		if err := d.Decode(&u); err != nil {
+			t.Fatal(err)
+		}
+
+		if u != (User{ID: 1, Name: "alice", Admin: true}) {
+			t.Errorf("bad value: %#v", u)
+		}
+
+		if err := NewDecoder(NewValueParser(row)).Decode(&u); err == nil {
+			t.Error("expected an error decoding an array into a struct when ArrayToStruct is not set")
+		}
+	})
+
+	t.Run("fewer-elements", func(t *testing.T) {
+		u := User{Admin: true}
+		d := Decoder{Parser: NewValueParser([]interface{}{2, "bob"}), ArrayToStruct: true}
+
+		if err := d.Decode(&u); err != nil {
+			t.Fatal(err)
+		}
+
+		if u != (User{ID: 2, Name: "bob", Admin: true}) {
+			t.Errorf("bad value: %#v", u)
+		}
+	})
+
+	t.Run("more-elements", func(t *testing.T) {
+		var u User
+		in := []interface{}{1, "alice", true, "extra"}
+
+		d := Decoder{Parser: NewValueParser(in), ArrayToStruct: true}
+		if err := d.Decode(&u); err == nil || err.Error() != "objconv: cannot decode an array of more than 3 elements into objconv.User" {
+			t.Errorf("bad error: %v", err)
+		}
+
+		d = Decoder{Parser: NewValueParser(in), ArrayToStruct: true, ArrayToStructTruncate: true}
+		if err := d.Decode(&u); err != nil || u != (User{ID: 1, Name: "alice", Admin: true}) {
+			t.Errorf("bad value: %#v (%v)", u, err)
+		}
+	})
+
+	t.Run("invalid-element", func(t *testing.T) {
+		var u User
+		d := Decoder{Parser: NewValueParser([]interface{}{1, "alice", "maybe"}), ArrayToStruct: true}
+
+		if err := d.Decode(&u); err == nil || err.Error() != "[2]: objconv: cannot convert from string to bool" {
+			t.Errorf("bad error: %v", err)
+		}
+	})
+
+	t.Run("tag", func(t *testing.T) {
+		var rows []Row
+
+		if err := NewDecoder(NewValueParser([]interface{}{[]interface{}{1, "a@example.com"}})).Decode(&rows); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(rows) != 1 || rows[0].ID != 1 || rows[0].Email != "a@example.com" {
+			t.Errorf("bad value: %#v", rows)
+		}
+
+		var r Row
+
+		if err := NewDecoder(NewValueParser([]interface{}{2})).Decode(&r); err == nil {
+			t.Error("expected an error decoding an array missing a required field")
+		}
+
+		if err := NewDecoder(NewValueParser(map[string]interface{}{"ID": 3, "Email": "c"})).Decode(&r); err != nil || r.ID != 3 {
+			t.Errorf("positional structs must still be decoded from maps: %#v (%v)", r, err)
+		}
+	})
+}
+
+func TestDecoderStructDefault(t *testing.T) {
+	type TLS struct {
+		Enabled bool          `objconv:"enabled,default=true"`
+		Timeout time.Duration `objconv:"timeout,default=5s"`
+	}
+
+	type Config struct {
+		Host  string  `objconv:"host,default=localhost"`
+		Port  int     `objconv:"port,default=8080"`
+		Ratio float64 `objconv:"ratio,default=0.5"`
+		TLS   TLS     `objconv:"tls"`
+	}
+
+	t.Run("present", func(t *testing.T) {
+		var c Config
+		in := map[string]interface{}{"host": "example.com", "port": 443, "tls": map[string]interface{}{"enabled": false}}
+
+		if err := NewDecoder(NewValueParser(in)).Decode(&c); err != nil {
+			t.Fatal(err)
+		}
+
+		expect := Config{Host: "example.com", Port: 443, Ratio: 0.5, TLS: TLS{Enabled: false, Timeout: 5 * time.Second}}
+		if c != expect {
+			t.Errorf("%#v != %#v", c, expect)
+		}
+	})
+
+	t.Run("absent", func(t *testing.T) {
+		var c Config
+
+		if err := NewDecoder(NewValueParser(map[string]interface{}{})).Decode(&c); err != nil {
+			t.Fatal(err)
+		}
+
+		expect := Config{Host: "localhost", Port: 8080, Ratio: 0.5, TLS: TLS{Enabled: true, Timeout: 5 * time.Second}}
+		if c != expect {
+			t.Errorf("%#v != %#v", c, expect)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		var v struct {
+			Port int `objconv:"port,default=http"`
+		}
+
+		err := NewDecoder(NewValueParser(map[string]interface{}{})).Decode(&v)
+		if err == nil || !strings.HasPrefix(err.Error(), `port: objconv: invalid default value "http": `) {
+			t.Errorf("bad error: %v", err)
+		}
+
+		if err := NewDecoder(NewValueParser(map[string]interface{}{"port": 80})).Decode(&v); err != nil || v.Port != 80 {
+			t.Errorf("invalid default values must not be used when the key is present: %d (%v)", v.Port, err)
+		}
+	})
+}
+
+func TestDecoderReuseSlices(t *testing.T) {
+	type Point struct {
+		X int
+		Y int
+	}
+
+	buf := make([]Point, 4, 8)
+	buf[0] = Point{X: 1, Y: 1}
+	buf[2] = Point{X: 2, Y: 2}
+	buf[3] = Point{X: 3, Y: 3}
+
+	v := buf[:1]
+	d := Decoder{Parser: NewValueParser([]interface{}{
+		map[string]interface{}{"X": 10},
+		map[string]interface{}{"Y": 20},
+	}), ReuseSlices: true}
+
+	if err := d.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	if expect := []Point{{X: 10}, {Y: 20}}; !reflect.DeepEqual(v, expect) {
+		t.Errorf("%#v != %#v", v, expect)
+	}
+
+	if &v[0] != &buf[0] {
+		t.Error("the backing array of the slice was not reused")
+	}
+
+	if stale := buf[2:4]; !reflect.DeepEqual(stale, []Point{{}, {}}) {
+		t.Errorf("elements past the decoded length were not zeroed: %#v", stale)
+	}
+
+	t.Run("grow", func(t *testing.T) {
+		v := make([]int, 0, 1)
+		d := Decoder{Parser: NewValueParser([]int{1, 2, 3}), ReuseSlices: true}
+
+		if err := d.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(v, []int{1, 2, 3}) {
+			t.Errorf("%#v", v)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		v := make([]int, 2, 4)
+
+		if err := NewDecoder(NewValueParser([]int{1})).Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+		if cap(v) == 4 {
+			t.Error("the backing array of the slice was reused when ReuseSlices is not set")
+		}
+	})
+}
+
+func TestDecoderStructAlias(t *testing.T) {
+	type User struct {
+		Email string `objconv:"emailAddress,alias=email|mail"`
+		Name  string `objconv:"name,alias=login"`
+		Login string `objconv:"login"`
+		Other string `objconv:"other,alias=email"`
+	}
+
+	tests := []struct {
+		name   string
+		in     map[string]interface{}
+		expect User
+	}{
+		{
+			name:   "new payload",
+			in:     map[string]interface{}{"emailAddress": "luke@example.com"},
+			expect: User{Email: "luke@example.com"},
+		},
+		{
+			name:   "old payload",
+			in:     map[string]interface{}{"email": "luke@example.com"},
+			expect: User{Email: "luke@example.com"},
+		},
+		{
+			name:   "second alias",
+			in:     map[string]interface{}{"mail": "luke@example.com"},
+			expect: User{Email: "luke@example.com"},
+		},
+		{
+			name:   "names take precedence over aliases",
+			in:     map[string]interface{}{"login": "luke"},
+			expect: User{Login: "luke"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var v User
+
+			if err := NewDecoder(NewValueParser(test.in)).Decode(&v); err != nil {
+				t.Fatal(err)
+			}
+			if v != test.expect {
+				t.Errorf("%#v != %#v", v, test.expect)
+			}
+		})
+	}
+
+	t.Run("encode", func(t *testing.T) {
+		var v interface{}
+
+		if err := NewDecoder(NewValueParser(User{Email: "luke@example.com"})).Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+		if m := v.(map[interface{}]interface{}); m["emailAddress"] != "luke@example.com" || len(m) != 4 {
+			t.Errorf("%#v", m)
+		}
+	})
+}
+
+func TestDecoderJSONPath(t *testing.T) {
+	type T struct {
+		Name  string
+		City  string `objconv:"city,jsonpath=/address/city"`
+		First int    `objconv:"first,jsonpath=/numbers/0"`
+	}
+
+	in := map[string]interface{}{
+		"address": map[string]interface{}{"city": "Paris", "zip": "75000"},
+		"Name":    "Luke",
+		"numbers": []interface{}{42, 43},
+	}
+
+	var v T
+	d := Decoder{Parser: NewValueParser(in), DisallowUnknownFields: true}
+
+	if err := d.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	if expect := (T{Name: "Luke", City: "Paris", First: 42}); v != expect {
+		t.Errorf("%#v != %#v", v, expect)
+	}
+
+	delete(in, "numbers")
+
+	err := NewDecoder(NewValueParser(in)).Decode(&v)
+	if err == nil {
+		t.Fatal("expected an error decoding a struct with an unresolvable jsonpath")
+	}
+
+	const expect = `objconv: the jsonpath "/numbers/0" of field first of objconv.T cannot be resolved`
+	if err.Error() != expect {
+		t.Errorf("%s != %s", err, expect)
+	}
+}
+
+func TestDecoderMaxDepth(t *testing.T) {
+	var in interface{} = map[string]interface{}{}
+
+	for i := 0; i != 100000; i++ {
+		if i%2 == 0 {
+			in = []interface{}{in}
+		} else {
+			in = map[string]interface{}{"a": in}
+		}
+	}
+
+	var v interface{}
+	d := Decoder{Parser: NewValueParser(in), MaxDepth: 1000}
+
+	err := d.Decode(&v)
+	if err == nil {
+		t.Fatal("expected an error decoding a value nested beyond the maximum depth")
+	}
+
+	if e, ok := err.(*DecodeError); !ok || e.Err.Error() != "objconv: maximum nesting depth 1000 exceeded" {
+		t.Error(err)
+	}
+
+	d = Decoder{Parser: NewValueParser([][]int{{1}, {2}}), MaxDepth: 2}
+
+	if err := d.Decode(&v); err != nil {
+		t.Error("the depth limit must not count sibling values:", err)
+	}
+}
+
+func TestDecoderNumericKeys(t *testing.T) {
+	type entry struct {
+		K interface{}
+		V interface{}
+	}
+
+	in := []entry{
+		{int64(0), "a"},
+		{"0", "b"},
+		{0.0, "c"},
+		{uint64(1), "d"},
+		{-0.0, "e"},
+		{1.5, "f"},
+		{int64(1), "g"},
+	}
+
+	// Parsers may produce maps with duplicate keys, use pair arrays to build
+	// such input with the value parser.
+	pairs := make([][]interface{}, len(in))
+	for i, e := range in {
+		pairs[i] = []interface{}{e.K, e.V}
+	}
+
+	expect := map[interface{}]interface{}{
+		0.0:      "e",
+		"0":      "b",
+		1.5:      "f",
+		int64(1): "g",
+	}
+
+	t.Run("map[interface{}]interface{}", func(t *testing.T) {
+		var m map[interface{}]interface{}
+		d := Decoder{Parser: NewValueParser(pairs), PairArraysAsMaps: true}
+
+		if err := d.Decode(&m); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(m, expect) {
+			t.Errorf("%#v != %#v", m, expect)
+		}
+	})
+
+	t.Run("map", func(t *testing.T) {
+		var m map[interface{}]interface{}
+		d := NewDecoder(NewValueParser(map[interface{}]interface{}{int64(0): "a", 0.0: "b", uint64(0): "c"}))
+
+		if err := d.Decode(&m); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(m) != 1 {
+			t.Errorf("%#v", m)
+		}
+	})
+
+	t.Run("map[interface{}]string", func(t *testing.T) {
+		var m map[interface{}]string
+		d := Decoder{Parser: NewValueParser(pairs), PairArraysAsMaps: true}
+
+		if err := d.Decode(&m); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(m) != len(expect) {
+			t.Errorf("%#v", m)
+		}
+
+		for k, v := range expect {
+			if m[k] != v {
+				t.Errorf("%#v: %#v != %#v", k, m[k], v)
+			}
+		}
+	})
+}
+
+func TestDecoderRequireSortedKeys(t *testing.T) {
+	type sorted struct{ A, B, C int }
+	type unsorted struct{ B, A int }
+	type duplicate struct {
+		A int
+		B int `objconv:"A"`
+	}
+
+	tests := []struct {
+		in  interface{}
+		out interface{}
+		ok  bool
+	}{
+		{sorted{1, 2, 3}, &map[string]int{}, true},
+		{sorted{1, 2, 3}, &map[string]interface{}{}, true},
+		{sorted{1, 2, 3}, &sorted{}, true},
+		{sorted{1, 2, 3}, new(interface{}), true},
+		{sorted{1, 2, 3}, nil, true},
+		{unsorted{1, 2}, &map[string]int{}, false},
+		{unsorted{1, 2}, &map[string]interface{}{}, false},
+		{unsorted{1, 2}, &sorted{}, false},
+		{unsorted{1, 2}, new(interface{}), false},
+		{unsorted{1, 2}, nil, false},
+		{duplicate{1, 2}, &map[string]int{}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%T->%T", test.in, test.out), func(t *testing.T) {
+			dec := Decoder{Parser: NewValueParser(test.in), RequireSortedKeys: true}
+			err := dec.Decode(test.out)
+
+			if test.ok && err != nil {
+				t.Error(err)
+			}
+			if !test.ok && err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		var m map[string]int
+		if err := NewDecoder(NewValueParser(unsorted{1, 2})).Decode(&m); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("custom", func(t *testing.T) {
+		var m map[string]int
+		dec := Decoder{
+			Parser:            NewValueParser(struct{ BB, A int }{1, 2}),
+			RequireSortedKeys: true,
+		}
+
+		if err := dec.Decode(&m); err == nil {
+			t.Error("expected an error with the default key ordering")
+		}
+
+		dec.Parser = NewValueParser(struct{ BB, A int }{1, 2})
+		dec.KeyCompare = func(a interface{}, b interface{}) int { return CompareKeysLengthFirst(b, a) }
+
+		if err := dec.Decode(&m); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestDecoderRejectDuplicateKeys(t *testing.T) {
+	type unique struct{ A, B int }
+	type duplicate struct {
+		A int
+		B int `objconv:"A"`
+	}
+
+	tests := []struct {
+		in  interface{}
+		out interface{}
+		ok  bool
+	}{
+		{unique{1, 2}, &map[string]int{}, true},
+		{unique{1, 2}, &map[string]interface{}{}, true},
+		{unique{1, 2}, &map[interface{}]interface{}{}, true},
+		{unique{1, 2}, &unique{}, true},
+		{duplicate{1, 2}, &map[string]int{}, false},
+		{duplicate{1, 2}, &map[string]interface{}{}, false},
+		{duplicate{1, 2}, &map[interface{}]interface{}{}, false},
+		{duplicate{1, 2}, &unique{}, false},
+		{duplicate{1, 2}, new(interface{}), false},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%T->%T", test.in, test.out), func(t *testing.T) {
+			dec := Decoder{Parser: NewValueParser(test.in), RejectDuplicateKeys: true}
+			err := dec.Decode(test.out)
+
+			if test.ok && err != nil {
+				t.Error(err)
+			}
+			if !test.ok && err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+
+	t.Run("error", func(t *testing.T) {
+		var v unique
+		dec := Decoder{Parser: NewValueParser(duplicate{1, 2}), RejectDuplicateKeys: true}
+
+		if err := dec.Decode(&v); err == nil || !strings.Contains(err.Error(), `"A"`) {
+			t.Error("expected an error naming the duplicate key but got", err)
+		}
+	})
+
+	t.Run("numeric", func(t *testing.T) {
+		var m map[interface{}]interface{}
+		dec := Decoder{
+			Parser:              NewValueParser([][]interface{}{{int64(1), "a"}, {1.0, "b"}}),
+			PairArraysAsMaps:    true,
+			RejectDuplicateKeys: true,
+		}
+
+		if err := dec.Decode(&m); err == nil {
+			t.Error("expected an error for numerically equal keys")
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		var v unique
+		if err := NewDecoder(NewValueParser(duplicate{1, 2})).Decode(&v); err != nil {
+			t.Error(err)
+		}
+		if v != (unique{A: 2}) {
+			t.Errorf("%#v", v)
+		}
+	})
+}
+
+func TestCompareKeys(t *testing.T) {
+	tests := []struct {
+		a interface{}
+		b interface{}
+		c int
+	}{
+		{"a", "b", -1},
+		{"b", "a", 1},
+		{"a", "a", 0},
+		{"b", "aa", 1},
+		{[]byte("a"), "b", -1},
+		{int64(-1), uint64(1), -1},
+		{uint64(1 << 63), int64(1), 1},
+		{int64(1<<62 + 1), int64(1 << 62), 1},
+		{1.5, int64(1), 1},
+	}
+
+	for _, test := range tests {
+		if c := CompareKeys(test.a, test.b); c != test.c {
+			t.Errorf("CompareKeys(%#v, %#v) = %d != %d", test.a, test.b, c, test.c)
+		}
+	}
+
+	if c := CompareKeysLengthFirst("b", "aa"); c != -1 {
+		t.Errorf("CompareKeysLengthFirst: %d", c)
+	}
+}
+
+func TestDecoderPairArraysAsMaps(t *testing.T) {
+	in := []interface{}{
+		[]interface{}{"a", 1},
+		[]interface{}{"b", 2},
+	}
+
+	t.Run("map[string]int", func(t *testing.T) {
+		var m map[string]int
+		dec := Decoder{Parser: NewValueParser(in), PairArraysAsMaps: true}
+
+		if err := dec.Decode(&m); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(m, map[string]int{"a": 1, "b": 2}) {
+			t.Errorf("%#v", m)
+		}
+	})
+
+	t.Run("map[string]interface{}", func(t *testing.T) {
+		var m map[string]interface{}
+		dec := Decoder{Parser: NewValueParser(in), PairArraysAsMaps: true}
+
+		if err := dec.Decode(&m); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(m, map[string]interface{}{"a": int64(1), "b": int64(2)}) {
+			t.Errorf("%#v", m)
+		}
+	})
+
+	t.Run("map[int]string", func(t *testing.T) {
+		var m map[int]string
+		dec := Decoder{Parser: NewValueParser([][]interface{}{{1, "a"}, {2, "b"}}), PairArraysAsMaps: true}
+
+		if err := dec.Decode(&m); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(m, map[int]string{1: "a", 2: "b"}) {
+			t.Errorf("%#v", m)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		var m map[string]int
+		if err := NewDecoder(NewValueParser(in)).Decode(&m); err == nil {
+			t.Error("expected an error when decoding an array into a map")
+		}
+	})
+
+	for _, bad := range []interface{}{
+		[]interface{}{[]interface{}{"a"}},
+		[]interface{}{[]interface{}{"a", 1, 2}},
+		[]interface{}{nil},
+		[]interface{}{"a"},
+	} {
+		t.Run(fmt.Sprint(bad), func(t *testing.T) {
+			var m map[string]int
+			dec := Decoder{Parser: NewValueParser(bad), PairArraysAsMaps: true}
+
+			if err := dec.Decode(&m); err == nil {
+				t.Error("expected an error for a malformed pair")
+			}
+		})
+	}
+}
+
+func TestDecoderInterfaceTimeAsString(t *testing.T) {
+	date := time.Date(2016, 12, 12, 01, 01, 01, 0, time.UTC)
+	in := map[string]interface{}{
+		"time":     date,
+		"duration": 1500 * time.Millisecond,
+		"error":    errors.New("oops"),
+	}
+
+	tests := []struct {
+		asString bool
+		expect   map[string]interface{}
+	}{
+		{
+			asString: false,
+			expect: map[string]interface{}{
+				"time":     date,
+				"duration": 1500 * time.Millisecond,
+				"error":    errors.New("oops"),
+			},
+		},
+		{
+			asString: true,
+			expect: map[string]interface{}{
+				"time":     "2016-12-12T01:01:01Z",
+				"duration": "1.5s",
+				"error":    "oops",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprint(test.asString), func(t *testing.T) {
+			var v map[string]interface{}
+			dec := Decoder{Parser: NewValueParser(in), InterfaceTimeAsString: test.asString}
+
+			if err := dec.Decode(&v); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(v, test.expect) {
+				t.Errorf("%#v != %#v", v, test.expect)
+			}
+		})
+	}
+}
+
+// bitset implements both encoding.BinaryUnmarshaler and
+// encoding.TextUnmarshaler, recording which one was used.
+type bitset struct {
+	bits uint8
+	from string
+}
+
+func (b *bitset) UnmarshalBinary(data []byte) error {
+	if len(data) != 1 {
+		return errors.New("bitset: invalid binary length")
+	}
+	b.bits, b.from = data[0], "binary"
+	return nil
+}
+
+func (b *bitset) UnmarshalText(text []byte) error {
+	v, err := strconv.ParseUint(string(text), 2, 8)
+	b.bits, b.from = uint8(v), "text"
+	return err
+}
+
+type binaryOnly struct{ data []byte }
+
+func (b *binaryOnly) UnmarshalBinary(data []byte) error {
+	b.data = append(b.data[:0], data...)
+	return nil
+}
+
+func TestDecoderBinaryUnmarshaler(t *testing.T) {
+	tests := []struct {
+		in     interface{}
+		expect bitset
+	}{
+		{[]byte{0x5}, bitset{bits: 0x5, from: "binary"}},
+		{"101", bitset{bits: 0x5, from: "text"}},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%T", test.in), func(t *testing.T) {
+			var v bitset
+
+			if err := NewDecoder(NewValueParser(test.in)).Decode(&v); err != nil {
+				t.Fatal(err)
+			}
+			if v != test.expect {
+				t.Errorf("%#v != %#v", v, test.expect)
+			}
+		})
+	}
+
+	t.Run("pointer", func(t *testing.T) {
+		var v struct{ B *bitset }
+
+		if err := NewDecoder(NewValueParser(map[string]interface{}{"B": []byte{0x3}})).Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+		if v.B == nil || *v.B != (bitset{bits: 0x3, from: "binary"}) {
+			t.Errorf("%#v", v.B)
+		}
+	})
+
+	t.Run("binary only", func(t *testing.T) {
+		var v binaryOnly
+
+		if err := NewDecoder(NewValueParser([]byte("abc"))).Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+		if string(v.data) != "abc" {
+			t.Errorf("%q", v.data)
+		}
+	})
+}
+
+func TestStreamDecoderSkip(t *testing.T) {
+	dec := NewStreamDecoder(NewValueParser([]int{0, 1, 2, 3, 4, 5}))
+
+	if n, err := dec.Skip(2); n != 2 || err != nil {
+		t.Fatal("skip:", n, err)
+	}
+
+	var v int
+	if err := dec.Decode(&v); err != nil || v != 2 {
+		t.Fatal("decode:", v, err)
+	}
+
+	if n := dec.Len(); n != 3 {
+		t.Error("length after skip:", n)
+	}
+
+	if n, err := dec.Skip(10); n != 3 || err != End {
+		t.Error("skip past the end:", n, err)
+	}
+
+	if err := dec.Err(); err != nil {
+		t.Error(err)
+	}
+
+	if n, err := dec.Skip(1); n != 0 || err != End {
+		t.Error("skip after the end:", n, err)
+	}
+
+	t.Run("SkipParser", func(t *testing.T) {
+		p := &skipParser{ValueParser: NewValueParser([]interface{}{map[string]interface{}{"a": 1}, []int{1}, 2})}
+		dec := NewStreamDecoder(p)
+
+		if n, err := dec.Skip(2); n != 2 || err != nil {
+			t.Fatal("skip:", n, err)
+		}
+
+		if p.skips != 2 {
+			t.Errorf("expected 2 calls to SkipValue but got %d", p.skips)
+		}
+
+		var v int
+		if err := dec.Decode(&v); err != nil || v != 2 {
+			t.Fatal("decode:", v, err)
+		}
+	})
+}
+
+// skipParser is a SkipParser which counts the values that it skipped.
+type skipParser struct {
+	*ValueParser
+	skips int
+}
+
+func (p *skipParser) SkipValue() error {
+	p.skips++
+	return (Decoder{Parser: p.ValueParser}).Decode(nil)
+}
+
+func TestStreamDecoderDecodeRemaining(t *testing.T) {
+	t.Run("array", func(t *testing.T) {
+		dec := NewStreamDecoder(NewValueParser([]int{0, 1, 2, 3, 4}))
+
+		var v int
+		if err := dec.Decode(&v); err != nil || v != 0 {
+			t.Fatal("decode:", v, err)
+		}
+		if err := dec.Decode(&v); err != nil || v != 1 {
+			t.Fatal("decode:", v, err)
+		}
+
+		rest := []int{-1}
+		if err := dec.DecodeRemaining(&rest); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(rest, []int{-1, 2, 3, 4}) {
+			t.Errorf("bad values: %#v", rest)
+		}
+
+		if err := dec.DecodeRemaining(&rest); err != nil || len(rest) != 4 {
+			t.Error("decode remaining after the end:", rest, err)
+		}
+	})
+
+	t.Run("single-value", func(t *testing.T) {
+		var rest []string
+		dec := NewStreamDecoder(NewValueParser("hello"))
+
+		if err := dec.DecodeRemaining(&rest); err != nil || !reflect.DeepEqual(rest, []string{"hello"}) {
+			t.Error("decode remaining:", rest, err)
+		}
+
+		rest = nil
+		dec = NewStreamDecoder(NewValueParser("hello"))
+
+		if err := dec.Decode(new(string)); err != nil {
+			t.Fatal(err)
+		}
+		if err := dec.DecodeRemaining(&rest); err != nil || len(rest) != 0 {
+			t.Error("decode remaining after decoding the value:", rest, err)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		var rest []int
+		dec := NewStreamDecoder(NewValueParser([]interface{}{1, "x", 3}))
+
+		if err := dec.DecodeRemaining(&rest); err == nil || !reflect.DeepEqual(rest, []int{1}) {
+			t.Error("decode remaining:", rest, err)
+		}
+	})
+}
+
+func TestStreamDecoderRemaining(t *testing.T) {
+	dec := NewStreamDecoder(NewValueParser([]int{0, 1, 2}))
+
+	if n, known := dec.Remaining(); n != 0 || known {
+		t.Error("remaining before the first decode:", n, known)
+	}
+
+	for i := 3; i != 0; i-- {
+		var v int
+		if err := dec.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+		if n, known := dec.Remaining(); n != i-1 || !known {
+			t.Error("remaining:", n, known)
+		}
+	}
+
+	if err := dec.Decode(new(int)); err != End {
+		t.Error("decode past the end:", err)
+	}
+
+	if n, known := dec.Remaining(); n != 0 || !known {
+		t.Error("remaining after the end:", n, known)
+	}
+}
+
+func TestDecoderDisallowUnknownFields(t *testing.T) {
+	type Address struct {
+		City string
+	}
+
+	type T struct {
+		Name    string
+		Address Address
+	}
+
+	tests := []struct {
+		in  interface{}
+		err string
+	}{
+		{
+			in: map[string]interface{}{"Name": "Luke", "Address": map[string]interface{}{"City": "Tatooine"}},
+		},
+		{
+			in:  map[string]interface{}{"Name": "Luke", "Age": 19},
+			err: `objconv: unknown field "Age" in objconv.T`,
+		},
+		{
+			in:  map[string]interface{}{"Address": map[string]interface{}{"Zip": "42"}},
+			err: `Address: objconv: unknown field "Zip" in objconv.Address`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprint(test.in), func(t *testing.T) {
+			var v T
+			dec := Decoder{Parser: NewValueParser(test.in), DisallowUnknownFields: true}
+			err := dec.Decode(&v)
+
+			switch {
+			case test.err == "" && err != nil:
+				t.Error(err)
+			case test.err != "" && (err == nil || err.Error() != test.err):
+				t.Errorf("expected error %q but got %v", test.err, err)
+			}
+		})
+	}
+
+	t.Run("default", func(t *testing.T) {
+		var v T
+		if err := NewDecoder(NewValueParser(map[string]interface{}{"Age": 19})).Decode(&v); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		var v T
+		dec := NewStreamDecoder(NewValueParser([]interface{}{map[string]interface{}{"Age": 19}}))
+		dec.DisallowUnknownFields = true
+
+		if err := dec.Decode(&v); err == nil {
+			t.Error("expected an error from the stream decoder")
+		}
+	})
+}
+
+func TestDecoderDecodeErrorPath(t *testing.T) {
+	type Address struct {
+		Zip int8 `objconv:"zip"`
+	}
+
+	type User struct {
+		Address Address `objconv:"address"`
+	}
+
+	tests := []struct {
+		in   interface{}
+		out  interface{}
+		path string
+	}{
+		{
+			in: map[string]interface{}{"users": []interface{}{
+				map[string]interface{}{},
+				map[string]interface{}{"address": map[string]interface{}{"zip": 300}},
+			}},
+			out:  &map[string][]User{},
+			path: "users[1].address.zip",
+		},
+		{
+			in:   map[string]interface{}{"a": []interface{}{[]interface{}{1}, []interface{}{2}, []interface{}{true}}},
+			out:  &map[string][][]int{},
+			path: "a[2][0]",
+		},
+		{
+			in:   map[int]interface{}{42: "x"},
+			out:  &map[int]int{},
+			path: "[42]",
+		},
+		{
+			in:   map[string]interface{}{"a": 1},
+			out:  &map[string]string{},
+			path: "a",
+		},
+		{
+			in:   []interface{}{"x"},
+			out:  &[1]int{},
+			path: "[0]",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			err := NewDecoder(NewValueParser(test.in)).Decode(test.out)
+
+			e, ok := err.(*DecodeError)
+			if !ok {
+				t.Fatalf("expected a *DecodeError but got %#v", err)
+			}
+
+			if e.Path != test.path {
+				t.Errorf("%q != %q", e.Path, test.path)
+			}
+
+			if e.Err == nil {
+				t.Error("missing wrapped error")
+			}
+		})
+	}
+
+	t.Run("top-level", func(t *testing.T) {
+		var v int8
+		err := NewDecoder(NewValueParser(300)).Decode(&v)
+
+		if _, ok := err.(*DecodeError); ok || err == nil {
+			t.Errorf("expected an unwrapped error but got %#v", err)
+		}
+	})
+}
+
+type directParser struct {
+	*ValueParser
+	calls int
+}
+
+func (p *directParser) ParseValue() (interface{}, bool) {
+	p.calls++
+	v := p.value()
+	if !v.IsValid() {
+		return nil, false
+	}
+	return v.Interface(), true
+}
+
+func TestDecoderErrorHandler(t *testing.T) {
+	in := []interface{}{1, "x", 3, map[string]interface{}{}, 5}
+
+	t.Run("DirectParser", func(t *testing.T) {
+		var v []int
+		var errs SkippedErrors
+
+		d := Decoder{Parser: &directParser{ValueParser: NewValueParser(in)}, ErrorHandler: errs.Skip}
+
+		if err := d.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(v, []int{1, 3, 5}) {
+			t.Errorf("bad value: %#v", v)
+		}
+
+		if len(errs) != 2 {
+			t.Errorf("bad errors: %v", errs)
+		}
+	})
+
+	t.Run("Parser", func(t *testing.T) {
+		var v []int
+		var indexes []int
+
+		d := Decoder{Parser: NewValueParser(in), ErrorHandler: func(index int, err error) error {
+			indexes = append(indexes, index)
+			return nil
+		}}
+
+		if err := d.Decode(&v); err == nil {
+			t.Error("expected an error from a parser which cannot skip elements")
+		}
+
+		if !reflect.DeepEqual(indexes, []int{1}) {
+			t.Errorf("the error handler must be called before aborting: %v", indexes)
+		}
+	})
+}
+
+func TestDecoderDirectParser(t *testing.T) {
+	t.Run("assignable", func(t *testing.T) {
+		in := map[string]interface{}{"a": []interface{}{1, "2"}}
+		p := &directParser{ValueParser: NewValueParser(in)}
+
+		var out interface{}
+		if err := NewDecoder(p).Decode(&out); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(out, in) {
+			t.Errorf("%#v != %#v", out, in)
+		}
+
+		if p.calls != 1 {
+			t.Errorf("expected a single call to ParseValue but got %d", p.calls)
+		}
+	})
+
+	t.Run("nested", func(t *testing.T) {
+		type T struct {
+			A interface{}
+			B []interface{}
+			C int
+		}
+
+		in := map[string]interface{}{
+			"A": map[string]interface{}{"x": 1},
+			"B": []interface{}{true, "x"},
+			"C": 42,
+		}
+		p := &directParser{ValueParser: NewValueParser(in)}
+
+		var out T
+		if err := NewDecoder(p).Decode(&out); err != nil {
+			t.Fatal(err)
+		}
+
+		expect := T{A: in["A"], B: in["B"].([]interface{}), C: 42}
+
+		if !reflect.DeepEqual(out, expect) {
+			t.Errorf("%#v != %#v", out, expect)
+		}
+	})
+
+	t.Run("not-assignable", func(t *testing.T) {
+		p := &directParser{ValueParser: NewValueParser([]interface{}{int64(1), int64(2)})}
+
+		var out []int
+		if err := NewDecoder(p).Decode(&out); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(out, []int{1, 2}) {
+			t.Errorf("%#v", out)
+		}
+	})
+}
+
+type fieldDecoderDirectTest struct {
+	Name string
+	Age  int
+}
+
+func TestDecoderDirectParserFieldDecoder(t *testing.T) {
+	typ := reflect.TypeOf(fieldDecoderDirectTest{})
+
+	if err := RegisterFieldDecoder(typ, "Name", func(d Decoder, v reflect.Value) error {
+		var s string
+		if err := d.Decode(&s); err != nil {
+			return err
+		}
+		v.SetString(strings.ToUpper(s))
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer RegisterFieldDecoder(typ, "Name", nil)
+
+	tests := []struct {
+		name string
+		in   interface{}
+		opts func(*Decoder)
+	}{
+		{"flat", map[string]interface{}{"Name": "luke", "Age": 19}, func(*Decoder) {}},
+		{"fields", map[string]interface{}{"Name": "luke", "Age": 19}, func(d *Decoder) { d.RejectDuplicateKeys = true }},
+		{"array", []interface{}{"luke", 19}, func(d *Decoder) { d.ArrayToStruct = true }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var v fieldDecoderDirectTest
+			d := NewDecoder(&directParser{ValueParser: NewValueParser(test.in)})
+			test.opts(d)
+
+			if err := d.Decode(&v); err != nil {
+				t.Fatal(err)
+			}
+
+			if v != (fieldDecoderDirectTest{Name: "LUKE", Age: 19}) {
+				t.Errorf("the field decoder was not called: %#v", v)
+			}
+		})
+	}
+}
+
+func TestDecoderDirectParserHooks(t *testing.T) {
+	type T struct {
+		Name string
+		Tags []string
+	}
+
+	in := map[string]interface{}{"Name": " luke\x00", "Tags": []string{" jedi\x00"}}
+
+	tests := []struct {
+		name string
+		opts func(*Decoder)
+		out  T
+	}{
+		{
+			name: "StringHook",
+			opts: func(d *Decoder) {
+				d.StringHook = func(b []byte) ([]byte, error) { return bytes.Trim(b, " \x00"), nil }
+			},
+			out: T{Name: "luke", Tags: []string{"jedi"}},
+		},
+		{
+			name: "ValueHook",
+			opts: func(d *Decoder) {
+				d.ValueHook = func(t Type, v reflect.Value) error {
+					if v.Kind() == reflect.String {
+						v.SetString(strings.Trim(v.String(), " \x00"))
+					}
+					return nil
+				}
+			},
+			out: T{Name: "luke", Tags: []string{"jedi"}},
+		},
+		{
+			name: "ControlCharPolicy",
+			opts: func(d *Decoder) { d.ControlCharPolicy = ControlCharStrip },
+			out:  T{Name: " luke", Tags: []string{" jedi"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var v T
+			d := NewDecoder(&directParser{ValueParser: NewValueParser(in)})
+			test.opts(d)
+
+			if err := d.Decode(&v); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(v, test.out) {
+				t.Errorf("the values were assigned without going through the hooks: %#v", v)
+			}
+		})
+	}
+}
+
+func TestDecoderDirectParserOptions(t *testing.T) {
+	t.Run("ReuseSlices", func(t *testing.T) {
+		in := []int{1, 2}
+		v := make([]int, 0, 4)
+		d := Decoder{Parser: &directParser{ValueParser: NewValueParser(in)}, ReuseSlices: true}
+
+		if err := d.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(v, in) || cap(v) != 4 {
+			t.Errorf("the backing array was not reused: %#v (cap=%d)", v, cap(v))
+		}
+	})
+
+	t.Run("DefaultFunc", func(t *testing.T) {
+		var v [][]int
+		d := Decoder{
+			Parser: &directParser{ValueParser: NewValueParser([][]int{nil})},
+			DefaultFunc: func(t reflect.Type) (reflect.Value, bool) {
+				return reflect.MakeSlice(t, 0, 0), t.Kind() == reflect.Slice
+			},
+		}
+
+		if err := d.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(v) != 1 || v[0] == nil {
+			t.Errorf("the null slice was not replaced by the default value: %#v", v)
+		}
+	})
+
+	t.Run("uniqueby", func(t *testing.T) {
+		type Item struct {
+			Key   string
+			Value int
+		}
+
+		var v struct {
+			Items []Item `objconv:"items,uniqueby=Key"`
+		}
+
+		items := []Item{{"a", 1}, {"b", 2}, {"a", 3}}
+		in := map[string]interface{}{"items": items}
+
+		if err := NewDecoder(&directParser{ValueParser: NewValueParser(in)}).Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+
+		if expect := []Item{{"a", 3}, {"b", 2}}; !reflect.DeepEqual(v.Items, expect) {
+			t.Errorf("%#v != %#v", v.Items, expect)
+		}
+
+		if expect := []Item{{"a", 1}, {"b", 2}, {"a", 3}}; !reflect.DeepEqual(items, expect) {
+			t.Errorf("the input was modified: %#v", items)
+		}
+	})
+}
+
+func TestDecoderBigNumbers(t *testing.T) {
+	t.Run("big.Int", func(t *testing.T) {
+		tests := []struct {
+			in  interface{}
+			out string
+		}{
+			{int64(-42), "-42"},
+			{uint64(1 << 63), "9223372036854775808"},
+			{float64(1e18), "1000000000000000000"},
+			{"123456789012345678901234567890", "123456789012345678901234567890"},
+		}
+
+		for _, test := range tests {
+			var v *big.Int
+
+			if err := NewDecoder(NewValueParser(test.in)).Decode(&v); err != nil {
+				t.Error(err)
+				continue
+			}
+
+			if v.String() != test.out {
+				t.Errorf("%s != %s", v, test.out)
+			}
+		}
+	})
+
+	t.Run("big.Float", func(t *testing.T) {
+		tests := []struct {
+			in  interface{}
+			out string
+		}{
+			{int64(-42), "-42"},
+			{uint64(1 << 63), "9223372036854775808"},
+			{0.5, "0.5"},
+			{"3.14159265358979323846264338327950288", "3.14159265358979323846264338327950288"},
+		}
+
+		for _, test := range tests {
+			var v big.Float
+
+			if err := NewDecoder(NewValueParser(test.in)).Decode(&v); err != nil {
+				t.Error(err)
+				continue
+			}
+
+			if s := v.Text('g', 36); s != test.out {
+				t.Errorf("%s != %s", s, test.out)
+			}
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			in  interface{}
+			out interface{}
+		}{
+			{0.5, new(big.Int)},
+			{"1.5", new(big.Int)},
+			{"hello", new(big.Float)},
+			{true, new(big.Int)},
+		}
+
+		for _, test := range tests {
+			if err := NewDecoder(NewValueParser(test.in)).Decode(test.out); err == nil {
+				t.Errorf("%#v: expected an error decoding into %T", test.in, test.out)
+			}
+		}
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		v := big.NewInt(42)
+
+		if err := NewDecoder(NewValueParser(nil)).Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+
+		if v != nil {
+			t.Errorf("expected a nil pointer but got %s", v)
+		}
+	})
+}
+
+func TestDecoderNetAndURL(t *testing.T) {
+	_, ipnet, _ := net.ParseCIDR("192.0.2.0/24")
+	u, _ := url.Parse("http://localhost:4242/hello?answer=42")
+
+	tests := []struct {
+		in  interface{}
+		out interface{}
+		exp interface{}
+	}{
+		{"127.0.0.1", new(net.IP), net.ParseIP("127.0.0.1")},
+		{"::1", new(net.IP), net.ParseIP("::1")},
+		{[]byte{127, 0, 0, 1}, new(net.IP), net.IP{127, 0, 0, 1}},
+		{[]byte(net.ParseIP("::1")), new(net.IP), net.ParseIP("::1")},
+		{[]byte("10.0.0.1"), new(net.IP), net.ParseIP("10.0.0.1")},
+		{"", new(net.IP), net.IP(nil)},
+		{nil, new(net.IP), net.IP(nil)},
+		{"192.0.2.0/24", new(net.IPNet), *ipnet},
+		{"192.0.2.0/24", new(*net.IPNet), ipnet},
+		{nil, new(net.IPNet), net.IPNet{}},
+		{nil, new(*net.IPNet), (*net.IPNet)(nil)},
+		{"http://localhost:4242/hello?answer=42", new(url.URL), *u},
+		{"http://localhost:4242/hello?answer=42", new(*url.URL), u},
+		{nil, new(url.URL), url.URL{}},
+		{nil, new(*url.URL), (*url.URL)(nil)},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%T(%v)", test.exp, test.in), func(t *testing.T) {
+			if err := NewDecoder(NewValueParser(test.in)).Decode(test.out); err != nil {
+				t.Fatal(err)
+			}
+			if v := reflect.ValueOf(test.out).Elem().Interface(); !reflect.DeepEqual(v, test.exp) {
+				t.Errorf("%#v != %#v", v, test.exp)
+			}
+		})
+	}
+
+	t.Run("round-trip", func(t *testing.T) {
+		type T struct {
+			IP     net.IP
+			Net    net.IPNet
+			NetPtr *net.IPNet
+			URL    url.URL
+			URLPtr *url.URL
+		}
+
+		for _, test := range []T{
+			{},
+			{IP: net.IPv4(10, 0, 0, 1), Net: *ipnet, NetPtr: ipnet, URL: *u, URLPtr: u},
+		} {
+			e := NewValueEmitter()
+
+			if err := NewEncoder(e).Encode(test); err != nil {
+				t.Fatal(err)
+			}
+
+			for k, v := range e.Value().(map[interface{}]interface{}) {
+				if _, ok := v.(string); !ok && v != nil {
+					t.Errorf("%s: expected a string but got %#v", k, v)
+				}
+			}
+
+			var v T
+			if err := NewDecoder(NewValueParser(e.Value())).Decode(&v); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(v, test) {
+				t.Errorf("%#v != %#v", v, test)
+			}
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			in  interface{}
+			out interface{}
+		}{
+			{"localhost", new(net.IP)},
+			{[]byte{1, 2, 3}, new(net.IP)},
+			{42, new(net.IP)},
+			{"192.0.2.0", new(net.IPNet)},
+			{"http://[::1", new(url.URL)},
+			{true, new(*url.URL)},
+		}
+
+		for _, test := range tests {
+			if err := NewDecoder(NewValueParser(test.in)).Decode(test.out); err == nil {
+				t.Errorf("%#v: expected an error decoding into %T", test.in, test.out)
+			}
+		}
+	})
+}
+
+func TestDecoderDecodeMapN(t *testing.T) {
+	var m map[string]int
+	var calls int
+
+	d := NewDecoder(NewValueParser(map[string]int{"a": 1, "b": 2, "c": 3}))
+
+	err := d.DecodeMapN(func(kd Decoder, vd Decoder, n int) error {
+		var k string
+		var v int
+
+		if m == nil {
+			m = make(map[string]int, n)
+		}
+
+		if n != 3 {
+			t.Errorf("bad number of entries: %d", n)
+		}
+
+		calls++
+
+		if err := kd.Decode(&k); err != nil {
+			return err
+		}
+		if err := vd.Decode(&v); err != nil {
+			return err
+		}
+
+		m[k] = v
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if calls != 3 {
+		t.Errorf("bad number of calls: %d", calls)
+	}
+
+	if !reflect.DeepEqual(m, map[string]int{"a": 1, "b": 2, "c": 3}) {
+		t.Errorf("bad map: %#v", m)
+	}
+}
+
+func TestDecoderWrapMapErrors(t *testing.T) {
+	decodeInts := func(kd Decoder, vd Decoder) error {
+		var k string
+		var v int
+		if err := kd.Decode(&k); err != nil {
+			return err
+		}
+		return vd.Decode(&v)
+	}
+
+	input := map[string]interface{}{"a": 1, "b": "x"}
+
+	t.Run("disabled", func(t *testing.T) {
+		err := NewDecoder(NewValueParser(input)).DecodeMap(decodeInts)
+		if _, ok := err.(*DecodeError); ok || err == nil {
+			t.Errorf("expected an unwrapped error but got %v", err)
+		}
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		d := Decoder{Parser: NewValueParser(input), WrapMapErrors: true}
+		err := d.DecodeMap(decodeInts)
+		if e, ok := err.(*DecodeError); !ok || e.Path != "b" {
+			t.Errorf("expected an error for key b but got %v", err)
+		}
+	})
+
+	t.Run("DecodeMapN", func(t *testing.T) {
+		d := Decoder{Parser: NewValueParser(input), WrapMapErrors: true}
+		err := d.DecodeMapN(func(kd Decoder, vd Decoder, n int) error { return decodeInts(kd, vd) })
+		if e, ok := err.(*DecodeError); !ok || e.Path != "b" {
+			t.Errorf("expected an error for key b but got %v", err)
+		}
+	})
+
+	t.Run("nested", func(t *testing.T) {
+		d := Decoder{Parser: NewValueParser(map[string]interface{}{"a": input}), WrapMapErrors: true}
+		err := d.DecodeMap(func(kd Decoder, vd Decoder) error {
+			var k string
+			if err := kd.Decode(&k); err != nil {
+				return err
+			}
+			return vd.DecodeMap(decodeInts)
+		})
+		if e, ok := err.(*DecodeError); !ok || e.Path != "a.b" {
+			t.Errorf("expected an error for key a.b but got %v", err)
+		}
+	})
+
+	t.Run("key-error", func(t *testing.T) {
+		d := Decoder{Parser: NewValueParser(map[string]interface{}{"a": 1}), WrapMapErrors: true}
+		err := d.DecodeMap(func(kd Decoder, vd Decoder) error {
+			var k int
+			return kd.Decode(&k)
+		})
+		if _, ok := err.(*DecodeError); ok || err == nil {
+			t.Errorf("expected an unwrapped error but got %v", err)
+		}
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		d := Decoder{Parser: NewValueParser(map[string]interface{}{"a": 1}), WrapMapErrors: true}
+		err := d.DecodeMap(func(kd Decoder, vd Decoder) error {
+			// A copy of the key decoder reset to read another input doesn't
+			// decode the key of the map.
+			c := kd
+			c.Reset(NewValueParser(42))
+			var n int
+			if err := c.Decode(&n); err != nil {
+				return err
+			}
+			return errors.New("failed")
+		})
+		if _, ok := err.(*DecodeError); ok || err == nil {
+			t.Errorf("expected an unwrapped error but got %v", err)
+		}
+	})
+}
+
+func TestDecoderAllowSpecialFloats(t *testing.T) {
+	tests := []struct {
+		in    string
+		out   float64
+		iface bool // whether the option decodes the string into interfaces as a float
+	}{
+		{"NaN", math.NaN(), true},
+		{"Infinity", math.Inf(+1), true},
+		{"-Infinity", math.Inf(-1), true},
+		{"Inf", math.Inf(+1), false},
+		{"+Inf", math.Inf(+1), false},
+		{"-Inf", math.Inf(-1), false},
+	}
+
+	equal := func(a, b float64) bool {
+		return math.IsNaN(a) == math.IsNaN(b) && (math.IsNaN(a) || a == b)
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			// Strings are decoded into floats with strconv.ParseFloat, which
+			// accepts these spellings whether the option is set or not.
+			for _, allow := range []bool{false, true} {
+				var f float64
+				d := Decoder{Parser: NewValueParser(test.in), AllowSpecialFloats: allow}
+
+				if err := d.Decode(&f); err != nil {
+					t.Fatal(err)
+				}
+
+				if !equal(f, test.out) {
+					t.Errorf("%g != %g", f, test.out)
+				}
+			}
+		})
+	}
+
+	t.Run("interface", func(t *testing.T) {
+		for _, test := range tests {
+			var v interface{}
+			d := Decoder{Parser: NewValueParser(test.in), AllowSpecialFloats: true}
+
+			if err := d.Decode(&v); err != nil {
+				t.Fatal(err)
+			}
+
+			if f, ok := v.(float64); ok != test.iface || (ok && !equal(f, test.out)) || (!ok && v != test.in) {
+				t.Errorf("%q: bad value: %#v", test.in, v)
+			}
+
+			if err := NewDecoder(NewValueParser(test.in)).Decode(&v); err != nil || v != test.in {
+				t.Errorf("%q: special floats decoded into empty interfaces must be strings by default: %#v (%v)", test.in, v, err)
+			}
+		}
+	})
+
+	t.Run("float32 overflow", func(t *testing.T) {
+		var f float32
+
+		if err := NewDecoder(NewValueParser(1e300)).Decode(&f); err == nil {
+			t.Errorf("expected an overflow error but got %g", f)
+		}
+
+		if err := NewDecoder(NewValueParser(math.Inf(-1))).Decode(&f); err != nil || !math.IsInf(float64(f), -1) {
+			t.Errorf("infinite values are not overflows: %g (%v)", f, err)
+		}
+	})
+}
+
+func TestDecoderLazyWithoutRawParser(t *testing.T) {
+	var m map[string]LazyValue
+
+	in := map[string]interface{}{"a": int64(1), "b": []interface{}{"x"}}
+
+	if err := NewDecoder(NewValueParser(in)).Decode(&m); err != nil {
+		t.Fatal(err)
+	}
+
+	var a int
+	if err := m["a"].Decode(&a); err != nil || a != 1 {
+		t.Errorf("bad value: %d (%v)", a, err)
+	}
+
+	var b []string
+	if err := m["b"].Decode(&b); err != nil || !reflect.DeepEqual(b, []string{"x"}) {
+		t.Errorf("bad value: %q (%v)", b, err)
+	}
+
+	if raw := m["a"].Raw(); raw != nil {
+		t.Errorf("expected no raw value but got %q", raw)
+	}
+
+	var z interface{} = 42
+	if err := (LazyValue{}).Decode(&z); err != nil || z != nil {
+		t.Errorf("the zero value must decode as null: %#v (%v)", z, err)
+	}
+}
+
+func TestDecoderComplex(t *testing.T) {
+	tests := []struct {
+		in  interface{}
+		out interface{}
+		exp interface{}
+	}{
+		{[]interface{}{3, 4}, new(complex128), complex(3, 4)},
+		{[]interface{}{1.5, -0.5}, new(complex64), complex64(complex(1.5, -0.5))},
+		{"3+4i", new(complex128), complex(3, 4)},
+		{[]byte("-2i"), new(complex64), complex64(complex(0, -2))},
+		{nil, new(complex128), complex128(0)},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprint(test.in), func(t *testing.T) {
+			if err := NewDecoder(NewValueParser(test.in)).Decode(test.out); err != nil {
+				t.Fatal(err)
+			}
+			if v := reflect.ValueOf(test.out).Elem().Interface(); v != test.exp {
+				t.Errorf("%v != %v", v, test.exp)
+			}
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			in  interface{}
+			out interface{}
+		}{
+			{[]interface{}{1}, new(complex128)},
+			{[]interface{}{1, 2, 3}, new(complex128)},
+			{[]interface{}{"a", 2}, new(complex128)},
+			{"3+", new(complex128)},
+			{true, new(complex128)},
+			{[]interface{}{1e300, 0}, new(complex64)},
+			{"1e300i", new(complex64)},
+		}
+
+		for _, test := range tests {
+			if err := NewDecoder(NewValueParser(test.in)).Decode(test.out); err == nil {
+				t.Errorf("%#v: expected an error decoding into %T", test.in, test.out)
+			}
+		}
+	})
+
+	t.Run("interface", func(t *testing.T) {
+		// There is no complex Type, so complex numbers decoded into empty
+		// interfaces keep the representation that they were encoded with.
+		for _, in := range []interface{}{[]interface{}{3, 4}, "3+4i"} {
+			var v interface{}
+
+			if err := NewDecoder(NewValueParser(in)).Decode(&v); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, ok := v.(complex128); ok {
+				t.Errorf("%#v: unexpected complex number %v", in, v)
+			}
+		}
+	})
+}
+
+type fieldDecoderTest struct {
+	Name string
+	Tags []string `objconv:"tags"`
+}
+
+func TestRegisterFieldDecoder(t *testing.T) {
+	typ := reflect.TypeOf(fieldDecoderTest{})
+	in := map[string]interface{}{"Name": "Luke", "tags": "a,b,c"}
+
+	if err := RegisterFieldDecoder(typ, "Tags", func(d Decoder, v reflect.Value) error {
+		var s string
+		if err := d.Decode(&s); err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(strings.Split(s, ",")))
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var v fieldDecoderTest
+	err := NewDecoder(NewValueParser(in)).Decode(&v)
+
+	if err := RegisterFieldDecoder(typ, "Tags", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(v, fieldDecoderTest{Name: "Luke", Tags: []string{"a", "b", "c"}}) {
+		t.Errorf("%#v", v)
+	}
+
+	if err := NewDecoder(NewValueParser(in)).Decode(&v); err == nil {
+		t.Error("expected an error decoding a string into a slice once the field decoder was removed")
+	}
+
+	for _, test := range []struct {
+		typ  reflect.Type
+		name string
+	}{
+		{typ, "Missing"},
+		{typ, "tags"},
+		{reflect.TypeOf(struct{ name string }{}), "name"},
+		{reflect.TypeOf(""), "Name"},
+	} {
+		if err := RegisterFieldDecoder(test.typ, test.name, func(Decoder, reflect.Value) error { return nil }); err == nil {
+			t.Errorf("%s.%s: expected an error registering the field decoder", test.typ, test.name)
+		}
+	}
+}
+
+func TestDecoderStrictArrayLength(t *testing.T) {
+	tests := []struct {
+		in     []int
+		strict bool
+		out    [3]int
+		err    string
+	}{
+		{in: []int{1, 2, 3}, out: [3]int{1, 2, 3}},
+		{in: []int{1, 2, 3}, strict: true, out: [3]int{1, 2, 3}},
+		{in: []int{1, 2, 3, 4}, out: [3]int{1, 2, 3}},
+		{in: []int{1, 2, 3, 4}, strict: true, err: "objconv: array length mismatch, expected 3 elements but the array has 4"},
+		{in: []int{1, 2}, err: "objconv: array length mismatch, expected 3 but only 2 elements were decoded"},
+		{in: []int{1, 2}, strict: true, err: "objconv: array length mismatch, expected 3 but only 2 elements were decoded"},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprint(test.in, test.strict), func(t *testing.T) {
+			var v [3]int
+			d := Decoder{Parser: NewValueParser(test.in), StrictArrayLength: test.strict}
+			err := d.Decode(&v)
+
+			if len(test.err) != 0 {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("bad error: %v", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if v != test.out {
+				t.Errorf("%v != %v", v, test.out)
+			}
+		})
+	}
+}
+
+// uuid is a [16]byte array implementing encoding.TextMarshaler and
+// encoding.TextUnmarshaler, like the types of the popular uuid packages.
+type uuid [16]byte
+
+func (u uuid) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%x-%x-%x-%x-%x", u[:4], u[4:6], u[6:8], u[8:10], u[10:])), nil
+}
+
+func (u *uuid) UnmarshalText(b []byte) error {
+	s := strings.Replace(string(b), "-", "", -1)
+	if len(s) != 32 {
+		return fmt.Errorf("invalid uuid: %q", b)
+	}
+	for i := range u {
+		x, err := strconv.ParseUint(s[2*i:2*i+2], 16, 8)
+		if err != nil {
+			return err
+		}
+		u[i] = byte(x)
+	}
+	return nil
+}
+
+// binaryUUID also implements encoding.BinaryUnmarshaler, so it may be decoded
+// from raw bytes as well.
+type binaryUUID [16]byte
+
+func (u *binaryUUID) UnmarshalText(b []byte) error {
+	return (*uuid)(u).UnmarshalText(b)
+}
+
+func (u *binaryUUID) UnmarshalBinary(b []byte) error {
+	if len(b) != 16 {
+		return fmt.Errorf("invalid uuid: %q", b)
+	}
+	copy(u[:], b)
+	return nil
+}
+
+func TestDecoderArrayTextUnmarshaler(t *testing.T) {
+	const s = "f47ac10b-58cc-4372-a567-0e02b2c3d479"
+
+	var expect uuid
+	if err := expect.UnmarshalText([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("value", func(t *testing.T) {
+		var u uuid
+		if err := NewDecoder(NewValueParser(s)).Decode(&u); err != nil {
+			t.Fatal(err)
+		}
+		if u != expect {
+			t.Errorf("%x != %x", u, expect)
+		}
+	})
+
+	t.Run("struct", func(t *testing.T) {
+		var v struct {
+			IDs  []uuid
+			Keys map[uuid]int
+		}
+		in := map[string]interface{}{
+			"IDs":  []interface{}{s},
+			"Keys": map[string]interface{}{s: 1},
+		}
+		if err := NewDecoder(NewValueParser(in)).Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+		if len(v.IDs) != 1 || v.IDs[0] != expect || v.Keys[expect] != 1 {
+			t.Errorf("%#v", v)
+		}
+	})
+
+	t.Run("array", func(t *testing.T) {
+		var u uuid
+		if err := NewDecoder(NewValueParser(make([]interface{}, 16))).Decode(&u); err == nil {
+			t.Error("expected an error decoding an array into a type implementing encoding.TextUnmarshaler")
+		}
+	})
+
+	t.Run("binary", func(t *testing.T) {
+		var u binaryUUID
+		if err := NewDecoder(NewValueParser(expect[:])).Decode(&u); err != nil {
+			t.Fatal(err)
+		}
+		if uuid(u) != expect {
+			t.Errorf("%x != %x", u, expect)
+		}
+
+		u = binaryUUID{}
+		if err := NewDecoder(NewValueParser(s)).Decode(&u); err != nil {
+			t.Fatal(err)
+		}
+		if uuid(u) != expect {
+			t.Errorf("%x != %x", u, expect)
+		}
+	})
+
+	t.Run("encode", func(t *testing.T) {
+		e := NewValueEmitter()
+		if err := NewEncoder(e).Encode(expect); err != nil {
+			t.Fatal(err)
+		}
+		if v := e.Value(); v != s {
+			t.Errorf("%#v != %#v", v, s)
+		}
+	})
+}
+
+func TestDecoderHooks(t *testing.T) {
+	type Address struct {
+		City string
+	}
+
+	type T struct {
+		Name    string
+		Address Address
+		Tags    map[string]string
+		Age     int
+	}
+
+	in := map[string]interface{}{
+		"Name":    "  Luke ",
+		"Address": map[string]interface{}{"City": "\tTatooine\n"},
+		"Tags":    map[string]interface{}{" side ": " light "},
+		"Age":     19,
+	}
+
+	t.Run("StringHook", func(t *testing.T) {
+		var v T
+		d := Decoder{
+			Parser: NewValueParser(in),
+			StringHook: func(b []byte) ([]byte, error) {
+				return bytes.TrimSpace(b), nil
+			},
+		}
+
+		if err := d.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+
+		expect := T{
+			Name:    "Luke",
+			Address: Address{City: "Tatooine"},
+			Tags:    map[string]string{"side": "light"},
+			Age:     19,
+		}
+
+		if !reflect.DeepEqual(v, expect) {
+			t.Errorf("%#v != %#v", v, expect)
+		}
+	})
+
+	t.Run("ValueHook", func(t *testing.T) {
+		var v T
+		var types []Type
+
+		d := Decoder{
+			Parser: NewValueParser(in),
+			ValueHook: func(t Type, v reflect.Value) error {
+				types = append(types, t)
+				if v.Kind() == reflect.Int && v.Int() < 21 {
+					return fmt.Errorf("too young: %d", v.Int())
+				}
+				return nil
+			},
+		}
+
+		if err := d.Decode(&v); err == nil || !strings.Contains(err.Error(), "too young: 19") {
+			t.Errorf("bad error: %v", err)
+		}
+
+		if len(types) == 0 {
+			t.Error("the value hook was never called")
+		}
+	})
+
+	t.Run("ValueHookFastPaths", func(t *testing.T) {
+		upper := func(t Type, v reflect.Value) error {
+			if v.Kind() == reflect.String && v.CanSet() {
+				v.SetString(strings.ToUpper(v.String()))
+			}
+			if v.Kind() == reflect.Int && v.Int() < 0 {
+				return fmt.Errorf("negative: %d", v.Int())
+			}
+			return nil
+		}
+
+		var ms map[string]string
+		var mi map[string]int
+		var mx map[string]interface{}
+		var x interface{}
+
+		tests := []struct {
+			in     interface{}
+			to     interface{}
+			expect interface{}
+		}{
+			{map[string]interface{}{"a": "b"}, &ms, map[string]string{"A": "B"}},
+			{map[string]interface{}{"a": 1}, &mi, map[string]int{"A": 1}},
+			{map[string]interface{}{"a": "b"}, &mx, map[string]interface{}{"A": "B"}},
+			{"b", &x, "B"},
+		}
+
+		for _, test := range tests {
+			d := Decoder{Parser: NewValueParser(test.in), ValueHook: upper}
+
+			if err := d.Decode(test.to); err != nil {
+				t.Fatal(err)
+			}
+
+			if v := reflect.ValueOf(test.to).Elem().Interface(); !reflect.DeepEqual(v, test.expect) {
+				t.Errorf("%#v != %#v", v, test.expect)
+			}
+		}
+
+		d := Decoder{Parser: NewValueParser(map[string]interface{}{"a": -1}), ValueHook: upper}
+
+		if err := d.Decode(&mi); err == nil || !strings.Contains(err.Error(), "negative: -1") {
+			t.Errorf("bad error: %v", err)
+		}
+	})
+
+	t.Run("ReplayedValues", func(t *testing.T) {
+		type WithPath struct {
+			Name string
+			City string `objconv:"City,jsonpath=/Address/City"`
+		}
+
+		type WithErrors struct {
+			Name   string
+			Errors FieldResult `objconv:",fielderrors"`
+		}
+
+		type WithShape struct {
+			Shape testShape
+		}
+
+		reg := newTestTypeRegistry()
+		reg.Key = "Type"
+
+		var lazy LazyValue
+		var name string
+
+		tests := []struct {
+			name   string
+			in     interface{}
+			to     interface{}
+			expect interface{}
+		}{
+			{
+				name:   "jsonpath",
+				in:     map[string]interface{}{"Name": "bob", "Address": map[string]interface{}{"City": "paris"}},
+				to:     &WithPath{},
+				expect: &WithPath{Name: "bob!", City: "paris!"},
+			},
+			{
+				name:   "fielderrors",
+				in:     map[string]interface{}{"Name": "bob"},
+				to:     &WithErrors{},
+				expect: &WithErrors{Name: "bob!"},
+			},
+			{
+				name:   "registry",
+				in:     map[string]interface{}{"Shape": map[string]interface{}{"Type": "circle", "R": 1}},
+				to:     &WithShape{},
+				expect: &WithShape{Shape: testCircle{Kind: "circle", R: 2}},
+			},
+			{
+				name:   "lazy",
+				in:     "bob",
+				to:     &lazy,
+				expect: "bob!",
+			},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				d := Decoder{
+					Parser:   NewValueParser(test.in),
+					Registry: reg,
+					StringHook: func(b []byte) ([]byte, error) {
+						if len(b) != 0 && b[0] >= 'a' && b[0] <= 'z' { // values only
+							b = append(b, '!')
+						}
+						return b, nil
+					},
+					ValueHook: func(t Type, v reflect.Value) error {
+						if v.Kind() == reflect.Float64 && v.CanSet() {
+							v.SetFloat(v.Float() + 1)
+						}
+						return nil
+					},
+				}
+
+				if err := d.Decode(test.to); err != nil {
+					t.Fatal(err)
+				}
+
+				var v interface{} = test.to
+
+				if test.to == &lazy {
+					if err := lazy.Decode(&name); err != nil {
+						t.Fatal(err)
+					}
+					v = name
+				}
+
+				if !reflect.DeepEqual(v, test.expect) {
+					t.Errorf("the hooks were not called exactly once: %#v != %#v", v, test.expect)
+				}
+			})
+		}
+	})
+}
+
+func TestDecoderTimeUnit(t *testing.T) {
+	date := time.Date(2017, 7, 14, 2, 40, 0, 0, time.UTC)
+
+	tests := []struct {
+		unit time.Duration
+		in   interface{}
+		out  time.Time
+	}{
+		{0, int64(1500000000), date},
+		{time.Second, uint64(1500000000), date},
+		{time.Second, 1500000000.25, date.Add(250 * time.Millisecond)},
+		{time.Millisecond, int64(1500000000123), date.Add(123 * time.Millisecond)},
+		{time.Millisecond, -int64(1500), time.Unix(-2, 500*int64(time.Millisecond)).UTC()},
+		{time.Microsecond, int64(1500000000000123), date.Add(123 * time.Microsecond)},
+		{time.Nanosecond, int64(1500000000000000123), date.Add(123)},
+		{time.Minute, int64(25000000), date},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprint(test.unit, "/", test.in), func(t *testing.T) {
+			var v time.Time
+			d := Decoder{Parser: NewValueParser(test.in), TimeUnit: test.unit}
+
+			if err := d.Decode(&v); err != nil {
+				t.Fatal(err)
+			}
+
+			if !v.Equal(test.out) {
+				t.Errorf("%s != %s", v, test.out)
+			}
+
+			if v.Location() != time.UTC {
+				t.Errorf("the time must be in UTC but is in %s", v.Location())
+			}
+		})
+	}
+
+	if err := NewDecoder(NewValueParser(true)).Decode(new(time.Time)); err == nil {
+		t.Error("expected an error decoding a boolean into a time.Time")
+	}
+}
+
+type linkedNode struct {
+	Value int
+	Next  *linkedNode
+}
+
+type recursiveSlice []recursiveSlice
+
+type recursiveMap map[string]recursiveMap
+
+func TestDecoderRecursiveTypes(t *testing.T) {
+	t.Run("linked-list", func(t *testing.T) {
+		list := &linkedNode{Value: 1, Next: &linkedNode{Value: 2, Next: &linkedNode{Value: 3}}}
+
+		var v struct {
+			List *linkedNode
+		}
+		if err := NewDecoder(NewValueParser(map[string]interface{}{
+			"List": map[string]interface{}{
+				"Value": 1,
+				"Next": map[string]interface{}{
+					"Value": 2,
+					"Next": map[string]interface{}{
+						"Value": 3,
+						"Next":  nil,
+					},
+				},
+			},
+		})).Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(v.List, list) {
+			t.Errorf("%#v != %#v", v.List, list)
+		}
+	})
+
+	t.Run("slices-and-maps", func(t *testing.T) {
+		type T struct {
+			S recursiveSlice
+			M recursiveMap
+		}
+
+		expect := T{
+			S: recursiveSlice{{}, {{}}},
+			M: recursiveMap{"A": {"B": {}}},
+		}
+
+		e := NewValueEmitter()
+		if err := NewEncoder(e).Encode(expect); err != nil {
+			t.Fatal(err)
+		}
+
+		var v T
+		if err := NewDecoder(NewValueParser(e.Value())).Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(v, expect) {
+			t.Errorf("%#v != %#v", v, expect)
+		}
+	})
+}
+
+// peekedValue decodes either a list of strings or a single string, it uses
+// PeekType to tell them apart.
+type peekedValue struct {
+	list []string
+}
+
+func (v *peekedValue) DecodeValue(d Decoder) error {
+	t, err := d.PeekType()
+	if err != nil {
+		return err
+	}
+	if t == Array {
+		return d.Decode(&v.list)
+	}
+	var s string
+	err = d.Decode(&s)
+	v.list = []string{s}
+	return err
+}
+
+func TestDecoderPeekType(t *testing.T) {
+	var v struct {
+		A peekedValue
+		B peekedValue
+	}
+
+	if err := NewDecoder(NewValueParser(map[string]interface{}{
+		"A": "hello",
+		"B": []string{"hello", "world"},
+	})).Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(v.A.list, []string{"hello"}) {
+		t.Errorf("bad A: %#v", v.A.list)
+	}
+
+	if !reflect.DeepEqual(v.B.list, []string{"hello", "world"}) {
+		t.Errorf("bad B: %#v", v.B.list)
+	}
+}
+
+// positionParser reports the number of strings parsed as its position, on the
+// first line of the input.
+type positionParser struct {
+	*ValueParser
+	n int
+}
+
+func (p *positionParser) ParseString() ([]byte, error) { p.n++; return p.ValueParser.ParseString() }
+
+func (p *positionParser) Position() (int, int, int) { return 1, p.n + 1, p.n }
+
+func TestDecoderPositionError(t *testing.T) {
+	var v struct{ A int }
+	var e *PositionError
+	var de *DecodeError
+
+	p := &positionParser{ValueParser: NewValueParser(map[string]interface{}{"A": "x"})}
+	err := NewDecoder(p).Decode(&v)
+
+	if !errors.As(err, &e) || !errors.As(e.Err, &de) || de.Path != "A" {
+		t.Fatalf("bad error: %#v", err)
+	}
+
+	if e.Line != 1 || e.Column != 3 || e.Offset != 2 {
+		t.Errorf("bad position: %d:%d (%d)", e.Line, e.Column, e.Offset)
+	}
+
+	if !strings.HasPrefix(err.Error(), "line 1, column 3 (offset 2): A: ") {
+		t.Errorf("bad error message: %s", err)
+	}
+
+	if err := NewDecoder(NewValueParser(map[string]interface{}{"A": "x"})).Decode(&v); err == nil || errors.As(err, &e) {
+		t.Errorf("errors of parsers which don't implement PositionParser must not be annotated: %v", err)
+	}
+
+	p = &positionParser{ValueParser: NewValueParser(map[string]interface{}{"A": "x"})}
+	err = NewDecoder(p).DecodeReflect(reflect.ValueOf(&v).Elem())
+
+	if !errors.As(err, &e) || e.Offset != 2 {
+		t.Errorf("bad error from DecodeReflect: %#v", err)
+	}
+}
+
+func TestDecoderRegexp(t *testing.T) {
+	type T struct {
+		R *regexp.Regexp
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		var v T
+		if err := NewDecoder(NewValueParser(map[string]interface{}{"R": `^a+b$`})).Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+		if v.R == nil || v.R.String() != `^a+b$` || !v.R.MatchString("aab") {
+			t.Errorf("bad regexp: %v", v.R)
+		}
+
+		e := NewValueEmitter()
+		if err := NewEncoder(e).Encode(v); err != nil {
+			t.Fatal(err)
+		}
+		if m := e.Value().(map[interface{}]interface{}); m["R"] != `^a+b$` {
+			t.Errorf("bad encoded value: %#v", m)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		var v T
+		err := NewDecoder(NewValueParser(map[string]interface{}{"R": `a(b`})).Decode(&v)
+		if err == nil {
+			t.Fatal("expected an error decoding an invalid regular expression")
+		}
+		if !strings.Contains(err.Error(), "R") {
+			t.Errorf("the error doesn't mention the field: %s", err)
+		}
+	})
+
+	t.Run("null", func(t *testing.T) {
+		v := T{R: regexp.MustCompile(`x`)}
+		if err := NewDecoder(NewValueParser(map[string]interface{}{"R": nil})).Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+		if v.R != nil {
+			t.Errorf("expected a nil regexp but got %v", v.R)
+		}
+	})
+
+	t.Run("posix", func(t *testing.T) {
+		var v T
+		d := Decoder{Parser: NewValueParser(map[string]interface{}{"R": `a|ab`}), RegexpPOSIX: true}
+		if err := d.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+		if s := v.R.FindString("ab"); s != "ab" {
+			t.Errorf("expected leftmost-longest matching but got %q", s)
+		}
+
+		d = Decoder{Parser: NewValueParser(map[string]interface{}{"R": `\d`}), RegexpPOSIX: true}
+		if err := d.Decode(&v); err == nil {
+			t.Error("expected an error decoding a pattern which isn't valid POSIX syntax")
+		}
+	})
+}
+
+func TestDecoderAtomicValue(t *testing.T) {
+	var a atomic.Value
+
+	if err := NewDecoder(NewValueParser(nil)).Decode(&a); err != nil || a.Load() != nil {
+		t.Errorf("decoding null must leave the value unset: %#v (%v)", a.Load(), err)
+	}
+
+	if err := NewDecoder(NewValueParser(true)).Decode(&a); err != nil || a.Load() != true {
+		t.Errorf("bad value: %#v (%v)", a.Load(), err)
+	}
+
+	const expect = "objconv: cannot store a value of type string in an atomic.Value holding a value of type bool"
+	if err := NewDecoder(NewValueParser("A")).Decode(&a); err == nil || err.Error() != expect {
+		t.Errorf("bad error: %v", err)
+	}
+}
+
+type flatStruct struct {
+	A bool
+	B int
+	C int8
+	D int64
+	E uint
+	F uint32
+	G float32
+	H float64
+	I string
+	J string
+}
+
+// tokenParser replays a map of scalar values from a list of tokens without
+// allocating memory, so benchmarks measure the decoder and not the parser.
+type tokenParser struct {
+	*ValueParser
+	tokens []interface{} // map length, then alternating keys and values
+	i      int
+}
+
+func (p *tokenParser) next() (v interface{}) {
+	v, p.i = p.tokens[p.i], p.i+1
+	return
+}
+
+func (p *tokenParser) ParseType() (Type, error) {
+	if p.i == 0 {
+		return Map, nil
+	}
+	switch p.tokens[p.i].(type) {
+	case bool:
+		return Bool, nil
+	case int64:
+		return Int, nil
+	case uint64:
+		return Uint, nil
+	case float64:
+		return Float, nil
+	case tokenBytes:
+		return Bytes, nil
+	default:
+		return String, nil
+	}
+}
+
+func (p *tokenParser) ParseMapBegin() (int, error)  { return p.next().(int), nil }
+func (p *tokenParser) ParseMapEnd(int) error        { return nil }
+func (p *tokenParser) ParseMapNext(int) error       { return nil }
+func (p *tokenParser) ParseMapValue(int) error      { return nil }
+func (p *tokenParser) ParseBool() (bool, error)     { return p.next().(bool), nil }
+func (p *tokenParser) ParseInt() (int64, error)     { return p.next().(int64), nil }
+func (p *tokenParser) ParseUint() (uint64, error)   { return p.next().(uint64), nil }
+func (p *tokenParser) ParseFloat() (float64, error) { return p.next().(float64), nil }
+func (p *tokenParser) ParseString() ([]byte, error) { return p.next().([]byte), nil }
+func (p *tokenParser) ParseBytes() ([]byte, error)  { return p.next().(tokenBytes), nil }
+
+// tokenBytes is the type of tokens that tokenParser returns as Bytes.
+type tokenBytes []byte
+
+func BenchmarkDecoderFlatStruct(b *testing.B) {
+	p := &tokenParser{tokens: []interface{}{
+		10,
+		[]byte("A"), true,
+		[]byte("B"), int64(1),
+		[]byte("C"), int64(2),
+		[]byte("D"), int64(3),
+		[]byte("E"), uint64(4),
+		[]byte("F"), uint64(5),
+		[]byte("G"), float64(6),
+		[]byte("H"), float64(7.5),
+		[]byte("I"), []byte("hello"),
+		[]byte("J"), []byte("world"),
+	}}
+
+	s := structCache.lookup(reflect.TypeOf(flatStruct{}))
+
+	if !s.flat {
+		b.Fatal("the struct was expected to be flat")
+	}
+
+	for _, bench := range []struct {
+		name   string
+		decode func(Decoder, Type, reflect.Value, *structType) error
+	}{
+		{"flat", Decoder.decodeFlatStruct},
+		{"generic", Decoder.decodeStructFields},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			var v flatStruct
+			to := reflect.ValueOf(&v).Elem()
+			d := Decoder{Parser: p}
+			b.ReportAllocs()
+
+			for i := 0; i != b.N; i++ {
+				p.i = 0
+
+				if err := bench.decode(d, Map, to, s); err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			if v.J != "world" {
+				b.Errorf("bad value: %#v", v)
+			}
+		})
+	}
+}
+
+func TestDecoderBytesMapKeys(t *testing.T) {
+	tokens := []interface{}{
+		2,
+		tokenBytes("A"), []byte("1"),
+		tokenBytes("B"), tokenBytes("2"),
+	}
+
+	t.Run("map[string]string", func(t *testing.T) {
+		var m map[string]string
+		if err := NewDecoder(&tokenParser{tokens: tokens}).Decode(&m); err != nil {
+			t.Fatal(err)
+		}
+		if expect := map[string]string{"A": "1", "B": "2"}; !reflect.DeepEqual(m, expect) {
+			t.Errorf("%#v != %#v", m, expect)
+		}
+	})
+
+	t.Run("map[string]interface{}", func(t *testing.T) {
+		var m map[string]interface{}
+		if err := NewDecoder(&tokenParser{tokens: tokens}).Decode(&m); err != nil {
+			t.Fatal(err)
+		}
+		if expect := map[string]interface{}{"A": "1", "B": []byte("2")}; !reflect.DeepEqual(m, expect) {
+			t.Errorf("%#v != %#v", m, expect)
+		}
+	})
+
+	t.Run("map[string]int", func(t *testing.T) {
+		var m map[string]int
+		if err := NewDecoder(&tokenParser{tokens: []interface{}{1, tokenBytes("A"), int64(1)}}).Decode(&m); err != nil {
+			t.Fatal(err)
+		}
+		if expect := map[string]int{"A": 1}; !reflect.DeepEqual(m, expect) {
+			t.Errorf("%#v != %#v", m, expect)
+		}
+	})
+
+	t.Run("struct", func(t *testing.T) {
+		var v struct{ A, B string }
+		if err := NewDecoder(&tokenParser{tokens: tokens}).Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+		if v.A != "1" || v.B != "2" {
+			t.Errorf("%#v", v)
+		}
+	})
+}
@@ -4,7 +4,12 @@ import (
 	"encoding"
 	"fmt"
 	"io"
+	"net"
+	"net/url"
 	"reflect"
+	"regexp"
+	"sort"
+	"sync/atomic"
 	"time"
 	"unsafe"
 )
@@ -328,6 +333,69 @@ func (e Encoder) encodeTime(v reflect.Value) error {
 	return e.Emitter.EmitTime(t)
 }
 
+func (e Encoder) encodeIPNet(v reflect.Value) error {
+	var n *net.IPNet
+
+	// Like for time.Time, both the pointer and the plain value are handled
+	// here, net.IPNet has a String method but isn't an encoding.TextMarshaler
+	// so it would otherwise be encoded as a struct.
+	if v.Kind() != reflect.Ptr {
+		x := v.Interface().(net.IPNet)
+		n = &x
+	} else if n = v.Interface().(*net.IPNet); n == nil {
+		return e.Emitter.EmitNil()
+	}
+
+	if n.IP == nil && n.Mask == nil {
+		return e.Emitter.EmitString("")
+	}
+
+	return e.Emitter.EmitString(n.String())
+}
+
+func (e Encoder) encodeURL(v reflect.Value) error {
+	var u *url.URL
+
+	// *url.URL implements encoding.BinaryMarshaler, url.URL nothing, the
+	// special case makes both be encoded as strings.
+	if v.Kind() != reflect.Ptr {
+		x := v.Interface().(url.URL)
+		u = &x
+	} else if u = v.Interface().(*url.URL); u == nil {
+		return e.Emitter.EmitNil()
+	}
+
+	return e.Emitter.EmitString(u.String())
+}
+
+func (e Encoder) encodeRegexp(v reflect.Value) error {
+	r := v.Interface().(*regexp.Regexp)
+	if r == nil {
+		return e.Emitter.EmitNil()
+	}
+	return e.Emitter.EmitString(r.String())
+}
+
+// The atomic types are encoded as the values that they hold, which are loaded
+// atomically. The values are addressable when they are found in structs or
+// behind pointers, which is how atomic types are used since they must not be
+// copied.
+
+func (e Encoder) encodeAtomicValue(v reflect.Value) error {
+	return e.Encode(atomicPtr(v).(*atomic.Value).Load())
+}
+
+// atomicPtr returns a pointer to v, which is a copy of the value when v is not
+// addressable.
+func atomicPtr(v reflect.Value) interface{} {
+	if !v.CanAddr() {
+		p := reflect.New(v.Type())
+		p.Elem().Set(v)
+		return p.Interface()
+	}
+	return v.Addr().Interface()
+}
+
 func (e Encoder) encodeDuration(v reflect.Value) error {
 	return e.Emitter.EmitDuration(time.Duration(v.Int()))
 }
@@ -521,11 +589,18 @@ func (e Encoder) encodeStruct(v reflect.Value) error {
 }
 
 func (e Encoder) encodeStructWith(v reflect.Value, s *structType) (err error) {
-	n := 0
+	var extraMap map[string]interface{}
+	var extra []string
+
+	if s.extra != nil {
+		extraMap, extra = e.extraKeys(v, s)
+	}
+
+	n := len(extra)
 
 	for i := range s.fields {
 		f := &s.fields[i]
-		if !f.omit(v.FieldByIndex(f.index)) {
+		if !f.decodeOnly() && !f.omit(v.FieldByIndex(f.index)) {
 			n++
 		}
 	}
@@ -537,6 +612,9 @@ func (e Encoder) encodeStructWith(v reflect.Value, s *structType) (err error) {
 
 	for i := range s.fields {
 		f := &s.fields[i]
+		if f.decodeOnly() {
+			continue
+		}
 		if fv := v.FieldByIndex(f.index); !f.omit(fv) {
 			if n != 0 {
 				if err = e.Emitter.EmitMapNext(); err != nil {
@@ -556,9 +634,48 @@ func (e Encoder) encodeStructWith(v reflect.Value, s *structType) (err error) {
 		}
 	}
 
+	for _, k := range extra {
+		if n != 0 {
+			if err = e.Emitter.EmitMapNext(); err != nil {
+				return
+			}
+		}
+		if err = e.Emitter.EmitString(k); err != nil {
+			return
+		}
+		if err = e.Emitter.EmitMapValue(); err != nil {
+			return
+		}
+		if err = e.Encode(extraMap[k]); err != nil {
+			return
+		}
+		n++
+	}
+
 	return e.Emitter.EmitMapEnd()
 }
 
+// extraKeys returns the keys of the extra map of a struct that are not shadowed
+// by one of its encoded fields.
+func (e Encoder) extraKeys(v reflect.Value, s *structType) (m map[string]interface{}, keys []string) {
+	fv, ok := lookupFieldByIndex(v, s.extra)
+	if !ok {
+		return
+	}
+	m, _ = fv.Interface().(map[string]interface{})
+
+	for k := range m {
+		if f := s.fieldsByName[k]; f == nil || f.decodeOnly() {
+			keys = append(keys, k)
+		}
+	}
+
+	if e.SortMapKeys {
+		sort.Strings(keys)
+	}
+	return
+}
+
 func (e Encoder) encodePointer(v reflect.Value) error {
 	return e.encodePointerWith(v, encodeFuncOf(v.Type().Elem()))
 }
@@ -815,8 +932,22 @@ func (f ValueEncoderFunc) EncodeValue(e Encoder) error { return f(e) }
 type encodeFuncOpts struct {
 	recurse bool
 	structs map[reflect.Type]*structType
+	types   map[reflect.Type]bool // see decodeFuncOpts
 }
 
+func (opts *encodeFuncOpts) enter(t reflect.Type) bool {
+	if opts.types[t] {
+		return false
+	}
+	if opts.types == nil {
+		opts.types = make(map[reflect.Type]bool)
+	}
+	opts.types[t] = true
+	return true
+}
+
+func (opts encodeFuncOpts) leave(t reflect.Type) { delete(opts.types, t) }
+
 // encodeFunc is the prototype of functions that encode values.
 type encodeFunc func(Encoder, reflect.Value) error
 
@@ -830,6 +961,10 @@ func makeEncodeFunc(t reflect.Type, opts encodeFuncOpts) encodeFunc {
 		return adapter.Encode
 	}
 
+	if f := makeAtomicEncodeFunc(t); f != nil {
+		return f
+	}
+
 	switch t {
 	case boolType:
 		return Encoder.encodeBool
@@ -843,6 +978,18 @@ func makeEncodeFunc(t reflect.Type, opts encodeFuncOpts) encodeFunc {
 	case timeType, timePtrType:
 		return Encoder.encodeTime
 
+	case ipNetType, ipNetPtrType:
+		return Encoder.encodeIPNet
+
+	case urlType, urlPtrType:
+		return Encoder.encodeURL
+
+	case regexpPtrType:
+		return Encoder.encodeRegexp
+
+	case atomicValueType:
+		return Encoder.encodeAtomicValue
+
 	case durationType:
 		return Encoder.encodeDuration
 
@@ -976,9 +1123,10 @@ func makeEncodeFunc(t reflect.Type, opts encodeFuncOpts) encodeFunc {
 }
 
 func makeEncodeArrayFunc(t reflect.Type, opts encodeFuncOpts) encodeFunc {
-	if !opts.recurse {
+	if !opts.recurse || !opts.enter(t) {
 		return Encoder.encodeArray
 	}
+	defer opts.leave(t)
 	f := makeEncodeFunc(t.Elem(), opts)
 	return func(e Encoder, v reflect.Value) error {
 		return e.encodeArrayWith(v, f)
@@ -986,9 +1134,10 @@ func makeEncodeArrayFunc(t reflect.Type, opts encodeFuncOpts) encodeFunc {
 }
 
 func makeEncodeMapFunc(t reflect.Type, opts encodeFuncOpts) encodeFunc {
-	if !opts.recurse {
+	if !opts.recurse || !opts.enter(t) {
 		return Encoder.encodeMap
 	}
+	defer opts.leave(t)
 	kf := makeEncodeFunc(t.Key(), opts)
 	vf := makeEncodeFunc(t.Elem(), opts)
 	return func(e Encoder, v reflect.Value) error {
@@ -1007,9 +1156,10 @@ func makeEncodeStructFunc(t reflect.Type, opts encodeFuncOpts) encodeFunc {
 }
 
 func makeEncodePtrFunc(t reflect.Type, opts encodeFuncOpts) encodeFunc {
-	if !opts.recurse {
+	if !opts.recurse || !opts.enter(t) {
 		return Encoder.encodePointer
 	}
+	defer opts.leave(t)
 	f := makeEncodeFunc(t.Elem(), opts)
 	return func(e Encoder, v reflect.Value) error {
 		return e.encodePointerWith(v, f)
@@ -0,0 +1,173 @@
+package objconv
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Enum describes the set of values accepted when decoding a string type, it is
+// associated with a type by calling InstallEnum.
+type Enum struct {
+	// Values is the list of canonical values of the enum.
+	Values []string
+
+	// IgnoreCase makes the decoder match strings with the values of the enum
+	// regardless of their case.
+	IgnoreCase bool
+
+	// Normalize, when not nil, is applied to the decoded strings and to the
+	// values of the enum before comparing them. It takes precedence over
+	// IgnoreCase.
+	Normalize func(string) string
+
+	// KeepInput makes the decoder store strings the way they were received
+	// instead of the canonical value that they matched.
+	KeepInput bool
+
+	// AllowUnknown makes the decoder accept strings that don't match any value
+	// of the enum and store them as-is, instead of returning an error.
+	AllowUnknown bool
+}
+
+func (e *Enum) normalize(s string) string {
+	switch {
+	case e.Normalize != nil:
+		return e.Normalize(s)
+	case e.IgnoreCase:
+		return strings.ToLower(s)
+	default:
+		return s
+	}
+}
+
+// enumType is the internal representation of an installed enum, it caches the
+// canonical values by their normalized form.
+type enumType struct {
+	Enum
+	values map[string]string
+}
+
+func (e *enumType) decode(d Decoder, to reflect.Value) (t Type, err error) {
+	var s string
+
+	if t, err = d.decodeString(reflect.ValueOf(&s).Elem()); err != nil || t == Nil {
+		if err == nil && to.IsValid() {
+			to.SetString("")
+		}
+		return
+	}
+
+	v, ok := e.values[e.normalize(s)]
+
+	switch {
+	case !ok && !e.AllowUnknown:
+		err = fmt.Errorf("objconv: %q is not a valid value of %s", s, to.Type())
+		return
+	case !ok || e.KeepInput:
+		v = s
+	}
+
+	if to.IsValid() {
+		to.SetString(v)
+	}
+	return
+}
+
+// InstallEnum associates enum with typ, which must be a type of the string
+// kind. Decoders then validate that the strings they decode into values of
+// typ match one of the values of the enum.
+//
+// Like Install, this function is expected to be called during the package
+// initialization phase.
+func InstallEnum(typ reflect.Type, enum Enum) {
+	if typ.Kind() != reflect.String {
+		panic("objconv: enums can only be installed on string types, found " + typ.String())
+	}
+
+	e := &enumType{
+		Enum:   enum,
+		values: make(map[string]string, len(enum.Values)),
+	}
+
+	for _, v := range enum.Values {
+		e.values[e.normalize(v)] = v
+	}
+
+	enumMutex.Lock()
+	enumStore[typ] = e
+	enumMutex.Unlock()
+
+	// See Install for why it is acceptable to clear the caches here.
+	structCache.clear()
+	clearDecodeFuncCache()
+}
+
+// EnumOf returns the enum installed for typ, setting ok to true if one was
+// found, false otherwise.
+func EnumOf(typ reflect.Type) (enum Enum, ok bool) {
+	var e *enumType
+	if e, ok = enumOf(typ); ok {
+		enum = e.Enum
+	}
+	return
+}
+
+func enumOf(typ reflect.Type) (e *enumType, ok bool) {
+	enumMutex.RLock()
+	e, ok = enumStore[typ]
+	enumMutex.RUnlock()
+	return
+}
+
+// makeDecodeEnumParserFunc returns the decode function of the named type t, of
+// a boolean, numeric or string kind, which decodes strings with the function
+// of the EnumParser map of the decoder registered for t, if any, and decodes
+// the other values with f.
+func makeDecodeEnumParserFunc(t reflect.Type, f decodeFunc) decodeFunc {
+	return func(d Decoder, to reflect.Value) (Type, error) {
+		if parse, ok := d.EnumParser[t]; ok {
+			return d.decodeEnumParser(t, to, parse, f)
+		}
+		return f(d, to)
+	}
+}
+
+func (d Decoder) decodeEnumParser(typ reflect.Type, to reflect.Value, parse func(string) (interface{}, error), f decodeFunc) (t Type, err error) {
+	if t, err = d.Parser.ParseType(); err != nil {
+		return
+	}
+
+	if t != String && t != Bytes {
+		return f(d, to)
+	}
+
+	var b []byte
+	var v interface{}
+
+	if _, b, err = d.decodeTypeAndString(); err != nil {
+		return
+	}
+
+	if v, err = parse(string(b)); err != nil {
+		return
+	}
+
+	x := reflect.ValueOf(v)
+
+	if !x.IsValid() || x.Type() != typ {
+		err = fmt.Errorf("objconv: the enum parser of %s returned a value of type %T", typ, v)
+		return
+	}
+
+	if to.IsValid() {
+		to.Set(x)
+	}
+	return
+}
+
+var (
+	enumMutex sync.RWMutex
+	enumStore = make(map[reflect.Type]*enumType)
+)
@@ -0,0 +1,159 @@
+package objconv
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testStatus string
+type testStatusRaw string
+type testStatusOpen string
+
+type testColor int
+type testColorBroken int
+
+const (
+	testRed testColor = iota
+	testGreen
+	testBlue
+)
+
+func parseTestColor(s string) (testColor, error) {
+	switch s {
+	case "red":
+		return testRed, nil
+	case "green":
+		return testGreen, nil
+	case "blue":
+		return testBlue, nil
+	default:
+		return 0, fmt.Errorf("invalid color: %q", s)
+	}
+}
+
+func init() {
+	values := []string{"Active", "Inactive"}
+	InstallEnum(reflect.TypeOf(testStatus("")), Enum{Values: values, IgnoreCase: true})
+	InstallEnum(reflect.TypeOf(testStatusRaw("")), Enum{Values: values, IgnoreCase: true, KeepInput: true})
+	InstallEnum(reflect.TypeOf(testStatusOpen("")), Enum{
+		Values:       values,
+		Normalize:    func(s string) string { return strings.ToLower(strings.TrimSpace(s)) },
+		AllowUnknown: true,
+	})
+}
+
+func TestDecoderEnum(t *testing.T) {
+	type T struct {
+		A testStatus
+		B testStatusRaw
+		C testStatusOpen
+		D testStatusOpen
+	}
+
+	var v T
+	d := NewDecoder(NewValueParser(map[string]interface{}{
+		"A": "ACTIVE",
+		"B": "inactive",
+		"C": " inactive ",
+		"D": "pending",
+	}))
+
+	if err := d.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := T{A: "Active", B: "inactive", C: "Inactive", D: "pending"}
+
+	if v != expect {
+		t.Errorf("%#v != %#v", v, expect)
+	}
+}
+
+func TestDecoderEnumInvalidValue(t *testing.T) {
+	var v testStatus
+
+	err := NewDecoder(NewValueParser("pending")).Decode(&v)
+	if err == nil {
+		t.Fatal("expected an error decoding an invalid enum value")
+	}
+
+	const expect = `objconv: "pending" is not a valid value of objconv.testStatus`
+	if err.Error() != expect {
+		t.Errorf("%s != %s", err, expect)
+	}
+}
+
+func TestDecoderEnumParser(t *testing.T) {
+	enums := map[reflect.Type]func(string) (interface{}, error){
+		reflect.TypeOf(testColor(0)):       func(s string) (interface{}, error) { return parseTestColor(s) },
+		reflect.TypeOf(testColorBroken(0)): func(s string) (interface{}, error) { return 1, nil },
+	}
+
+	type T struct {
+		A testColor
+		B testColor
+		C testColor
+		D *testColor
+	}
+
+	var v T
+	d := Decoder{
+		Parser: NewValueParser(map[string]interface{}{
+			"A": "red",
+			"B": []byte("blue"),
+			"C": 1,
+			"D": "green",
+		}),
+		EnumParser: enums,
+	}
+
+	if err := d.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	if v.A != testRed || v.B != testBlue || v.C != testGreen || v.D == nil || *v.D != testGreen {
+		t.Errorf("bad value: %#v", v)
+	}
+
+	t.Run("flat", func(t *testing.T) {
+		var v struct {
+			A testColor
+			B int
+		}
+
+		d := Decoder{Parser: NewValueParser(map[string]interface{}{"A": "blue", "B": 2}), EnumParser: enums}
+
+		if err := d.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+
+		if v.A != testBlue || v.B != 2 {
+			t.Errorf("bad value: %#v", v)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		var c testColor
+
+		if err := (Decoder{Parser: NewValueParser("purple"), EnumParser: enums}).Decode(&c); err == nil || err.Error() != `invalid color: "purple"` {
+			t.Errorf("bad error: %v", err)
+		}
+
+		var b testColorBroken
+
+		const expect = "objconv: the enum parser of objconv.testColorBroken returned a value of type int"
+		if err := (Decoder{Parser: NewValueParser("red"), EnumParser: enums}).Decode(&b); err == nil || err.Error() != expect {
+			t.Errorf("bad error: %v", err)
+		}
+	})
+
+	t.Run("not set", func(t *testing.T) {
+		var c testColor
+
+		if err := NewDecoder(NewValueParser("red")).Decode(&c); err == nil {
+			t.Errorf("decoders without enum parsers must not parse enums: %v", c)
+		}
+	})
+}
@@ -3,17 +3,165 @@ package objconv
 import (
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 )
 
 func typeConversionError(from Type, to Type) error {
 	return fmt.Errorf("objconv: cannot convert from %s to %s", from, to)
 }
 
+// DecodeError is returned by decoders when an error occurs while decoding a
+// value nested in an array, a map or a struct.
+type DecodeError struct {
+	// Path is the location of the value that failed to decode, in the form of
+	// struct field names or map keys separated by dots, and array indexes in
+	// brackets (for example users[3].address.zip).
+	Path string
+
+	// Err is the error that occurred while decoding the value.
+	Err error
+}
+
+// Error satisfies the error interface.
+func (e *DecodeError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// PositionError is returned by decoders which parser implements PositionParser,
+// it carries the position that the parser had reached in the input when the
+// error occurred, which is usually right after the value that caused it.
+type PositionError struct {
+	Line   int
+	Column int
+	Offset int
+
+	// Err is the error that occurred at this position.
+	Err error
+}
+
+// Error satisfies the error interface.
+func (e *PositionError) Error() string {
+	return fmt.Sprintf("line %d, column %d (offset %d): %s", e.Line, e.Column, e.Offset, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *PositionError) Unwrap() error {
+	return e.Err
+}
+
+// FieldResult is the type of struct fields tagged with `fielderrors`, which
+// receive the errors that occurred while decoding the other fields of the
+// struct, indexed by field name:
+//
+//	type Form struct {
+//		Name   string
+//		Age    int
+//		Errors objconv.FieldResult `objconv:",fielderrors"`
+//	}
+//
+// When a struct has such a field, an error decoding one of its fields does not
+// abort decoding the struct, the field is set to its zero value and the error
+// is recorded in the result instead. Fields that decoded successfully have no
+// entry in the result, which is nil when all fields were decoded.
+//
+// Errors that leave the parser in an undefined state, like syntax errors, are
+// still returned by the decoder.
+type FieldResult map[string]error
+
+// SkippedErrors collects the errors of array elements skipped by decoders, its
+// Skip method can be used as the ErrorHandler of a Decoder:
+//
+//	var errs objconv.SkippedErrors
+//	d := objconv.Decoder{Parser: p, ErrorHandler: errs.Skip}
+//
+// The errors are recorded with the index of the element prepended to their
+// path.
+type SkippedErrors []error
+
+// Skip records err and returns nil, instructing the decoder to skip the element.
+func (e *SkippedErrors) Skip(index int, err error) error {
+	*e = append(*e, decodeErrorWithIndex(err, index))
+	return nil
+}
+
+// Error satisfies the error interface.
+func (e SkippedErrors) Error() string {
+	s := make([]string, len(e))
+	for i, err := range e {
+		s[i] = err.Error()
+	}
+	return strings.Join(s, "; ")
+}
+
+// decodeErrorWithKey prepends a map key or struct field name to the path of
+// err.
+func decodeErrorWithKey(err error, key interface{}) error {
+	switch k := key.(type) {
+	case string:
+		return decodeErrorWithPath(err, k)
+	case []byte:
+		return decodeErrorWithPath(err, string(k))
+	default:
+		return decodeErrorWithPath(err, "["+fmt.Sprint(k)+"]")
+	}
+}
+
+// decodeErrorWithIndex prepends an array index to the path of err.
+func decodeErrorWithIndex(err error, index int) error {
+	return decodeErrorWithPath(err, "["+strconv.Itoa(index)+"]")
+}
+
+// positionError wraps err in a PositionError holding the position of p, unless
+// it is End or already carries a position.
+func positionError(err error, p PositionParser) error {
+	var e *PositionError
+
+	if err == End || errors.As(err, &e) {
+		return err
+	}
+
+	line, col, offset := p.Position()
+	return &PositionError{Line: line, Column: col, Offset: offset, Err: err}
+}
+
+func decodeErrorWithPath(err error, elem string) error {
+	if err == End {
+		// End is used to signal the end of streams, it must be returned as-is.
+		return err
+	}
+
+	e, ok := err.(*DecodeError)
+	if !ok {
+		return &DecodeError{Path: elem, Err: err}
+	}
+
+	if len(e.Path) != 0 && e.Path[0] != '[' {
+		elem += "."
+	}
+
+	e.Path = elem + e.Path
+	return e
+}
+
 var (
 	// End is expected to be returned to indicate that a function has completed
 	// its work, this is usually employed in generic algorithms.
 	End = errors.New("end")
 
+	// ErrIdleTimeout is returned by stream decoders when no value was received
+	// within their idle timeout.
+	ErrIdleTimeout = errors.New("objconv: the stream decoder timed out waiting for the next value")
+
+	// ErrEOFUnsupported is returned by the ParseEOF method of parsers which
+	// are not able to detect the end of their input.
+	ErrEOFUnsupported = errors.New("objconv: the parser cannot detect the end of its input")
+
 	// This error value is used as a building block for reflection and is never
 	// returned by the package.
 	errBase = errors.New("")
@@ -1,3 +1,7 @@
 module github.com/segmentio/objconv
 
+go 1.27.1
+
 require gopkg.in/yaml.v2 v2.2.1
+
+require gopkg.in/check.v1 v0.0.0-20161208181325-20d25e280405 // indirect
@@ -19,6 +19,8 @@ import (
 	"strings"
 	"sync"
 	"testing"
+
+	"github.com/segmentio/objconv"
 )
 
 type codeResponse struct {
@@ -184,6 +186,94 @@ func BenchmarkUnmarshalInt64(b *testing.B) {
 	}
 }
 
+func BenchmarkUnmarshalMapStringInt(b *testing.B) {
+	// The named type is not matched by the fast path of map[string]int, which
+	// gives a baseline for the generic map decoding algorithm.
+	type slowMap map[string]int
+
+	data := []byte(`{"a":1,"b":2,"c":3,"d":4,"e":5,"f":6,"g":7,"h":8}`)
+
+	b.Run("fast", func(b *testing.B) {
+		b.ReportAllocs()
+		m := map[string]int{}
+		for i := 0; i < b.N; i++ {
+			if err := Unmarshal(data, &m); err != nil {
+				b.Fatal("Unmarshal:", err)
+			}
+		}
+	})
+
+	b.Run("generic", func(b *testing.B) {
+		b.ReportAllocs()
+		m := slowMap{}
+		for i := 0; i < b.N; i++ {
+			if err := Unmarshal(data, &m); err != nil {
+				b.Fatal("Unmarshal:", err)
+			}
+		}
+	})
+}
+
+func BenchmarkUnmarshalUnknownFields(b *testing.B) {
+	// The tree is an unknown field of the struct, the parser either skips it
+	// or, when SkipValue is hidden by the wrapper, it is decoded and discarded.
+	var v struct {
+		Username string `json:"username"`
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`{"tree":[`)
+	for i := 0; i != 1000; i++ {
+		if i != 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(`{"name":"node","kids":[{"touches":1,"min_t":1254378100}],"cl_weight":0.5}`)
+	}
+	buf.WriteString(`],"username":"objconv"}`)
+	data := buf.Bytes()
+
+	for _, bench := range []struct {
+		name   string
+		parser func(*Parser) objconv.Parser
+	}{
+		{"skip", func(p *Parser) objconv.Parser { return p }},
+		{"materialize", func(p *Parser) objconv.Parser { return struct{ objconv.Parser }{p} }},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			r := bytes.NewReader(data)
+			p := NewParser(r)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				r.Reset(data)
+				p.Reset(r)
+				if err := objconv.NewDecoder(bench.parser(p)).Decode(&v); err != nil || v.Username != "objconv" {
+					b.Fatal("Decode:", err)
+				}
+			}
+			b.SetBytes(int64(len(data)))
+		})
+	}
+}
+
+func BenchmarkDecoderReset(b *testing.B) {
+	data := []byte(`42`)
+	r := bytes.NewReader(data)
+	p := NewParser(r)
+	d := objconv.NewDecoder(p)
+	x := int64(0)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r.Reset(data)
+		p.Reset(r)
+		d.Reset(p)
+
+		if err := d.Decode(&x); err != nil || x != 42 {
+			b.Fatalf("Decode: %v (%d)", err, x)
+		}
+	}
+}
+
 func BenchmarkIssue10335(b *testing.B) {
 	b.ReportAllocs()
 	var s struct{}
@@ -1,11 +1,20 @@
 package json
 
 import (
+	"bytes"
+	stdjson "encoding/json"
 	"fmt"
+	"io"
 	"math"
+	"math/big"
+	"net"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/segmentio/objconv"
+	"github.com/segmentio/objconv/msgpack"
 	"github.com/segmentio/objconv/objtests"
 )
 
@@ -68,6 +77,496 @@ func TestMapValueOverflow(t *testing.T) {
 	}
 }
 
+func TestPromoteOverflowToFloat(t *testing.T) {
+	const src = `[1, 18446744073709551616, -99999999999999999999]`
+
+	var v interface{}
+	d := objconv.Decoder{Parser: NewParser(strings.NewReader(src))}
+
+	if err := d.Decode(&v); err == nil {
+		t.Error("expected an overflow error when PromoteOverflowToFloat is not set")
+	}
+
+	d = objconv.Decoder{Parser: NewParser(strings.NewReader(src)), PromoteOverflowToFloat: true}
+
+	if err := d.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []interface{}{int64(1), 18446744073709551616.0, -99999999999999999999.0}
+
+	if fmt.Sprint(v) != fmt.Sprint(expect) {
+		t.Errorf("%#v != %#v", v, expect)
+	}
+}
+
+func TestRawValue(t *testing.T) {
+	const src = `{"type":"point", "value": {"x": 1, "y": [2, "}\""]}, "n": -1.5,"s":"a,b" ,"z":null}`
+
+	var m map[string]objconv.RawValue
+
+	if err := Unmarshal([]byte(src), &m); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := map[string]string{
+		"type":  `"point"`,
+		"value": `{"x": 1, "y": [2, "}\""]}`,
+		"n":     `-1.5`,
+		"s":     `"a,b"`,
+		"z":     `null`,
+	}
+
+	for k, v := range expect {
+		if string(m[k]) != v {
+			t.Errorf("%s: %s != %s", k, m[k], v)
+		}
+	}
+
+	var p struct {
+		X int           `json:"x"`
+		Y []interface{} `json:"y"`
+	}
+
+	if err := Unmarshal(m["value"], &p); err != nil {
+		t.Fatal(err)
+	}
+
+	if p.X != 1 || len(p.Y) != 2 || p.Y[1] != `}"` {
+		t.Errorf("%#v", p)
+	}
+}
+
+func TestRawValueEmitter(t *testing.T) {
+	var r objconv.RawValue
+
+	d := objconv.Decoder{Parser: objconv.NewValueParser([]int{1, 2})}
+
+	if err := d.Decode(&r); err == nil {
+		t.Error("expected an error when the decoder has no RawEmitter")
+	}
+
+	d.RawEmitter = func(w io.Writer) objconv.Emitter { return NewEmitter(w) }
+
+	if err := d.Decode(&r); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(r) != `[1,2]` {
+		t.Error(string(r))
+	}
+}
+
+func TestRawValueUnknownFields(t *testing.T) {
+	const src = `{"a":1,"b":{"c":[true, null]},"name":"Luke","d":"\u2022"}`
+
+	var v struct {
+		Name  string                      `json:"name"`
+		Extra map[string]objconv.RawValue `objconv:",unknownfields"`
+	}
+
+	if err := Unmarshal([]byte(src), &v); err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Name != "Luke" {
+		t.Error(v.Name)
+	}
+
+	expect := map[string]string{
+		"a": `1`,
+		"b": `{"c":[true, null]}`,
+		"d": `"\u2022"`,
+	}
+
+	if len(v.Extra) != len(expect) {
+		t.Errorf("%q", v.Extra)
+	}
+
+	for k, r := range expect {
+		if string(v.Extra[k]) != r {
+			t.Errorf("%s: %s != %s", k, v.Extra[k], r)
+		}
+	}
+}
+
+func TestBigNumbers(t *testing.T) {
+	const src = `{"i":123456789012345678901234567890,"e":1e21,"f":3.14159265358979323846264338327950288}`
+
+	var v struct {
+		I *big.Int   `json:"i"`
+		E big.Int    `json:"e"`
+		F *big.Float `json:"f"`
+	}
+
+	if err := Unmarshal([]byte(src), &v); err != nil {
+		t.Fatal(err)
+	}
+
+	if s := v.I.String(); s != "123456789012345678901234567890" {
+		t.Error("I:", s)
+	}
+
+	if s := v.E.String(); s != "1000000000000000000000" {
+		t.Error("E:", s)
+	}
+
+	if s := v.F.Text('g', 36); s != "3.14159265358979323846264338327950288" {
+		t.Error("F:", s)
+	}
+}
+
+func TestStreamDecoderEncoderTranscode(t *testing.T) {
+	const src = `{"b":1,"a":[true,{"z":null,"y":"2"}],"c":{}}`
+
+	var buf bytes.Buffer
+	dec := NewStreamDecoder(strings.NewReader(src))
+
+	enc, err := dec.Encoder(NewEmitter(&buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := dec.Decode(nil); err != objconv.End {
+		t.Error("expected the stream to be fully consumed but got", err)
+	}
+
+	if err := enc.Close(); err != nil {
+		t.Error(err)
+	}
+
+	if s := buf.String(); s != src {
+		t.Errorf("%s != %s", s, src)
+	}
+}
+
+func TestTranscodeBufferedMaps(t *testing.T) {
+	const src = `{"a":[1,{"b":"c"}]}`
+
+	var buf bytes.Buffer
+
+	if err := objconv.Transcode(msgpack.NewEmitter(&buf), NewParser(strings.NewReader(src))); err != nil {
+		t.Fatal(err)
+	}
+
+	var v interface{}
+
+	if err := msgpack.Unmarshal(buf.Bytes(), &v); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := map[interface{}]interface{}{
+		"a": []interface{}{int64(1), map[interface{}]interface{}{"b": "c"}},
+	}
+
+	if !reflect.DeepEqual(v, expect) {
+		t.Errorf("%#v != %#v", v, expect)
+	}
+}
+
+func TestStreamDecoderDecodeMapEntry(t *testing.T) {
+	dec := NewStreamDecoder(strings.NewReader(`{"1": {"name": "Luke"}, "2": {"name": "Leia"}}`))
+	res := map[string]string{}
+
+	for {
+		var k string
+		var v struct {
+			Name string `json:"name"`
+		}
+
+		if err := dec.DecodeMapEntry(&k, &v); err != nil {
+			break
+		}
+
+		res[k] = v.Name
+	}
+
+	if err := dec.Err(); err != nil {
+		t.Error(err)
+	}
+
+	if !reflect.DeepEqual(res, map[string]string{"1": "Luke", "2": "Leia"}) {
+		t.Errorf("%#v", res)
+	}
+}
+
+func TestStreamDecoderRemainingUnknown(t *testing.T) {
+	dec := NewStreamDecoder(strings.NewReader(`[1, 2, 3]`))
+
+	var v int
+	if err := dec.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, known := dec.Remaining(); known {
+		t.Error("json arrays have no length but the stream decoder reported", n)
+	}
+}
+
+func TestStreamDecoderDecodeRemaining(t *testing.T) {
+	dec := NewStreamDecoder(strings.NewReader(`[{"A":1},{"A":2},{"A":3}]`))
+
+	var first struct{ A int }
+	if err := dec.Decode(&first); err != nil || first.A != 1 {
+		t.Fatal(first, err)
+	}
+
+	var rest []struct{ A int }
+	if err := dec.DecodeRemaining(&rest); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rest) != 2 || rest[0].A != 2 || rest[1].A != 3 {
+		t.Errorf("bad values: %#v", rest)
+	}
+}
+
+func TestStreamDecoderDecodeWithRaw(t *testing.T) {
+	dec := NewStreamDecoder(strings.NewReader(`[{"name": "Luke", "age": 19}, {"name":"Leia"}, null]`))
+
+	type T struct {
+		Name string `json:"name"`
+	}
+
+	var values []T
+	var raws []string
+
+	for {
+		var v T
+
+		raw, err := dec.DecodeWithRaw(&v)
+		if err != nil {
+			break
+		}
+
+		values = append(values, v)
+		raws = append(raws, string(raw))
+	}
+
+	if err := dec.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(values, []T{{"Luke"}, {"Leia"}, {}}) {
+		t.Errorf("%#v", values)
+	}
+
+	if expect := []string{`{"name": "Luke", "age": 19}`, `{"name":"Leia"}`, `null`}; !reflect.DeepEqual(raws, expect) {
+		t.Errorf("%q != %q", raws, expect)
+	}
+
+	t.Run("emitter", func(t *testing.T) {
+		dec := objconv.NewStreamDecoder(objconv.NewValueParser([]interface{}{map[string]interface{}{"name": "Han"}}))
+
+		var v T
+
+		if _, err := dec.DecodeWithRaw(&v); err == nil {
+			t.Error("expected an error when the stream decoder has no RawEmitter")
+		}
+
+		dec = objconv.NewStreamDecoder(objconv.NewValueParser([]interface{}{map[string]interface{}{"name": "Han"}}))
+		dec.RawEmitter = func(w io.Writer) objconv.Emitter { return NewEmitter(w) }
+
+		raw, err := dec.DecodeWithRaw(&v)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if v.Name != "Han" || string(raw) != `{"name":"Han"}` {
+			t.Errorf("%#v %s", v, raw)
+		}
+	})
+}
+
+func TestDecodeStructFormat(t *testing.T) {
+	type T struct {
+		Name   string `objconv:"name"`
+		Format string `objconv:",format"`
+	}
+
+	var v1, v2 T
+
+	if err := Unmarshal([]byte(`{"name":"A"}`), &v1); err != nil {
+		t.Fatal(err)
+	}
+
+	b, _ := msgpack.Marshal(map[string]string{"name": "B"})
+
+	if err := msgpack.Unmarshal(b, &v2); err != nil {
+		t.Fatal(err)
+	}
+
+	if v1 != (T{Name: "A", Format: "json"}) {
+		t.Errorf("%#v", v1)
+	}
+
+	if v2 != (T{Name: "B", Format: "msgpack"}) {
+		t.Errorf("%#v", v2)
+	}
+}
+
+type upperKey string
+
+func (k *upperKey) UnmarshalText(b []byte) error {
+	*k = upperKey(strings.ToUpper(string(b)))
+	return nil
+}
+
+type pointKey struct{ X, Y int }
+
+func (p *pointKey) UnmarshalText(b []byte) error {
+	_, err := fmt.Sscanf(string(b), "%d,%d", &p.X, &p.Y)
+	return err
+}
+
+func TestDecodeMapKeysFromStrings(t *testing.T) {
+	tests := []struct {
+		in  string
+		out interface{}
+	}{
+		{`{"1":"a","-2":"b"}`, map[int]string{1: "a", -2: "b"}},
+		{`{"255":true}`, map[uint8]bool{255: true}},
+		{`{"a":1,"b":2}`, map[upperKey]int{"A": 1, "B": 2}},
+		{`{"1,2":3}`, map[pointKey]int{{1, 2}: 3}},
+		{`{"x":{"1":1}}`, map[string]map[int64]int{"x": {1: 1}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			v := reflect.New(reflect.TypeOf(test.out))
+
+			if err := Unmarshal([]byte(test.in), v.Interface()); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(v.Elem().Interface(), test.out) {
+				t.Errorf("%#v != %#v", v.Elem().Interface(), test.out)
+			}
+		})
+	}
+}
+
+func TestDecodeMapKeysFromStringsError(t *testing.T) {
+	tests := []struct {
+		in  string
+		out interface{}
+	}{
+		{`{"256":true}`, &map[uint8]bool{}},
+		{`{"a":1}`, &map[int]int{}},
+		{`{"true":1}`, &map[bool]int{}},
+		{`{"a":1}`, &map[[2]int]int{}},
+		{`{"a":1}`, &map[struct{ A int }]int{}},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%T", test.out), func(t *testing.T) {
+			if err := Unmarshal([]byte(test.in), test.out); err == nil {
+				t.Error("expected an error decoding", test.in)
+			}
+		})
+	}
+}
+
+func TestUseNumber(t *testing.T) {
+	const src = `{"a":1.50,"b":12345678901234567890123,"c":-0,"d":"1"}`
+
+	var v interface{}
+	d := objconv.Decoder{Parser: NewParser(strings.NewReader(src)), UseNumber: true}
+
+	if err := d.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := map[interface{}]interface{}{
+		"a": objconv.Number("1.50"),
+		"b": objconv.Number("12345678901234567890123"),
+		"c": objconv.Number("-0"),
+		"d": "1",
+	}
+
+	if !reflect.DeepEqual(v, expect) {
+		t.Errorf("%#v != %#v", v, expect)
+	}
+
+	b, err := Marshal(map[string]interface{}{"n": objconv.Number("42")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(b) != `{"n":42}` {
+		t.Errorf("%s", b)
+	}
+}
+
+func TestDecodeStructFieldErrors(t *testing.T) {
+	const src = `{"id":"x","point":{"x":1,"y":[1,2]},"name":"hello","when":"yesterday"}`
+
+	type Point struct {
+		X int `objconv:"x"`
+		Y int `objconv:"y"`
+	}
+
+	var v struct {
+		ID     int                 `objconv:"id"`
+		Point  Point               `objconv:"point"`
+		Name   string              `objconv:"name"`
+		When   time.Time           `objconv:"when"`
+		Errors objconv.FieldResult `objconv:",fielderrors"`
+	}
+
+	if err := Unmarshal([]byte(src), &v); err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Name != "hello" || v.ID != 0 || v.Point != (Point{}) || !v.When.IsZero() {
+		t.Errorf("%#v", v)
+	}
+
+	if len(v.Errors) != 3 || v.Errors["id"] == nil || v.Errors["point"] == nil || v.Errors["when"] == nil {
+		t.Errorf("%#v", v.Errors)
+	}
+}
+
+func TestStreamDecoderIdleTimeout(t *testing.T) {
+	r, w := net.Pipe()
+	defer r.Close()
+	defer w.Close()
+
+	go w.Write([]byte(`[1,2,`))
+
+	dec := NewStreamDecoder(r)
+	dec.IdleTimeout = 50 * time.Millisecond
+
+	for _, expect := range []int{1, 2} {
+		var v int
+		if err := dec.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+		if v != expect {
+			t.Errorf("%d != %d", v, expect)
+		}
+	}
+
+	var v int
+	if err := dec.Decode(&v); err != objconv.ErrIdleTimeout {
+		t.Error("expected an idle timeout error but got", err)
+	}
+
+	if err := dec.Err(); err != objconv.ErrIdleTimeout {
+		t.Error("expected the idle timeout error to be sticky but got", err)
+	}
+
+	t.Run("unsupported", func(t *testing.T) {
+		dec := NewStreamDecoder(strings.NewReader(`[1]`))
+		dec.IdleTimeout = time.Second
+
+		var v int
+		if err := dec.Decode(&v); err == nil || err == objconv.ErrIdleTimeout {
+			t.Error("expected an error using an idle timeout with a reader which doesn't support deadlines but got", err)
+		}
+	})
+}
+
 func TestEmitImpossibleFloats(t *testing.T) {
 	values := []float64{
 		math.NaN(),
@@ -85,3 +584,394 @@ func TestEmitImpossibleFloats(t *testing.T) {
 		})
 	}
 }
+
+func TestDecodeMapNUnknownLength(t *testing.T) {
+	d := NewDecoder(strings.NewReader(`{"a":1,"b":2}`))
+	i := 0
+
+	err := d.DecodeMapN(func(kd objconv.Decoder, vd objconv.Decoder, n int) error {
+		var k string
+		var v int
+
+		if n >= 0 {
+			t.Errorf("json maps have no length, expected a negative count but got %d", n)
+		}
+
+		i++
+
+		if err := kd.Decode(&k); err != nil {
+			return err
+		}
+		return vd.Decode(&v)
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if i != 2 {
+		t.Errorf("bad number of entries: %d", i)
+	}
+}
+
+func TestDecodeLazy(t *testing.T) {
+	const src = `{
+		"name": "config",
+		"server": {"host": "localhost", "port": 4242},
+		"tags": ["a", "b"],
+		"limit": 1.5,
+		"broken": {"port": "not a number"}
+	}`
+
+	type Server struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+	}
+
+	var m map[string]interface{}
+	d := objconv.Decoder{Parser: NewParser(strings.NewReader(src)), Lazy: true}
+
+	if err := d.Decode(&m); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(m) != 5 {
+		t.Fatalf("bad number of entries: %d", len(m))
+	}
+
+	server, ok := m["server"].(objconv.LazyValue)
+	if !ok {
+		t.Fatalf("expected a lazy value but got %#v", m["server"])
+	}
+
+	if raw := string(server.Raw()); raw != `{"host": "localhost", "port": 4242}` {
+		t.Errorf("bad raw value: %s", raw)
+	}
+
+	var s Server
+	if err := server.Decode(&s); err != nil {
+		t.Fatal(err)
+	}
+	if s != (Server{"localhost", 4242}) {
+		t.Errorf("bad server: %#v", s)
+	}
+
+	var name string
+	if err := m["name"].(objconv.LazyValue).Decode(&name); err != nil || name != "config" {
+		t.Errorf("bad name: %q (%v)", name, err)
+	}
+
+	var tags []string
+	if err := m["tags"].(objconv.LazyValue).Decode(&tags); err != nil || !reflect.DeepEqual(tags, []string{"a", "b"}) {
+		t.Errorf("bad tags: %q (%v)", tags, err)
+	}
+
+	var limit float64
+	if err := m["limit"].(objconv.LazyValue).Decode(&limit); err != nil || limit != 1.5 {
+		t.Errorf("bad limit: %g (%v)", limit, err)
+	}
+
+	// Errors are only reported when the lazy value is decoded.
+	if err := m["broken"].(objconv.LazyValue).Decode(&s); err == nil {
+		t.Error("expected an error decoding an invalid value")
+	}
+}
+
+func TestDecodeExact(t *testing.T) {
+	tests := []struct {
+		in string
+		ok bool
+	}{
+		{`{"a":1}`, true},
+		{` {"a":1} ` + "\n", true},
+		{`{"a":1}garbage`, false},
+		{`{"a":1} {"a":2}`, false},
+		{`1 2`, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			var v interface{}
+			err := NewDecoder(strings.NewReader(test.in)).DecodeExact(&v)
+
+			if test.ok && err != nil {
+				t.Error(err)
+			}
+
+			if !test.ok && err == nil {
+				t.Error("expected an error decoding an input with trailing data")
+			}
+		})
+	}
+
+	t.Run("unsupported", func(t *testing.T) {
+		var v int
+		if err := objconv.NewDecoder(objconv.NewValueParser(42)).DecodeExact(&v); err != nil || v != 42 {
+			t.Errorf("the check must be skipped for parsers which don't detect the end of the input: %d (%v)", v, err)
+		}
+	})
+}
+
+func TestDecodeArrayExtraElements(t *testing.T) {
+	var v struct {
+		A [2]int `json:"a"`
+		B int    `json:"b"`
+	}
+
+	if err := Unmarshal([]byte(`{"a":[1,2,[3,{"x":4}]],"b":5}`), &v); err != nil {
+		t.Fatal(err)
+	}
+
+	if v.A != [2]int{1, 2} || v.B != 5 {
+		t.Errorf("%#v", v)
+	}
+}
+
+func TestDecodeAny(t *testing.T) {
+	v, err := NewDecoder(strings.NewReader(`{"users":[{"name":"Luke","age":19},{"name":"Leia"}],"total":2}`)).DecodeAny()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n, ok := v.Get("total").AsInt(); !ok || n != 2 {
+		t.Errorf("bad total: %d", n)
+	}
+
+	if age, ok := v.Get("users").Index(0).Get("age").AsInt(); !ok || age != 19 {
+		t.Errorf("bad age: %d", age)
+	}
+
+	if name, ok := v.Get("users").Index(1).Get("name").AsString(); !ok || name != "Leia" {
+		t.Errorf("bad name: %q", name)
+	}
+
+	b, err := Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := string(b); s != `{"users":[{"name":"Luke","age":19},{"name":"Leia"}],"total":2}` {
+		t.Errorf("bad output: %s", s)
+	}
+}
+
+func TestDecodePeekType(t *testing.T) {
+	var types []objconv.Type
+	var values []interface{}
+
+	if err := NewDecoder(strings.NewReader(`{"a":1,"b":"x","c":[true]}`)).DecodeMap(func(kd objconv.Decoder, vd objconv.Decoder) error {
+		var k string
+		if err := kd.Decode(&k); err != nil {
+			return err
+		}
+
+		// The decoder of the map value must parse the separator before
+		// peeking, and not parse it again when decoding the value.
+		typ, err := vd.PeekType()
+		if err != nil {
+			return err
+		}
+
+		var v interface{}
+		if err := vd.Decode(&v); err != nil {
+			return err
+		}
+
+		types = append(types, typ)
+		values = append(values, v)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(types, []objconv.Type{objconv.Int, objconv.String, objconv.Array}) {
+		t.Errorf("bad types: %v", types)
+	}
+
+	if !reflect.DeepEqual(values, []interface{}{int64(1), "x", []interface{}{true}}) {
+		t.Errorf("bad values: %#v", values)
+	}
+}
+
+func TestDecodeJSONNumber(t *testing.T) {
+	var v struct {
+		A stdjson.Number
+		B stdjson.Number
+		C stdjson.Number
+	}
+
+	if err := Unmarshal([]byte(`{"A":123456789012345678901234567890,"B":-0.5e3,"C":"42"}`), &v); err != nil {
+		t.Fatal(err)
+	}
+
+	if v.A != "123456789012345678901234567890" || v.B != "-0.5e3" || v.C != "42" {
+		t.Errorf("%#v", v)
+	}
+}
+
+func TestOrderedMap(t *testing.T) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i := 0; i != 20; i++ {
+		if i != 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `"k%d":[{"z":%d,"a":null}]`, (i*7)%20, i)
+	}
+	b.WriteByte('}')
+
+	var m objconv.OrderedMap
+
+	if err := Unmarshal(b.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(out) != b.String() {
+		t.Errorf("the map was re-encoded in a different order:\n%s\n%s", b.String(), out)
+	}
+}
+
+func TestDecodeErrorHandler(t *testing.T) {
+	var errs objconv.SkippedErrors
+	var v []struct{ A int }
+
+	d := objconv.NewDecoder(NewParser(strings.NewReader(`[{"A":1},{"A":"x"},{"A":[{"B":true}]},{"A":4}]`)))
+	d.ErrorHandler = errs.Skip
+
+	if err := d.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(v, []struct{ A int }{{1}, {4}}) {
+		t.Errorf("bad value: %#v", v)
+	}
+
+	if len(errs) != 2 || !strings.HasPrefix(errs[0].Error(), "[1].A: ") || !strings.HasPrefix(errs[1].Error(), "[2].A: ") {
+		t.Errorf("bad errors: %v", errs)
+	}
+
+	d = objconv.NewDecoder(NewParser(strings.NewReader(`[1,"x",3]`)))
+	d.ErrorHandler = func(index int, err error) error {
+		return fmt.Errorf("element %d is invalid", index)
+	}
+
+	var a []int
+
+	if err := d.Decode(&a); err == nil || err.Error() != "[1]: element 1 is invalid" {
+		t.Errorf("bad error: %v", err)
+	}
+
+	d = objconv.NewDecoder(NewParser(strings.NewReader(`[1,{"A":},"x`)))
+	d.ErrorHandler = errs.Skip
+
+	if err := d.Decode(&a); err == nil {
+		t.Error("unterminated elements must not be skipped")
+	}
+
+	if len(errs) != 3 {
+		t.Errorf("the malformed element was not skipped: %v", errs)
+	}
+}
+
+func TestDecodeSkipUnknownFields(t *testing.T) {
+	var v struct {
+		A int
+		C string
+	}
+
+	if err := Unmarshal([]byte(`{"A":1,"B":{"x":["]",{"y":"\"}"}],"z":null},"D":-1.5e3,"C":"hello"}`), &v); err != nil {
+		t.Fatal(err)
+	}
+
+	if v.A != 1 || v.C != "hello" {
+		t.Errorf("bad value: %#v", v)
+	}
+}
+
+type embeddedBase struct {
+	ID int
+}
+
+func TestEmbeddedStructs(t *testing.T) {
+	type T struct {
+		embeddedBase
+		Name string
+	}
+
+	b, err := Marshal(T{embeddedBase{ID: 1}, "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The fields of embedded structs are promoted when decoding only.
+	if s := string(b); s != `{"Name":"x"}` {
+		t.Errorf("bad encoding: %s", s)
+	}
+
+	var v T
+
+	if err := Unmarshal([]byte(`{"ID":2,"Name":"y"}`), &v); err != nil {
+		t.Fatal(err)
+	}
+
+	if v.ID != 2 || v.Name != "y" {
+		t.Errorf("bad value: %#v", v)
+	}
+}
+
+func TestEmbeddedTaggedStruct(t *testing.T) {
+	type Base struct {
+		ID int
+	}
+
+	type T struct {
+		Base `objconv:"base"`
+		Name string
+	}
+
+	b, err := Marshal(T{Base{ID: 1}, "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Embedded structs given a name by their tag are decoded like other
+	// fields, but embedded fields are never encoded.
+	if s := string(b); s != `{"Name":"x"}` {
+		t.Errorf("bad encoding: %s", s)
+	}
+
+	var v T
+
+	if err := Unmarshal([]byte(`{"base":{"ID":2},"ID":3,"Name":"y"}`), &v); err != nil {
+		t.Fatal(err)
+	}
+
+	if v.ID != 2 || v.Name != "y" {
+		t.Errorf("bad value: %#v", v)
+	}
+}
+
+func TestEmbeddedNilPointer(t *testing.T) {
+	type Address struct {
+		Zip int
+	}
+
+	type T struct {
+		*Address
+		Name string
+	}
+
+	b, err := Marshal(T{Name: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := string(b); s != `{"Name":"x"}` {
+		t.Errorf("bad encoding: %s", s)
+	}
+}
@@ -41,6 +41,37 @@ func (p *Parser) Buffered() io.Reader {
 	return bytes.NewReader(p.b[p.i:p.j])
 }
 
+// FormatName satisfies the objconv.Named interface.
+func (p *Parser) FormatName() string {
+	return "json"
+}
+
+// SetReadDeadline satisfies the objconv.DeadlineParser interface, it returns an
+// error if the reader of the parser doesn't support read deadlines.
+func (p *Parser) SetReadDeadline(t time.Time) error {
+	return objutil.SetReadDeadline(p.r, t)
+}
+
+// Reparse satisfies the objconv.Reparser interface.
+func (p *Parser) Reparse(b []byte) objconv.Parser {
+	return NewParser(bytes.NewReader(b))
+}
+
+// ParseEOF satisfies the objconv.EOFParser interface, white spaces are allowed
+// after the last value.
+func (p *Parser) ParseEOF() (err error) {
+	if err = p.skipSpaces(); err == nil {
+		b := p.b[p.i:p.j]
+		if len(b) > 16 {
+			b = b[:16]
+		}
+		err = fmt.Errorf("objconv/json: expected the end of the input but found %#v", string(b))
+	} else if err == io.EOF {
+		err = nil
+	}
+	return
+}
+
 func (p *Parser) ParseType() (t objconv.Type, err error) {
 	var b byte
 
@@ -325,6 +356,87 @@ func (p *Parser) ParseMapNext(n int) (err error) {
 	return
 }
 
+func (p *Parser) ParseRaw() (v []byte, err error) {
+	p.s = p.s[:0]
+
+	if err = p.scanValue(true); err == nil {
+		v = p.s
+	}
+	return
+}
+
+// SkipValue satisfies the objconv.SkipParser interface, it moves past the next
+// value like ParseRaw does but without copying its bytes.
+func (p *Parser) SkipValue() error {
+	return p.scanValue(false)
+}
+
+// scanValue consumes the bytes of the next value, appending them to p.s if keep
+// is true.
+func (p *Parser) scanValue(keep bool) (err error) {
+	var depth int
+	var quoted bool
+	var escaped bool
+	var n int
+
+	if err = p.skipSpaces(); err != nil {
+		return
+	}
+
+	for {
+		var b byte
+
+		if b, err = p.peekByteAt(0); err != nil {
+			if err == io.EOF && depth == 0 && !quoted && n != 0 {
+				// scalar values like numbers may be terminated by the end of
+				// the stream.
+				err = nil
+			}
+			return
+		}
+
+		if quoted {
+			switch {
+			case escaped:
+				escaped = false
+			case b == '\\':
+				escaped = true
+			case b == '"':
+				quoted = false
+			}
+		} else {
+			switch b {
+			case '"':
+				quoted = true
+			case '{', '[':
+				depth++
+			case '}', ']':
+				if depth == 0 {
+					if n == 0 {
+						err = fmt.Errorf("objconv/json: expected token but found '%c'", b)
+					}
+					return
+				}
+				depth--
+			case ',', ':', ' ', '\n', '\t', '\r', '\b', '\f':
+				if depth == 0 {
+					return
+				}
+			}
+		}
+
+		if keep {
+			p.s = append(p.s, b)
+		}
+		p.i++
+		n++
+
+		if depth == 0 && !quoted && (b == '"' || b == '}' || b == ']') {
+			return
+		}
+	}
+}
+
 func (p *Parser) TextParser() bool {
 	return true
 }
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/segmentio/objconv"
+	"github.com/segmentio/objconv/objutil"
 )
 
 type Parser struct {
@@ -32,6 +33,17 @@ func (p *Parser) Buffered() io.Reader {
 	return bytes.NewReader(p.b[p.i:p.j])
 }
 
+// FormatName satisfies the objconv.Named interface.
+func (p *Parser) FormatName() string {
+	return "msgpack"
+}
+
+// SetReadDeadline satisfies the objconv.DeadlineParser interface, it returns an
+// error if the reader of the parser doesn't support read deadlines.
+func (p *Parser) SetReadDeadline(t time.Time) error {
+	return objutil.SetReadDeadline(p.r, t)
+}
+
 func (p *Parser) ParseType() (objconv.Type, error) {
 	b, err := p.peek(1)
 	if err != nil {
@@ -0,0 +1,210 @@
+package objconv
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// Number is the textual representation of a number, it is produced when
+// decoding numbers into empty interfaces with a decoder configured with
+// UseNumber, so the distinction between integers and floating point values
+// and all their digits are preserved:
+//
+//	d := objconv.Decoder{Parser: p, UseNumber: true}
+//
+//	var v interface{}
+//	if err := d.Decode(&v); err != nil {
+//		...
+//	}
+//
+//	if n, ok := v.(objconv.Number); ok {
+//		i, err := n.Int64()
+//		...
+//	}
+//
+// When the parser is a text parser which implements RawParser (like json), the
+// number holds the exact representation found in the input, otherwise it is
+// formatted from the value returned by the parser.
+type Number string
+
+var numberType = reflect.TypeOf(Number(""))
+
+// String returns the textual representation of the number.
+func (n Number) String() string { return string(n) }
+
+// Int64 returns the number as an int64.
+func (n Number) Int64() (int64, error) { return strconv.ParseInt(string(n), 10, 64) }
+
+// Uint64 returns the number as an uint64.
+func (n Number) Uint64() (uint64, error) { return strconv.ParseUint(string(n), 10, 64) }
+
+// Float64 returns the number as a float64.
+func (n Number) Float64() (float64, error) { return strconv.ParseFloat(string(n), 64) }
+
+// EncodeValue satisfies the ValueEncoder interface, the number is emitted as an
+// integer if it can be represented by an int64 or uint64, or as a floating
+// point value otherwise.
+func (n Number) EncodeValue(e Encoder) error {
+	if i, err := n.Int64(); err == nil {
+		return e.Emitter.EmitInt(i, 64)
+	}
+
+	if u, err := n.Uint64(); err == nil {
+		return e.Emitter.EmitUint(u, 64)
+	}
+
+	f, err := n.Float64()
+	if err != nil && !isRangeError(err) {
+		return fmt.Errorf("objconv: %q is not a valid number", string(n))
+	}
+	return e.Emitter.EmitFloat(f, 64)
+}
+
+// DecodeValue satisfies the ValueDecoder interface.
+func (n *Number) DecodeValue(d Decoder) error {
+	t, err := d.Parser.ParseType()
+	if err != nil {
+		return err
+	}
+	return d.decodeNumberFromType(t, reflect.ValueOf(n).Elem())
+}
+
+func (d Decoder) decodeNumberFromType(t Type, to reflect.Value) (err error) {
+	var a [64]byte
+	var b []byte
+	var i int64
+	var u uint64
+	var f float64
+
+	switch t {
+	case Nil:
+		err = d.Parser.ParseNil()
+
+	case Int:
+		if b, err = d.parseBigNumber(); err != nil || b != nil {
+			break
+		}
+		if i, err = d.Parser.ParseInt(); err == nil {
+			b = strconv.AppendInt(a[:0], i, 10)
+		}
+
+	case Uint:
+		if b, err = d.parseBigNumber(); err != nil || b != nil {
+			break
+		}
+		if u, err = d.Parser.ParseUint(); err == nil {
+			b = strconv.AppendUint(a[:0], u, 10)
+		}
+
+	case Float:
+		if b, err = d.parseBigNumber(); err != nil || b != nil {
+			break
+		}
+		if f, err = d.Parser.ParseFloat(); err == nil {
+			b = strconv.AppendFloat(a[:0], f, 'g', -1, 64)
+		}
+
+	case String, Bytes:
+		if _, b, err = d.decodeTypeAndString(); err != nil {
+			break
+		}
+		if _, e := strconv.ParseFloat(unsafeString(b), 64); e != nil && !isRangeError(e) {
+			err = fmt.Errorf("objconv: %q cannot be decoded into a number", b)
+		}
+
+	default:
+		err = typeConversionError(t, Float)
+	}
+
+	if err != nil {
+		return
+	}
+
+	if to.IsValid() {
+		to.SetString(string(b))
+	}
+	return
+}
+
+// decodeJSONNumber decodes json.Number values, which are accepted from numbers
+// like objconv.Number values, and from strings holding numbers in the syntax of
+// the json format.
+func (d Decoder) decodeJSONNumber(to reflect.Value) (t Type, err error) {
+	if t, err = d.Parser.ParseType(); err != nil {
+		return
+	}
+
+	if t != String && t != Bytes {
+		err = d.decodeNumberFromType(t, to)
+		return
+	}
+
+	var b []byte
+
+	if _, b, err = d.decodeTypeAndString(); err != nil {
+		return
+	}
+
+	if !isJSONNumber(b) {
+		err = fmt.Errorf("objconv: %q cannot be decoded into a json.Number", b)
+		return
+	}
+
+	if to.IsValid() {
+		to.SetString(string(b))
+	}
+	return
+}
+
+// isJSONNumber returns true if b is a number in the syntax of the json format,
+// which excludes the infinities and NaN accepted by strconv.ParseFloat.
+func isJSONNumber(b []byte) bool {
+	if len(b) != 0 && b[0] == '-' {
+		b = b[1:]
+	}
+
+	switch {
+	case len(b) == 0:
+		return false
+	case b[0] == '0':
+		b = b[1:]
+	case b[0] >= '1' && b[0] <= '9':
+		b = skipDigits(b[1:])
+	default:
+		return false
+	}
+
+	if len(b) != 0 && b[0] == '.' {
+		if b = b[1:]; len(b) == 0 || !isDigit(b[0]) {
+			return false
+		}
+		b = skipDigits(b)
+	}
+
+	if len(b) != 0 && (b[0] == 'e' || b[0] == 'E') {
+		if b = b[1:]; len(b) != 0 && (b[0] == '+' || b[0] == '-') {
+			b = b[1:]
+		}
+		if len(b) == 0 || !isDigit(b[0]) {
+			return false
+		}
+		b = skipDigits(b)
+	}
+
+	return len(b) == 0
+}
+
+func skipDigits(b []byte) []byte {
+	for len(b) != 0 && isDigit(b[0]) {
+		b = b[1:]
+	}
+	return b
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isRangeError(err error) bool {
+	e, ok := err.(*strconv.NumError)
+	return ok && e.Err == strconv.ErrRange
+}
@@ -0,0 +1,142 @@
+package objconv
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDecoderUseNumber(t *testing.T) {
+	in := []interface{}{int64(-1), uint64(1 << 63), 0.5, "1", nil}
+
+	var v interface{}
+	d := Decoder{Parser: NewValueParser(in), UseNumber: true}
+
+	if err := d.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []interface{}{Number("-1"), Number("9223372036854775808"), Number("0.5"), "1", nil}
+
+	if !reflect.DeepEqual(v, expect) {
+		t.Errorf("%#v != %#v", v, expect)
+	}
+}
+
+func TestNumberDecodeValue(t *testing.T) {
+	tests := []struct {
+		in  interface{}
+		out Number
+		ok  bool
+	}{
+		{int64(42), "42", true},
+		{uint64(42), "42", true},
+		{1.25, "1.25", true},
+		{"1e3", "1e3", true},
+		{"1e400", "1e400", true},
+		{"abc", "", false},
+		{true, "", false},
+	}
+
+	for _, test := range tests {
+		var n Number
+		err := NewDecoder(NewValueParser(test.in)).Decode(&n)
+
+		switch {
+		case test.ok && err != nil:
+			t.Errorf("%#v: %s", test.in, err)
+		case !test.ok && err == nil:
+			t.Errorf("%#v: expected an error", test.in)
+		case n != test.out:
+			t.Errorf("%#v: %q != %q", test.in, n, test.out)
+		}
+	}
+}
+
+func TestNumberEncodeValue(t *testing.T) {
+	tests := []struct {
+		in  Number
+		out interface{}
+	}{
+		{"-1", int64(-1)},
+		{"18446744073709551615", uint64(18446744073709551615)},
+		{"0.5", 0.5},
+	}
+
+	for _, test := range tests {
+		e := &ValueEmitter{}
+
+		if err := NewEncoder(e).Encode(test.in); err != nil {
+			t.Error(err)
+			continue
+		}
+
+		if v := e.Value(); v != test.out {
+			t.Errorf("%q: %#v != %#v", test.in, v, test.out)
+		}
+	}
+
+	if err := NewEncoder(&ValueEmitter{}).Encode(Number("abc")); err == nil {
+		t.Error("expected an error encoding an invalid number")
+	}
+}
+
+func TestNumberConversions(t *testing.T) {
+	n := Number("42")
+
+	if i, err := n.Int64(); err != nil || i != 42 {
+		t.Error(i, err)
+	}
+
+	if u, err := n.Uint64(); err != nil || u != 42 {
+		t.Error(u, err)
+	}
+
+	if f, err := n.Float64(); err != nil || f != 42 {
+		t.Error(f, err)
+	}
+
+	if _, err := Number("0.5").Int64(); err == nil {
+		t.Error("expected an error converting 0.5 to an int64")
+	}
+}
+
+func TestDecoderJSONNumber(t *testing.T) {
+	tests := []struct {
+		in  interface{}
+		out json.Number
+		ok  bool
+	}{
+		{int64(-42), "-42", true},
+		{uint64(1 << 63), "9223372036854775808", true},
+		{1.25, "1.25", true},
+		{"0", "0", true},
+		{"-1.5e+10", "-1.5e+10", true},
+		{[]byte("12"), "12", true},
+		{nil, "", true},
+		{"", "", false},
+		{"NaN", "", false},
+		{"Inf", "", false},
+		{"01", "", false},
+		{"1.", "", false},
+		{".5", "", false},
+		{"1e", "", false},
+		{"0x10", "", false},
+		{"abc", "", false},
+		{true, "", false},
+	}
+
+	for _, test := range tests {
+		var v struct{ N json.Number }
+		err := NewDecoder(NewValueParser(map[string]interface{}{"N": test.in})).Decode(&v)
+
+		switch {
+		case test.ok && err != nil:
+			t.Errorf("%#v: %s", test.in, err)
+		case !test.ok && err == nil:
+			t.Errorf("%#v: expected an error", test.in)
+		case v.N != test.out:
+			t.Errorf("%#v: %q != %q", test.in, v.N, test.out)
+		}
+	}
+}
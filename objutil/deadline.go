@@ -0,0 +1,18 @@
+package objutil
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// SetReadDeadline sets the read deadline of r to t if r supports it (like
+// net.Conn or *os.File values do), or returns an error otherwise.
+func SetReadDeadline(r io.Reader, t time.Time) error {
+	if d, ok := r.(interface {
+		SetReadDeadline(time.Time) error
+	}); ok {
+		return d.SetReadDeadline(t)
+	}
+	return fmt.Errorf("objconv: %T does not support read deadlines", r)
+}
@@ -0,0 +1,27 @@
+package objutil
+
+import (
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetReadDeadline(t *testing.T) {
+	r, w := net.Pipe()
+	defer r.Close()
+	defer w.Close()
+
+	if err := SetReadDeadline(r, time.Now().Add(time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.Read(make([]byte, 1)); !os.IsTimeout(err) {
+		t.Error("expected a timeout error but got", err)
+	}
+
+	if err := SetReadDeadline(strings.NewReader(""), time.Now()); err == nil {
+		t.Error("expected an error setting a deadline on a reader which doesn't support it")
+	}
+}
@@ -89,7 +89,7 @@ const (
 // original type of v.
 func CheckUint64Bounds(v uint64, max uint64, t reflect.Type) (err error) {
 	if v > max {
-		err = fmt.Errorf("objconv: %d overflows the maximum value of %d for %s", v, max, t)
+		err = overflowError(fmt.Sprint(v), minOf(t, max), max, t)
 	}
 	return
 }
@@ -97,11 +97,23 @@ func CheckUint64Bounds(v uint64, max uint64, t reflect.Type) (err error) {
 // CheckInt64Bounds verifies that v is within min and max, t represents the
 // original type of v.
 func CheckInt64Bounds(v int64, min int64, max uint64, t reflect.Type) (err error) {
-	if v < min {
-		err = fmt.Errorf("objconv: %d overflows the minimum value of %d for %s", v, min, t)
-	}
-	if v > 0 && uint64(v) > max {
-		err = fmt.Errorf("objconv: %d overflows the maximum value of %d for %s", v, max, t)
+	if v < min || (v > 0 && uint64(v) > max) {
+		err = overflowError(fmt.Sprint(v), fmt.Sprint(min), max, t)
 	}
 	return
 }
+
+func overflowError(v string, min string, max uint64, t reflect.Type) error {
+	return fmt.Errorf("objconv: value %s overflows %s (range %s..%d)", v, t, min, max)
+}
+
+// minOf returns the minimum value of the integer type t, given its maximum
+// value.
+func minOf(t reflect.Type, max uint64) string {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return fmt.Sprint(-int64(max) - 1)
+	default:
+		return "0"
+	}
+}
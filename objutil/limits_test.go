@@ -1 +1,58 @@
 package objutil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckInt64Bounds(t *testing.T) {
+	tests := []struct {
+		v   int64
+		min int64
+		max uint64
+		t   reflect.Type
+		err string
+	}{
+		{127, Int8Min, Int8Max, reflect.TypeOf(int8(0)), ""},
+		{-128, Int8Min, Int8Max, reflect.TypeOf(int8(0)), ""},
+		{128, Int8Min, Int8Max, reflect.TypeOf(int8(0)), "objconv: value 128 overflows int8 (range -128..127)"},
+		{-129, Int8Min, Int8Max, reflect.TypeOf(int8(0)), "objconv: value -129 overflows int8 (range -128..127)"},
+		{-1, 0, Uint16Max, reflect.TypeOf(uint16(0)), "objconv: value -1 overflows uint16 (range 0..65535)"},
+	}
+
+	for _, test := range tests {
+		err := CheckInt64Bounds(test.v, test.min, test.max, test.t)
+
+		if s := errorString(err); s != test.err {
+			t.Errorf("%d: %q != %q", test.v, s, test.err)
+		}
+	}
+}
+
+func TestCheckUint64Bounds(t *testing.T) {
+	tests := []struct {
+		v   uint64
+		max uint64
+		t   reflect.Type
+		err string
+	}{
+		{255, Uint8Max, reflect.TypeOf(uint8(0)), ""},
+		{256, Uint8Max, reflect.TypeOf(uint8(0)), "objconv: value 256 overflows uint8 (range 0..255)"},
+		{32768, Int16Max, reflect.TypeOf(int16(0)), "objconv: value 32768 overflows int16 (range -32768..32767)"},
+	}
+
+	for _, test := range tests {
+		err := CheckUint64Bounds(test.v, test.max, test.t)
+
+		if s := errorString(err); s != test.err {
+			t.Errorf("%d: %q != %q", test.v, s, test.err)
+		}
+	}
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
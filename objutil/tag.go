@@ -7,11 +7,78 @@ type Tag struct {
 	// Name is the field name that should be used when serializing.
 	Name string
 
+	// Alias is the list of alternative names set with `alias=a|b`, which are
+	// also matched with the keys of maps when decoding. The names are
+	// separated by '|' characters. An alias never shadows the name of another
+	// field, and when several fields declare the same alias the first one in
+	// the struct wins.
+	Alias string
+
 	// Omitempty is true if the tag had `omitempty` set.
 	Omitempty bool
 
 	// Omitzero is true if the tag had `omitzero` set.
 	Omitzero bool
+
+	// Required is true if the tag had `required` set.
+	Required bool
+
+	// Warnings is true if the tag had `warnings` set.
+	Warnings bool
+
+	// DiscriminatorValue is true if the tag had `discriminatorvalue` set.
+	DiscriminatorValue bool
+
+	// UnknownFields is true if the tag had `unknownfields` set.
+	UnknownFields bool
+
+	// Extra is true if the tag had `extra` set.
+	Extra bool
+
+	// Format is true if the tag had `format` set.
+	Format bool
+
+	// FieldErrors is true if the tag had `fielderrors` set.
+	FieldErrors bool
+
+	// Positional is true if the tag had `positional` set, it applies to the
+	// struct and may be declared on any of its fields, including placeholders
+	// like `_ struct{}`.
+	Positional bool
+
+	// TrimPrefix and TrimSuffix are the decorations set with `trimprefix=...`
+	// and `trimsuffix=...`, which are removed from string values when decoding
+	// and added back when encoding.
+	TrimPrefix string
+	TrimSuffix string
+
+	// JSONPath is the JSON pointer set with `jsonpath=...`, which locates the
+	// value of the field in the decoded document. Decoding structs that have
+	// such fields requires loading the whole map in memory first.
+	JSONPath string
+
+	// OneOf is the list of field names set with `oneof=a|b|c`, at most one of
+	// these fields may be set to a non-zero value by the decoded input, keys
+	// with null or zero values are ignored. ExactlyOneOf is set with
+	// `exactlyoneof=a|b|c` and also requires one of the fields to be set. The
+	// names are separated by '|' characters.
+	OneOf        string
+	ExactlyOneOf string
+
+	// OneOfGroup is set with `oneof=...` when the value is a single name with
+	// no '|' characters, it's the name of a group that the field belongs to
+	// instead of a list of fields. Exactly one of the fields declaring the same
+	// group must be set by the decoded input.
+	OneOfGroup string
+
+	// UniqueBy is the name of the field set with `uniqueby=...`, the elements
+	// of slices of structs are deduplicated by the value of this field.
+	UniqueBy string
+
+	// Default is the value set with `default=...`, which is decoded into the
+	// field when its key is absent from the input. The value cannot contain
+	// commas since they separate the options of the tag.
+	Default string
 }
 
 // ParseTag parses a raw tag obtained from a struct field, returning the results
@@ -19,7 +86,24 @@ type Tag struct {
 func ParseTag(s string) Tag {
 	var name string
 	var omitzero bool
+	var required bool
 	var omitempty bool
+	var warnings bool
+	var discriminatorValue bool
+	var unknownFields bool
+	var extra bool
+	var format bool
+	var fieldErrors bool
+	var positional bool
+	var trimPrefix string
+	var trimSuffix string
+	var oneOf string
+	var exactlyOneOf string
+	var oneOfGroup string
+	var uniqueBy string
+	var alias string
+	var jsonPath string
+	var defaultValue string
 
 	name, s = parseNextTagToken(s)
 
@@ -30,13 +114,70 @@ func ParseTag(s string) Tag {
 			omitempty = true
 		case "omitzero":
 			omitzero = true
+		case "required":
+			required = true
+		case "warnings":
+			warnings = true
+		case "discriminatorvalue":
+			discriminatorValue = true
+		case "unknownfields":
+			unknownFields = true
+		case "extra":
+			extra = true
+		case "format":
+			format = true
+		case "fielderrors":
+			fieldErrors = true
+		case "positional":
+			positional = true
+		default:
+			switch {
+			case strings.HasPrefix(token, "jsonpath="):
+				jsonPath = token[len("jsonpath="):]
+			case strings.HasPrefix(token, "trimprefix="):
+				trimPrefix = token[len("trimprefix="):]
+			case strings.HasPrefix(token, "trimsuffix="):
+				trimSuffix = token[len("trimsuffix="):]
+			case strings.HasPrefix(token, "oneof="):
+				if list := token[len("oneof="):]; strings.IndexByte(list, '|') >= 0 {
+					oneOf = list
+				} else {
+					oneOfGroup = list
+				}
+			case strings.HasPrefix(token, "exactlyoneof="):
+				exactlyOneOf = token[len("exactlyoneof="):]
+			case strings.HasPrefix(token, "uniqueby="):
+				uniqueBy = token[len("uniqueby="):]
+			case strings.HasPrefix(token, "alias="):
+				alias = token[len("alias="):]
+			case strings.HasPrefix(token, "default="):
+				defaultValue = token[len("default="):]
+			}
 		}
 	}
 
 	return Tag{
 		Name:      name,
+		Alias:     alias,
 		Omitempty: omitempty,
 		Omitzero:  omitzero,
+		Required:  required,
+		Warnings:  warnings,
+
+		DiscriminatorValue: discriminatorValue,
+		UnknownFields:      unknownFields,
+		Extra:              extra,
+		Format:             format,
+		FieldErrors:        fieldErrors,
+		Positional:         positional,
+		TrimPrefix:         trimPrefix,
+		TrimSuffix:         trimSuffix,
+		OneOf:              oneOf,
+		ExactlyOneOf:       exactlyOneOf,
+		OneOfGroup:         oneOfGroup,
+		UniqueBy:           uniqueBy,
+		JSONPath:           jsonPath,
+		Default:            defaultValue,
 	}
 }
 
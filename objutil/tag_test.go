@@ -39,6 +39,70 @@ func TestParseTag(t *testing.T) {
 			tag: "-,omitempty,omitzero",
 			res: Tag{Name: "-", Omitempty: true, Omitzero: true},
 		},
+		{
+			tag: "id,required,omitempty",
+			res: Tag{Name: "id", Required: true, Omitempty: true},
+		},
+		{
+			tag: ",warnings",
+			res: Tag{Warnings: true},
+		},
+		{
+			tag: ",discriminatorvalue",
+			res: Tag{DiscriminatorValue: true},
+		},
+		{
+			tag: ",unknownfields",
+			res: Tag{UnknownFields: true},
+		},
+		{
+			tag: ",extra",
+			res: Tag{Extra: true},
+		},
+		{
+			tag: ",format",
+			res: Tag{Format: true},
+		},
+		{
+			tag: ",fielderrors",
+			res: Tag{FieldErrors: true},
+		},
+		{
+			tag: "port,default=8080,omitempty",
+			res: Tag{Name: "port", Default: "8080", Omitempty: true},
+		},
+		{
+			tag: "-,positional",
+			res: Tag{Name: "-", Positional: true},
+		},
+		{
+			tag: "user,trimprefix=user:,trimsuffix=@example.com",
+			res: Tag{Name: "user", TrimPrefix: "user:", TrimSuffix: "@example.com"},
+		},
+		{
+			tag: "emailAddress,alias=email|mail,omitempty",
+			res: Tag{Name: "emailAddress", Alias: "email|mail", Omitempty: true},
+		},
+		{
+			tag: "-,oneof=a|b|c",
+			res: Tag{Name: "-", OneOf: "a|b|c"},
+		},
+		{
+			tag: ",exactlyoneof=a|b",
+			res: Tag{ExactlyOneOf: "a|b"},
+		},
+		{
+			tag: "card,oneof=payment,omitempty",
+			res: Tag{Name: "card", OneOfGroup: "payment", Omitempty: true},
+		},
+		{
+			tag: "items,uniqueby=Key,omitempty",
+			res: Tag{Name: "items", UniqueBy: "Key", Omitempty: true},
+		},
+		{
+			tag: "city,jsonpath=/address/city,omitempty",
+			res: Tag{Name: "city", JSONPath: "/address/city", Omitempty: true},
+		},
 	}
 
 	for _, test := range tests {
@@ -0,0 +1,156 @@
+package objconv
+
+import "reflect"
+
+// OrderedMap is a map which preserves the order of its keys, it can be used to
+// decode and re-encode documents without reordering their maps, like
+// configuration files edited by humans.
+//
+// Maps nested in the values of an OrderedMap are also decoded as OrderedMap
+// values, including those found in arrays, which are decoded as []interface{}.
+// Other values are decoded like when the destination is an empty interface.
+//
+// Keys of type []byte are converted to strings when decoding. Keys are compared
+// with reflect.DeepEqual, so keys which cannot be compared with == like arrays
+// (decoded as []interface{}) or maps can also be looked up. When a key appears
+// multiple times in a map, all the items are kept and Get returns the last
+// one, like decoding into a Go map would.
+type OrderedMap []OrderedMapItem
+
+// OrderedMapItem is a key/value pair of an OrderedMap.
+type OrderedMapItem struct {
+	Key   interface{}
+	Value interface{}
+}
+
+// Get returns the value associated with key in m, and whether the key exists.
+func (m OrderedMap) Get(key interface{}) (interface{}, bool) {
+	if i := m.index(key); i >= 0 {
+		return m[i].Value, true
+	}
+	return nil, false
+}
+
+// Set associates value with key in m, the value replaces the one of an existing
+// item with the same key, or is added at the end of the map otherwise.
+func (m *OrderedMap) Set(key interface{}, value interface{}) {
+	if i := m.index(key); i >= 0 {
+		(*m)[i].Value = value
+	} else {
+		*m = append(*m, OrderedMapItem{Key: key, Value: value})
+	}
+}
+
+func (m OrderedMap) index(key interface{}) int {
+	for i := len(m) - 1; i >= 0; i-- {
+		if keyEqual(m[i].Key, key) {
+			return i
+		}
+	}
+	return -1
+}
+
+// keyEqual compares the keys a and b of an OrderedMap, strings are compared
+// directly since they are the most common keys.
+func keyEqual(a interface{}, b interface{}) bool {
+	if s, ok := a.(string); ok {
+		t, ok := b.(string)
+		return ok && s == t
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// EncodeValue satisfies the ValueEncoder interface, the items of the map are
+// encoded in order.
+func (m OrderedMap) EncodeValue(e Encoder) error {
+	if m == nil {
+		return e.Emitter.EmitNil()
+	}
+
+	i := 0
+	return e.EncodeMap(len(m), func(ke Encoder, ve Encoder) error {
+		i++
+		if err := ke.Encode(m[i-1].Key); err != nil {
+			return err
+		}
+		return ve.Encode(m[i-1].Value)
+	})
+}
+
+// DecodeValue satisfies the ValueDecoder interface, decoding null values sets
+// m to nil.
+func (m *OrderedMap) DecodeValue(d Decoder) error {
+	var items OrderedMap
+
+	t, err := d.PeekType()
+	if err != nil {
+		return err
+	}
+
+	if err = d.decodeMapImpl(t, func(kd Decoder, vd Decoder) error {
+		var item OrderedMapItem
+
+		if err := kd.Decode(&item.Key); err != nil {
+			return err
+		}
+
+		if b, ok := item.Key.([]byte); ok {
+			item.Key = string(b)
+		}
+
+		v, err := decodeOrderedValue(vd)
+		if err != nil {
+			return decodeErrorWithKey(err, item.Key)
+		}
+
+		item.Value = v
+		items = append(items, item)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if t == Nil {
+		*m = nil
+	} else if items == nil {
+		*m = OrderedMap{}
+	} else {
+		*m = items
+	}
+	return nil
+}
+
+// decodeOrderedValue decodes the next value into an empty interface, except for
+// maps and arrays which are decoded as OrderedMap and []interface{} values to
+// preserve the order of the maps that they contain.
+func decodeOrderedValue(d Decoder) (v interface{}, err error) {
+	var t Type
+
+	if t, err = d.PeekType(); err != nil {
+		return
+	}
+
+	switch t {
+	case Map:
+		var m OrderedMap
+		err = m.DecodeValue(d)
+		v = m
+
+	case Array:
+		a := []interface{}{}
+		err = d.DecodeArray(func(d Decoder) error {
+			e, err := decodeOrderedValue(d)
+			if err != nil {
+				return decodeErrorWithIndex(err, len(a))
+			}
+			a = append(a, e)
+			return nil
+		})
+		v = a
+
+	default:
+		err = d.Decode(&v)
+	}
+
+	return
+}
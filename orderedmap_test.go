@@ -0,0 +1,118 @@
+package objconv
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestOrderedMap(t *testing.T) {
+	tokens := []interface{}{20}
+	for i := 0; i != 20; i++ {
+		// Use keys which don't sort in the order they are decoded in.
+		tokens = append(tokens, []byte(fmt.Sprintf("k%d", (i*7)%20)), int64(i))
+	}
+
+	var m OrderedMap
+
+	if err := NewDecoder(&tokenParser{tokens: tokens}).Decode(&m); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(m) != 20 {
+		t.Fatalf("bad length: %d", len(m))
+	}
+
+	for i, item := range m {
+		if key := fmt.Sprintf("k%d", (i*7)%20); item.Key != key || item.Value != int64(i) {
+			t.Errorf("bad item at index %d: %#v", i, item)
+		}
+	}
+
+	if v, ok := m.Get("k7"); !ok || v != int64(1) {
+		t.Errorf("bad value of k7: %#v (%t)", v, ok)
+	}
+
+	if _, ok := m.Get("k20"); ok {
+		t.Error("unexpected value of k20")
+	}
+
+	m.Set("k7", "A")
+	m.Set("k20", "B")
+
+	if len(m) != 21 || m[1].Value != "A" || m[20] != (OrderedMapItem{Key: "k20", Value: "B"}) {
+		t.Errorf("bad map after setting values: %#v", m)
+	}
+}
+
+func TestOrderedMapNested(t *testing.T) {
+	in := map[interface{}]interface{}{
+		1:   "A",
+		"B": []interface{}{map[string]interface{}{"C": true}},
+		"D": map[string]interface{}{"E": nil},
+		"F": []byte("G"),
+	}
+
+	var m OrderedMap
+
+	if err := NewDecoder(NewValueParser(in)).Decode(&m); err != nil {
+		t.Fatal(err)
+	}
+
+	if v, _ := m.Get(int64(1)); v != "A" {
+		t.Errorf("bad value of 1: %#v", v)
+	}
+
+	if v, _ := m.Get("B"); !reflect.DeepEqual(v, []interface{}{OrderedMap{{Key: "C", Value: true}}}) {
+		t.Errorf("bad value of B: %#v", v)
+	}
+
+	if v, _ := m.Get("D"); !reflect.DeepEqual(v, OrderedMap{{Key: "E", Value: nil}}) {
+		t.Errorf("bad value of D: %#v", v)
+	}
+
+	if v, _ := m.Get("F"); !reflect.DeepEqual(v, []byte("G")) {
+		t.Errorf("bad value of F: %#v", v)
+	}
+
+	e := NewValueEmitter()
+
+	if err := NewEncoder(e).Encode(OrderedMap{{Key: "A", Value: OrderedMap{{Key: "B", Value: 1}}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if v := e.Value(); !reflect.DeepEqual(v, map[interface{}]interface{}{"A": map[interface{}]interface{}{"B": int64(1)}}) {
+		t.Errorf("bad encoded value: %#v", v)
+	}
+
+	if err := NewDecoder(NewValueParser(nil)).Decode(&m); err != nil || m != nil {
+		t.Errorf("bad value after decoding null: %#v (%v)", m, err)
+	}
+}
+
+func TestOrderedMapUncomparableKeys(t *testing.T) {
+	in := map[interface{}]interface{}{[2]int{1, 2}: "A", "B": "C"}
+
+	var m OrderedMap
+
+	if err := NewDecoder(NewValueParser(in)).Decode(&m); err != nil {
+		t.Fatal(err)
+	}
+
+	key := []interface{}{int64(1), int64(2)}
+
+	if v, ok := m.Get(key); !ok || v != "A" {
+		t.Errorf("bad value of %v: %#v (%t)", key, v, ok)
+	}
+
+	m.Set(map[string]interface{}{"D": nil}, "E")
+	m.Set(key, "F")
+
+	if v, ok := m.Get(map[string]interface{}{"D": nil}); len(m) != 3 || !ok || v != "E" {
+		t.Errorf("bad map after setting values: %#v", m)
+	}
+
+	if v, _ := m.Get(key); v != "F" {
+		t.Errorf("bad value of %v after setting it: %#v", key, v)
+	}
+}
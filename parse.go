@@ -124,6 +124,115 @@ type bytesDecoder interface {
 	DecodeBytes([]byte) ([]byte, error)
 }
 
+// DirectParser may be implemented by parsers that expose values which are
+// already held in memory as Go values (for example a parser reading from a
+// map[string]interface{}).
+//
+// When the value returned by ParseValue can be assigned to the destination
+// of the decoder, it is assigned directly instead of going through the decoding
+// algorithms. Note that maps and slices are not copied in this case, the
+// decoded value shares its memory with the one exposed by the parser.
+//
+// Values are never assigned directly when the decoder has a StringHook, a
+// ValueHook or a ControlCharPolicy, or to struct fields which have a decoder
+// registered with RegisterFieldDecoder.
+type DirectParser interface {
+	Parser
+
+	// ParseValue returns the next value as a Go value, setting ok to false if
+	// the value cannot be exposed this way.
+	//
+	// Like ParseType, ParseValue must be idempotent and must not change the
+	// state of the parser. When the decoder uses the returned value it does
+	// not call any other methods to parse it, so the parser must be capable of
+	// moving past the value when the next call to ParseArrayNext,
+	// ParseArrayEnd, ParseMapValue, ParseMapNext or ParseMapEnd occurs. This is
+	// usually the case of parsers exposing in-memory values.
+	ParseValue() (v interface{}, ok bool)
+}
+
+// RawParser may be implemented by parsers that are capable of returning the
+// serialized representation of the next value without interpreting it, it is
+// used to decode RawValue destinations.
+type RawParser interface {
+	Parser
+
+	// ParseRaw consumes the next value and returns its serialized bytes. The
+	// returned slice may be reused by the parser after the next call to one of
+	// its methods, callers must make a copy if they need to retain it.
+	ParseRaw() ([]byte, error)
+}
+
+// SkipParser may be implemented by parsers that are capable of moving past the
+// next value without parsing it, it is used to discard the values of unknown
+// struct fields without materializing them.
+type SkipParser interface {
+	Parser
+
+	// SkipValue consumes the next value. Parsers are not required to validate
+	// the skipped value, as long as they can find where it ends.
+	SkipValue() error
+}
+
+// PositionParser may be implemented by parsers that track their position in
+// the input, it is used by decoders to report where the errors occurred.
+type PositionParser interface {
+	Parser
+
+	// Position returns the current line and column (both starting at 1), and
+	// the byte offset (starting at 0) of the parser in its input.
+	Position() (line int, col int, offset int)
+}
+
+// Named may be implemented by parsers to report the name of the format that
+// they are decoding (for example "json"), it is used to fill struct fields with
+// the `format` tag option.
+type Named interface {
+	// FormatName returns the name of the format parsed by the parser.
+	FormatName() string
+}
+
+func formatName(p Parser) string {
+	if n, ok := p.(Named); ok {
+		return n.FormatName()
+	}
+	return ""
+}
+
+// DeadlineParser may be implemented by parsers reading from sources which
+// support read deadlines, like network connections. It is used by stream
+// decoders to enforce their idle timeout.
+type DeadlineParser interface {
+	Parser
+
+	// SetReadDeadline sets the time after which the parser gives up waiting
+	// for input, a zero value means no deadline. The errors returned by the
+	// parser when the deadline is exceeded must have a Timeout method which
+	// returns true, like the standard os.ErrDeadlineExceeded error.
+	SetReadDeadline(t time.Time) error
+}
+
+// Reparser may be implemented by parsers which also implement RawParser, to
+// create parsers of the same format reading the bytes returned by ParseRaw. It
+// is used by stream decoders to decode values while retaining their raw bytes.
+type Reparser interface {
+	// Reparse returns a new parser which reads the serialized value b.
+	Reparse(b []byte) Parser
+}
+
+// EOFParser may be implemented by parsers which can detect that their input was
+// fully consumed, it is used by Decoder.DecodeExact to reject inputs which have
+// trailing data after the decoded value.
+type EOFParser interface {
+	Parser
+
+	// ParseEOF returns nil if there is nothing left to parse in the input
+	// (formats may allow trailing white spaces), or an error if more data
+	// follows. Parsers which cannot tell, for example because they wrap other
+	// parsers, return ErrEOFUnsupported so the check is skipped.
+	ParseEOF() error
+}
+
 // The textParser interface may be implemented by parsers of human-readable
 // formats. Such parsers instruct the encoder to prefer using
 // encoding.TextUnmarshaler over encoding.BinaryUnmarshaler for example.
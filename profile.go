@@ -0,0 +1,97 @@
+package objconv
+
+import "math/bits"
+
+// SizeProfile records the distribution of sizes of the strings, arrays and maps
+// seen by a decoder.
+//
+// Programs that decode many similar documents can use the profile to pre-size
+// the buffers and data structures used by future decoding operations.
+//
+// A SizeProfile is not safe for use by multiple goroutines.
+type SizeProfile struct {
+	// Strings records the lengths of strings and byte slices.
+	Strings SizeHistogram
+
+	// Arrays records the number of elements of arrays.
+	Arrays SizeHistogram
+
+	// Maps records the number of entries of maps.
+	Maps SizeHistogram
+}
+
+// Reset clears all the histograms of the profile.
+func (p *SizeProfile) Reset() {
+	*p = SizeProfile{}
+}
+
+// SizeHistogram is a histogram of sizes, it groups values in power of two
+// buckets so it uses a constant amount of memory regardless of the number of
+// values it observed.
+type SizeHistogram struct {
+	buckets [65]int
+	count   int
+	max     int
+}
+
+// Observe adds n to the histogram.
+func (h *SizeHistogram) Observe(n int) {
+	if n < 0 {
+		return
+	}
+	h.buckets[bits.Len(uint(n))]++
+	h.count++
+	if n > h.max {
+		h.max = n
+	}
+}
+
+// Count returns the number of values observed by the histogram.
+func (h *SizeHistogram) Count() int {
+	return h.count
+}
+
+// Max returns the largest value observed by the histogram.
+func (h *SizeHistogram) Max() int {
+	return h.max
+}
+
+// Percentile returns an upper bound of the p-th percentile of the values
+// observed by the histogram, where p is between 0 and 100.
+//
+// Because values are grouped in power of two buckets the result is the upper
+// bound of the bucket containing the percentile (never more than Max), which
+// makes it suitable for sizing buffers.
+func (h *SizeHistogram) Percentile(p float64) int {
+	if h.count == 0 {
+		return 0
+	}
+
+	switch {
+	case p < 0:
+		p = 0
+	case p > 100:
+		p = 100
+	}
+
+	rank := int(p / 100 * float64(h.count))
+	if rank >= h.count {
+		rank = h.count - 1
+	}
+
+	sum := 0
+
+	for i, n := range h.buckets {
+		if sum += n; sum > rank {
+			if i == 0 {
+				return 0
+			}
+			if max := (1 << uint(i)) - 1; max < h.max {
+				return max
+			}
+			break
+		}
+	}
+
+	return h.max
+}
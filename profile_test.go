@@ -0,0 +1,78 @@
+package objconv
+
+import "testing"
+
+func TestSizeHistogram(t *testing.T) {
+	h := SizeHistogram{}
+
+	if p := h.Percentile(50); p != 0 {
+		t.Error("empty histogram percentile:", p)
+	}
+
+	for i := 1; i <= 100; i++ {
+		h.Observe(i)
+	}
+
+	if n := h.Count(); n != 100 {
+		t.Error("count:", n)
+	}
+
+	if n := h.Max(); n != 100 {
+		t.Error("max:", n)
+	}
+
+	tests := []struct {
+		p float64
+		n int
+	}{
+		{0, 1},
+		{10, 15},
+		{50, 63},
+		{90, 100},
+		{100, 100},
+	}
+
+	for _, test := range tests {
+		if n := h.Percentile(test.p); n != test.n {
+			t.Errorf("percentile %g: %d != %d", test.p, n, test.n)
+		}
+	}
+}
+
+func TestDecoderSizeProfile(t *testing.T) {
+	p := &SizeProfile{}
+	d := Decoder{
+		Parser: NewValueParser(map[string]interface{}{
+			"list": []interface{}{"a", "bb", "ccc"},
+			"name": "hello",
+		}),
+		SizeProfile: p,
+	}
+
+	var v interface{}
+
+	if err := d.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := p.Maps.Count(); n != 1 {
+		t.Error("maps count:", n)
+	}
+
+	if n := p.Maps.Max(); n != 2 {
+		t.Error("maps max:", n)
+	}
+
+	if n := p.Arrays.Max(); n != 3 {
+		t.Error("arrays max:", n)
+	}
+
+	// 2 keys + 4 values
+	if n := p.Strings.Count(); n != 6 {
+		t.Error("strings count:", n)
+	}
+
+	if n := p.Strings.Max(); n != 5 {
+		t.Error("strings max:", n)
+	}
+}
@@ -0,0 +1,260 @@
+package protobuf
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/segmentio/objconv"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Message is an adapter which implements the objconv.ValueDecoder interface on
+// top of a protocol buffer message.
+type Message struct {
+	proto.Message
+}
+
+// DecodeValue satisfies the objconv.ValueDecoder interface.
+func (m Message) DecodeValue(d objconv.Decoder) error {
+	return decodeMessage(d, m.ProtoReflect())
+}
+
+// Decode loads the next value parsed by d into m.
+func Decode(d objconv.Decoder, m proto.Message) error {
+	return d.Decode(Message{m})
+}
+
+func decodeMessage(d objconv.Decoder, m protoreflect.Message) (err error) {
+	var t objconv.Type
+
+	if t, err = d.Parser.ParseType(); err != nil {
+		return
+	}
+
+	if t == objconv.Nil {
+		return d.Parser.ParseNil()
+	}
+
+	switch desc := m.Descriptor(); desc.FullName() {
+	case "google.protobuf.Timestamp":
+		return decodeTimestamp(d, m)
+
+	case "google.protobuf.Duration":
+		return decodeDuration(d, m)
+
+	case "google.protobuf.DoubleValue",
+		"google.protobuf.FloatValue",
+		"google.protobuf.Int64Value",
+		"google.protobuf.UInt64Value",
+		"google.protobuf.Int32Value",
+		"google.protobuf.UInt32Value",
+		"google.protobuf.BoolValue",
+		"google.protobuf.StringValue",
+		"google.protobuf.BytesValue":
+		fd := desc.Fields().ByName("value")
+		return d.Decode(objconv.ValueDecoderFunc(func(d objconv.Decoder) error {
+			return decodeField(d, m, fd)
+		}))
+	}
+
+	fields := m.Descriptor().Fields()
+
+	return d.DecodeMap(func(kd objconv.Decoder, vd objconv.Decoder) (err error) {
+		var k string
+
+		if err = kd.Decode(&k); err != nil {
+			return
+		}
+
+		fd := fields.ByJSONName(k)
+		if fd == nil {
+			fd = fields.ByName(protoreflect.Name(k))
+		}
+
+		if fd == nil {
+			return vd.Decode(nil) // discard
+		}
+
+		return vd.Decode(objconv.ValueDecoderFunc(func(d objconv.Decoder) error {
+			return decodeField(d, m, fd)
+		}))
+	})
+}
+
+func decodeField(d objconv.Decoder, m protoreflect.Message, fd protoreflect.FieldDescriptor) (err error) {
+	var t objconv.Type
+
+	if t, err = d.Parser.ParseType(); err != nil {
+		return
+	}
+
+	if t == objconv.Nil {
+		if err = d.Parser.ParseNil(); err == nil {
+			m.Clear(fd)
+		}
+		return
+	}
+
+	switch {
+	case fd.IsList():
+		l := m.Mutable(fd).List()
+		l.Truncate(0)
+		return d.DecodeArray(func(d objconv.Decoder) (err error) {
+			var v protoreflect.Value
+			if v, err = decodeValue(d, fd, l.NewElement); err == nil {
+				l.Append(v)
+			}
+			return
+		})
+
+	case fd.IsMap():
+		mp := m.Mutable(fd).Map()
+		return d.DecodeMap(func(kd objconv.Decoder, vd objconv.Decoder) (err error) {
+			var k protoreflect.Value
+			var v protoreflect.Value
+
+			if k, err = decodeValue(kd, fd.MapKey(), nil); err != nil {
+				return
+			}
+
+			if v, err = decodeValue(vd, fd.MapValue(), mp.NewValue); err != nil {
+				return
+			}
+
+			mp.Set(k.MapKey(), v)
+			return
+		})
+
+	default:
+		var v protoreflect.Value
+		if v, err = decodeValue(d, fd, func() protoreflect.Value { return m.NewField(fd) }); err == nil {
+			m.Set(fd, v)
+		}
+		return
+	}
+}
+
+func decodeValue(d objconv.Decoder, fd protoreflect.FieldDescriptor, newValue func() protoreflect.Value) (v protoreflect.Value, err error) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		var x bool
+		err = d.Decode(&x)
+		v = protoreflect.ValueOfBool(x)
+
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		var x int32
+		err = d.Decode(&x)
+		v = protoreflect.ValueOfInt32(x)
+
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		var x int64
+		err = d.Decode(&x)
+		v = protoreflect.ValueOfInt64(x)
+
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		var x uint32
+		err = d.Decode(&x)
+		v = protoreflect.ValueOfUint32(x)
+
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		var x uint64
+		err = d.Decode(&x)
+		v = protoreflect.ValueOfUint64(x)
+
+	case protoreflect.FloatKind:
+		var x float32
+		err = d.Decode(&x)
+		v = protoreflect.ValueOfFloat32(x)
+
+	case protoreflect.DoubleKind:
+		var x float64
+		err = d.Decode(&x)
+		v = protoreflect.ValueOfFloat64(x)
+
+	case protoreflect.StringKind:
+		var x string
+		err = d.Decode(&x)
+		v = protoreflect.ValueOfString(x)
+
+	case protoreflect.BytesKind:
+		var x []byte
+		err = d.Decode(&x)
+		v = protoreflect.ValueOfBytes(x)
+
+	case protoreflect.EnumKind:
+		var x protoreflect.EnumNumber
+		err = d.Decode(objconv.ValueDecoderFunc(func(d objconv.Decoder) error {
+			return decodeEnum(d, fd.Enum(), &x)
+		}))
+		v = protoreflect.ValueOfEnum(x)
+
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		v = newValue()
+		err = d.Decode(objconv.ValueDecoderFunc(func(d objconv.Decoder) error {
+			return decodeMessage(d, v.Message())
+		}))
+
+	default:
+		err = fmt.Errorf("objconv/protobuf: unsupported kind %s for field %s", fd.Kind(), fd.FullName())
+	}
+
+	return
+}
+
+func decodeEnum(d objconv.Decoder, ed protoreflect.EnumDescriptor, x *protoreflect.EnumNumber) (err error) {
+	var t objconv.Type
+
+	if t, err = d.Parser.ParseType(); err != nil {
+		return
+	}
+
+	switch t {
+	case objconv.String, objconv.Bytes:
+		var s string
+
+		if err = d.Decode(&s); err != nil {
+			return
+		}
+
+		v := ed.Values().ByName(protoreflect.Name(s))
+		if v == nil {
+			return fmt.Errorf("objconv/protobuf: invalid value %q for enum %s", s, ed.FullName())
+		}
+
+		*x = v.Number()
+		return
+
+	default:
+		var n int32
+		err = d.Decode(&n)
+		*x = protoreflect.EnumNumber(n)
+		return
+	}
+}
+
+func decodeTimestamp(d objconv.Decoder, m protoreflect.Message) (err error) {
+	var t time.Time
+
+	if err = d.Decode(&t); err != nil {
+		return
+	}
+
+	fields := m.Descriptor().Fields()
+	m.Set(fields.ByName("seconds"), protoreflect.ValueOfInt64(t.Unix()))
+	m.Set(fields.ByName("nanos"), protoreflect.ValueOfInt32(int32(t.Nanosecond())))
+	return
+}
+
+func decodeDuration(d objconv.Decoder, m protoreflect.Message) (err error) {
+	var t time.Duration
+
+	if err = d.Decode(&t); err != nil {
+		return
+	}
+
+	fields := m.Descriptor().Fields()
+	m.Set(fields.ByName("seconds"), protoreflect.ValueOfInt64(int64(t/time.Second)))
+	m.Set(fields.ByName("nanos"), protoreflect.ValueOfInt32(int32(t%time.Second)))
+	return
+}
@@ -0,0 +1,96 @@
+package protobuf
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/segmentio/objconv"
+	"github.com/segmentio/objconv/json"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestDecodeMessage(t *testing.T) {
+	const input = `{
+		"name": "Point",
+		"field": [
+			{"name": "x", "number": 1, "type": "TYPE_INT64", "jsonName": "x"},
+			{"name": "y", "number": 2, "type": 3, "unknown": [1, 2, 3]}
+		],
+		"options": {"deprecated": true},
+		"reservedName": null
+	}`
+
+	m := &descriptorpb.DescriptorProto{}
+
+	if err := Decode(*json.NewDecoder(strings.NewReader(input)), m); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := &descriptorpb.DescriptorProto{
+		Name: proto.String("Point"),
+		Field: []*descriptorpb.FieldDescriptorProto{
+			{
+				Name:     proto.String("x"),
+				Number:   proto.Int32(1),
+				Type:     descriptorpb.FieldDescriptorProto_TYPE_INT64.Enum(),
+				JsonName: proto.String("x"),
+			},
+			{
+				Name:   proto.String("y"),
+				Number: proto.Int32(2),
+				Type:   descriptorpb.FieldDescriptorProto_TYPE_INT64.Enum(),
+			},
+		},
+		Options: &descriptorpb.MessageOptions{
+			Deprecated: proto.Bool(true),
+		},
+	}
+
+	if !proto.Equal(m, expect) {
+		t.Errorf("%v != %v", m, expect)
+	}
+}
+
+func TestDecodeWellKnownTypes(t *testing.T) {
+	date := time.Date(2016, 12, 12, 1, 1, 1, 500, time.UTC)
+
+	tests := []struct {
+		in  interface{}
+		out proto.Message
+		exp proto.Message
+	}{
+		{date, &timestamppb.Timestamp{}, timestamppb.New(date)},
+		{"2016-12-12T01:01:01.0000005Z", &timestamppb.Timestamp{}, timestamppb.New(date)},
+		{1500 * time.Millisecond, &durationpb.Duration{}, durationpb.New(1500 * time.Millisecond)},
+		{"1.5s", &durationpb.Duration{}, durationpb.New(1500 * time.Millisecond)},
+		{int64(42), &wrapperspb.Int64Value{}, wrapperspb.Int64(42)},
+		{"hello", &wrapperspb.StringValue{}, wrapperspb.String("hello")},
+		{true, &wrapperspb.BoolValue{}, wrapperspb.Bool(true)},
+		{0.5, &wrapperspb.DoubleValue{}, wrapperspb.Double(0.5)},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.exp.ProtoReflect().Descriptor().Name()), func(t *testing.T) {
+			if err := Decode(objconv.Decoder{Parser: objconv.NewValueParser(test.in)}, test.out); err != nil {
+				t.Fatal(err)
+			}
+			if !proto.Equal(test.out, test.exp) {
+				t.Errorf("%v != %v", test.out, test.exp)
+			}
+		})
+	}
+}
+
+func TestDecodeInvalidEnum(t *testing.T) {
+	m := &descriptorpb.FieldDescriptorProto{}
+	d := json.NewDecoder(strings.NewReader(`{"type":"TYPE_WHATEVER"}`))
+
+	if err := Decode(*d, m); err == nil {
+		t.Error("expected an error decoding an invalid enum value")
+	}
+}
@@ -0,0 +1,11 @@
+// Package protobuf provides support for decoding objconv value streams into
+// protocol buffer messages.
+//
+// The package lives in its own module so programs that don't need it are not
+// forced to depend on the protobuf runtime.
+//
+// Fields are matched by their JSON name first, then by their declared name.
+// The well-known types google.protobuf.Timestamp, google.protobuf.Duration and
+// the google.protobuf.*Value wrappers are decoded from the scalar values that
+// they represent instead of maps.
+package protobuf
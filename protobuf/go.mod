@@ -0,0 +1,8 @@
+module github.com/segmentio/objconv/protobuf
+
+go 1.23
+
+require (
+	github.com/segmentio/objconv v1.0.1
+	google.golang.org/protobuf v1.36.12
+)
@@ -0,0 +1,143 @@
+package objconv
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+)
+
+// RawValue is a raw serialized value. It can be used as a decode destination to
+// delay decoding a value, for example until a discriminator has been read from
+// another key of the same map:
+//
+//	var m map[string]objconv.RawValue
+//
+//	if err := json.Unmarshal(b, &m); err != nil {
+//		...
+//	}
+//
+//	var v Value
+//	if err := json.Unmarshal(m["value"], &v); err != nil {
+//		...
+//	}
+//
+// The bytes are in the format of the parser that the value was decoded from.
+type RawValue []byte
+
+var rawValueMapType = reflect.TypeOf(map[string]RawValue(nil))
+
+// DecodeValue satisfies the ValueDecoder interface.
+func (r *RawValue) DecodeValue(d Decoder) (err error) {
+	var b []byte
+
+	if p, ok := d.Parser.(RawParser); ok {
+		if b, err = p.ParseRaw(); err != nil {
+			return
+		}
+		*r = append((*r)[:0], b...)
+		return
+	}
+
+	if d.RawEmitter == nil {
+		return errors.New("objconv: the parser doesn't support raw values and no RawEmitter was configured on the decoder")
+	}
+
+	var v interface{}
+	var buf bytes.Buffer
+
+	if err = d.Decode(&v); err != nil {
+		return
+	}
+
+	if err = NewEncoder(d.RawEmitter(&buf)).Encode(v); err != nil {
+		return
+	}
+
+	*r = append((*r)[:0], buf.Bytes()...)
+	return
+}
+
+// LazyValue is a value whose decoding is delayed until its Decode method is
+// called. Lazy values are produced by decoders configured with Lazy, or can be
+// used as decode destinations, for example to load a configuration file and
+// decode each of its sections into a different type:
+//
+//	var m map[string]objconv.LazyValue
+//
+//	if err := json.Unmarshal(b, &m); err != nil {
+//		...
+//	}
+//
+//	var s Server
+//	if err := m["server"].Decode(&s); err != nil {
+//		...
+//	}
+//
+// When the parser implements both RawParser and Reparser (like json), the lazy
+// value only retains the raw bytes of the value, which are parsed again when
+// Decode is called. With other parsers the value is eagerly decoded into an
+// empty interface, and replayed by Decode, so errors that would have been
+// reported for the destination type are only reported by Decode but the full
+// cost of decoding is paid upfront.
+//
+// Decode uses the same configuration than the decoder which produced the lazy
+// value, and may be called multiple times. The zero value decodes as a null
+// value.
+type LazyValue struct {
+	raw    []byte
+	parser Reparser
+	value  interface{}
+	dec    Decoder
+}
+
+// Raw returns the raw serialized value, or nil if the parser that the value
+// was decoded from doesn't support capturing raw values.
+func (v LazyValue) Raw() []byte {
+	if v.parser == nil {
+		return nil
+	}
+	return v.raw
+}
+
+// Decode decodes the lazy value into x.
+func (v LazyValue) Decode(x interface{}) error {
+	d := v.dec
+
+	if v.parser != nil {
+		d.Parser = v.parser.Reparse(v.raw)
+	} else {
+		d.Parser = replayParser{ValueParser: NewValueParser(v.value), parser: d.Parser}
+	}
+
+	return d.Decode(x)
+}
+
+// DecodeValue satisfies the ValueDecoder interface.
+func (v *LazyValue) DecodeValue(d Decoder) (err error) {
+	// Values decoded from lazy values are decoded eagerly, only the first
+	// level is delayed.
+	d.Lazy = false
+	p, ok := d.Parser.(RawParser)
+	rp, ok2 := d.Parser.(Reparser)
+
+	if ok && ok2 {
+		var b []byte
+
+		if b, err = p.ParseRaw(); err != nil {
+			return
+		}
+
+		d.Parser = nil // replaced by Decode, don't retain the parser
+		*v = LazyValue{raw: append([]byte(nil), b...), parser: rp, dec: d}
+		return
+	}
+
+	var x interface{}
+
+	if err = d.loader().Decode(&x); err != nil {
+		return
+	}
+
+	*v = LazyValue{value: x, dec: d}
+	return
+}
@@ -38,6 +38,17 @@ func (p *Parser) Buffered() io.Reader {
 	return bytes.NewReader(p.s[p.n:])
 }
 
+// FormatName satisfies the objconv.Named interface.
+func (p *Parser) FormatName() string {
+	return "resp"
+}
+
+// SetReadDeadline satisfies the objconv.DeadlineParser interface, it returns an
+// error if the reader of the parser doesn't support read deadlines.
+func (p *Parser) SetReadDeadline(t time.Time) error {
+	return objutil.SetReadDeadline(p.r, t)
+}
+
 func (p *Parser) ParseType() (t objconv.Type, err error) {
 	var line []byte
 
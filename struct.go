@@ -1,7 +1,10 @@
 package objconv
 
 import (
+	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/segmentio/objconv/objutil"
@@ -16,6 +19,10 @@ type structField struct {
 	// The name of the field in the structure.
 	name string
 
+	// Alternative names of the field, which are matched with the keys of maps
+	// when decoding but never used when encoding.
+	aliases []string
+
 	// Omitempty is set to true when the field should be omitted if it has an
 	// empty value.
 	omitempty bool
@@ -24,6 +31,78 @@ type structField struct {
 	// value.
 	omitzero bool
 
+	// Required is set to true when decoding a struct must fail if the field
+	// is not present in the input.
+	required bool
+
+	// Warnings is set to true when the field should receive the list of
+	// warnings produced while decoding the struct.
+	warnings bool
+
+	// DiscriminatorValue is set to true when the field should receive the
+	// discriminator which selected the struct type when decoding a union.
+	discriminatorValue bool
+
+	// UnknownFields is set to true when the field should receive the keys that
+	// did not match any other field of the struct, either as a list of names or
+	// as a map of raw values.
+	unknownFields bool
+
+	// Extra is set to true when the field should receive the values of the
+	// keys that did not match any other field of the struct, which are also
+	// encoded inline with the other fields.
+	extra bool
+
+	// Format is set to true when the field should receive the name of the
+	// format that the struct was decoded from.
+	format bool
+
+	// FieldErrors is set to true when the field should receive the errors
+	// that occurred while decoding the other fields of the struct.
+	fieldErrors bool
+
+	// TrimPrefix and TrimSuffix are removed from the value of string fields
+	// when decoding, and added back when encoding.
+	trimPrefix string
+	trimSuffix string
+
+	// UniqueBy is the name of the field of the elements of slices of structs
+	// used to deduplicate them, uniqueIndex is the index of this field.
+	uniqueBy    string
+	uniqueIndex []int
+
+	// OneOfGroup is the name of the group of fields which exactly one must be
+	// set by the decoded input, declared by each of the fields of the group.
+	oneOfGroup string
+
+	// Path is the JSON pointer locating the value of the field in the decoded
+	// document, instead of looking it up by name.
+	path string
+
+	// DefaultValue is the raw value decoded into the field when its key is
+	// absent from the input. Defaults is the struct type of the field when it
+	// is a struct which has fields with default values, which are applied when
+	// the key of the field is absent.
+	defaultValue string
+	defaults     *structType
+
+	// Position of the field in the fields of the struct type, used to track
+	// the fields seen when decoding.
+	position int
+
+	// Registered is set to true when decode is a function registered with
+	// RegisterFieldDecoder, which must be called even when the parser exposes
+	// values that could be assigned to the field directly.
+	registered bool
+
+	// Promoted is set to true when the field belongs to an embedded struct,
+	// promoted fields are decoded but never encoded.
+	promoted bool
+
+	// Anonymous is set to true when the field is an embedded struct given a
+	// name by its tag, which is decoded like other fields but never encoded.
+	anonymous bool
+
 	// cache for the encoder and decoder methods
 	encode encodeFunc
 	decode decodeFunc
@@ -50,6 +129,20 @@ func makeStructField(f reflect.StructField, c map[reflect.Type]*structType) stru
 		name:      f.Name,
 		omitempty: t.Omitempty,
 		omitzero:  t.Omitzero,
+		required:  t.Required,
+		warnings:  t.Warnings,
+
+		discriminatorValue: t.DiscriminatorValue,
+		unknownFields:      t.UnknownFields,
+		extra:              t.Extra,
+		format:             t.Format,
+		fieldErrors:        t.FieldErrors,
+		trimPrefix:         t.TrimPrefix,
+		trimSuffix:         t.TrimSuffix,
+		uniqueBy:           t.UniqueBy,
+		oneOfGroup:         t.OneOfGroup,
+		path:               t.JSONPath,
+		defaultValue:       t.Default,
 
 		encode: makeEncodeFunc(f.Type, encodeFuncOpts{
 			recurse: true,
@@ -66,9 +159,114 @@ func makeStructField(f reflect.StructField, c map[reflect.Type]*structType) stru
 		s.name = t.Name
 	}
 
+	if len(t.Alias) != 0 {
+		s.aliases = strings.Split(t.Alias, "|")
+	}
+
+	if s.trimmed() && f.Type.Kind() == reflect.String {
+		prefix, suffix := s.trimPrefix, s.trimSuffix
+		s.encode = func(e Encoder, v reflect.Value) error {
+			return e.Emitter.EmitString(prefix + v.String() + suffix)
+		}
+	}
+
 	return s
 }
 
+// decodeOnly returns true if the field is decoded but not encoded, because it
+// is promoted from or is itself an embedded struct.
+func (f *structField) decodeOnly() bool {
+	return f.promoted || f.anonymous
+}
+
+func (f *structField) trimmed() bool {
+	return len(f.trimPrefix) != 0 || len(f.trimSuffix) != 0
+}
+
+// postDecode applies the transformations configured on the field to v, which
+// is the value that was just decoded into it.
+func (f *structField) postDecode(v reflect.Value) {
+	if f.trimmed() {
+		v.SetString(strings.TrimSuffix(strings.TrimPrefix(v.String(), f.trimPrefix), f.trimSuffix))
+	}
+	if f.uniqueIndex != nil {
+		f.dedup(v)
+	}
+}
+
+// dedup removes the elements of the slice v which have the same value of the
+// uniqueby field than an element before them. The last of the duplicates wins,
+// at the position of the first one. Elements which are nil, or which uniqueby
+// field cannot be reached because it is promoted through a nil pointer to an
+// embedded struct, are always kept.
+func (f *structField) dedup(v reflect.Value) {
+	n := v.Len()
+	j := 0
+	seen := make(map[interface{}]int, n)
+
+	for i := 0; i != n; i++ {
+		e := v.Index(i)
+		k, ok := e, true
+
+		if k.Kind() == reflect.Ptr {
+			if ok = !k.IsNil(); ok {
+				k = k.Elem()
+			}
+		}
+
+		if ok {
+			k, ok = lookupFieldByIndex(k, f.uniqueIndex)
+		}
+
+		if !ok {
+			v.Index(j).Set(e)
+			j++
+			continue
+		}
+
+		key := k.Interface()
+
+		if p, ok := seen[key]; ok {
+			v.Index(p).Set(e)
+			continue
+		}
+
+		seen[key] = j
+		v.Index(j).Set(e)
+		j++
+	}
+
+	for i, z := j, reflect.Zero(v.Type().Elem()); i != n; i++ {
+		v.Index(i).Set(z) // release the references held by the removed elements
+	}
+
+	v.SetLen(j)
+}
+
+// uniqueIndexOf returns the index of the field named name in the elements of
+// the slice type t, which must be structs or pointers to structs.
+func uniqueIndexOf(t reflect.Type, name string) ([]int, bool) {
+	if t.Kind() != reflect.Slice {
+		return nil, false
+	}
+
+	e := t.Elem()
+	if e.Kind() == reflect.Ptr {
+		e = e.Elem()
+	}
+
+	if e.Kind() != reflect.Struct {
+		return nil, false
+	}
+
+	f, ok := e.FieldByName(name)
+	if !ok || !f.Type.Comparable() || f.Type.Kind() == reflect.Interface {
+		return nil, false
+	}
+
+	return f.Index, true
+}
+
 func (f *structField) omit(v reflect.Value) bool {
 	return (f.omitempty && objutil.IsEmptyValue(v)) || (f.omitzero && objutil.IsZeroValue(v))
 }
@@ -79,6 +277,23 @@ func (f *structField) omit(v reflect.Value) bool {
 type structType struct {
 	fields       []structField           // the serializable fields of the struct
 	fieldsByName map[string]*structField // cache of fields by name
+	aliases      map[string]*structField // cache of fields by alias
+	warnings     []int                   // index of the field receiving decode warnings
+	discriminant []int                   // index of the field receiving the union discriminator
+	unknown      []int                   // index of the field receiving the unknown keys
+	unknownRaw   bool                    // whether the unknown keys are captured with their raw values
+	extra        []int                   // index of the field receiving the values of the unknown keys
+	format       []int                   // index of the field receiving the name of the source format
+	fieldErrors  []int                   // index of the field receiving the errors of the other fields
+	paths        []int                   // positions of the fields decoded from a JSON pointer
+	pathKeys     map[string]bool         // top-level keys that the JSON pointers resolve through
+	oneOf        []oneOfGroup            // groups of mutually exclusive fields
+	required     bool                    // whether some fields are required
+	flat         bool                    // whether the struct can be decoded with decodeFlatStruct
+	positional   bool                    // whether the struct is decoded from arrays by position
+	defaults     bool                    // whether some fields have default values, including in nested structs
+	embedded     []reflect.StructField   // embedded structs whose fields are promoted
+	err          error                   // error detected while building the struct type
 }
 
 // newStructType takes a Go type as argument and extract information to make a
@@ -96,26 +311,664 @@ func newStructType(t reflect.Type, c map[reflect.Type]*structType) *structType {
 	}
 	c[t] = s
 
+	var groups []objutil.Tag
+	var embedded []reflect.StructField
+
 	for i := 0; i != n; i++ {
 		ft := t.Field(i)
 
-		if ft.Anonymous || len(ft.PkgPath) != 0 { // anonymous or non-exported
+		// Groups of mutually exclusive fields and the positional option may be
+		// declared on any field, including placeholders like `_ struct{}`
+		// which are not serialized.
+		if tag := ft.Tag.Get("objconv"); len(tag) != 0 {
+			g := objutil.ParseTag(tag)
+			if len(g.OneOf) != 0 || len(g.ExactlyOneOf) != 0 {
+				groups = append(groups, g)
+			}
+			if g.Positional {
+				s.positional = true
+			}
+		}
+
+		// The fields of embedded structs are promoted to the struct when
+		// decoding unless the embedded field is given a name by its tag, in
+		// which case it's decoded like any other field. Embedded fields are
+		// never encoded either way.
+		if ft.Anonymous && !hasTagName(ft) {
+			if isEmbeddedStruct(ft) {
+				embedded = append(embedded, ft)
+			}
+			continue
+		}
+
+		// Non-exported fields cannot be set with reflection, they are never
+		// part of the struct fields so the keys matching their names are
+		// handled like any other unknown key, even if they have a tag.
+		if len(ft.PkgPath) != 0 { // non-exported
 			continue
 		}
 
 		sf := makeStructField(ft, c)
+		sf.anonymous = ft.Anonymous
 
 		if sf.name == "-" { // skip
 			continue
 		}
 
+		if decode, ok := fieldDecoderOf(t, ft.Name); ok {
+			sf.decode = func(d Decoder, v reflect.Value) (Type, error) {
+				return Unknown /* just needs to not be Nil */, decode(d, v)
+			}
+			sf.registered = true
+		}
+
+		// Special fields receive information about the decoding of the struct
+		// instead of a value, they are not part of the struct fields.
+		switch {
+		case sf.warnings:
+			s.setSpecialField(t, ft, "warnings", ft.Type == stringsType, "of type []string", &s.warnings)
+			continue
+		case sf.discriminatorValue:
+			s.setSpecialField(t, ft, "discriminatorvalue", ft.Type.Kind() == reflect.String, "a string", &s.discriminant)
+			continue
+		case sf.unknownFields:
+			s.setSpecialField(t, ft, "unknownfields", ft.Type == stringsType || ft.Type == rawValueMapType, "of type []string or map[string]objconv.RawValue", &s.unknown)
+			s.unknownRaw = ft.Type == rawValueMapType
+			continue
+		case sf.extra:
+			s.setSpecialField(t, ft, "extra", ft.Type == mapStringInterfaceType, "of type map[string]interface{}", &s.extra)
+			continue
+		case sf.format:
+			s.setSpecialField(t, ft, "format", ft.Type.Kind() == reflect.String, "a string", &s.format)
+			continue
+		case sf.fieldErrors:
+			s.setSpecialField(t, ft, "fielderrors", ft.Type == fieldResultType, "of type objconv.FieldResult", &s.fieldErrors)
+			continue
+		}
+
+		if sf.trimmed() && ft.Type.Kind() != reflect.String {
+			s.err = fmt.Errorf("objconv: the field %s of %s has trimprefix or trimsuffix set but is not a string", ft.Name, t)
+			continue
+		}
+
+		if len(sf.uniqueBy) != 0 {
+			index, ok := uniqueIndexOf(ft.Type, sf.uniqueBy)
+			if !ok {
+				s.err = fmt.Errorf("objconv: the uniqueby field %q of %s of %s does not exist or is not comparable, the field must be a slice of structs", sf.uniqueBy, ft.Name, t)
+				continue
+			}
+			sf.uniqueIndex = index
+		}
+
+		if len(sf.path) != 0 {
+			key, ok := jsonPointerKey(sf.path)
+			if !ok {
+				s.err = fmt.Errorf("objconv: the jsonpath %q of field %s of %s is not a valid JSON pointer", sf.path, ft.Name, t)
+				continue
+			}
+			if s.pathKeys == nil {
+				s.pathKeys = make(map[string]bool)
+			}
+			s.pathKeys[key] = true
+			s.paths = append(s.paths, len(s.fields))
+			s.fields = append(s.fields, sf)
+			continue
+		}
+
 		s.fields = append(s.fields, sf)
-		s.fieldsByName[sf.name] = &s.fields[len(s.fields)-1]
 	}
 
+	if s.extra != nil && s.unknown != nil {
+		s.err = fmt.Errorf("objconv: %s cannot have both an extra and an unknownfields field", t)
+	}
+
+	s.embedded = embedded
+	s.promote(t, c)
+
+	// The lookup tables are built once all fields were added because
+	// promoting the fields of embedded structs may grow the slice.
+	for i := range s.fields {
+		f := &s.fields[i]
+		f.position = i
+		if len(f.path) == 0 {
+			s.fieldsByName[f.name] = f
+			s.required = s.required || f.required
+		}
+	}
+
+	for i := range s.fields {
+		for _, alias := range s.fields[i].aliases {
+			if s.aliases == nil {
+				s.aliases = make(map[string]*structField)
+			}
+			// When several fields declare the same alias the first one wins.
+			if _, exists := s.aliases[alias]; !exists {
+				s.aliases[alias] = &s.fields[i]
+			}
+		}
+	}
+
+	for _, g := range groups {
+		if len(g.OneOf) != 0 {
+			s.addOneOfGroup(t, g.OneOf, false)
+		}
+		if len(g.ExactlyOneOf) != 0 {
+			s.addOneOfGroup(t, g.ExactlyOneOf, true)
+		}
+	}
+
+	s.addOneOfGroupsOfFields(t)
+	s.addDefaults(t, c)
+	s.flat = s.isFlat(t)
 	return s
 }
 
+// isFlat returns true if all the fields of s, which was built from the struct
+// type t, are scalars declared directly in the struct, and s uses none of the
+// features which require bookkeeping while decoding (special fields, aliases,
+// required fields, ...).
+func (s *structType) isFlat(t reflect.Type) bool {
+	if s.err != nil || s.warnings != nil || s.discriminant != nil || s.unknown != nil ||
+		s.extra != nil || s.format != nil || s.fieldErrors != nil || s.paths != nil ||
+		s.aliases != nil || s.oneOf != nil || s.required || s.defaults {
+		return false
+	}
+
+	for i := range s.fields {
+		f := &s.fields[i]
+
+		if len(f.index) != 1 || f.trimmed() || f.uniqueIndex != nil {
+			return false
+		}
+
+		switch t.Field(f.index[0]).Type.Kind() {
+		case reflect.Bool, reflect.String,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+			reflect.Float32, reflect.Float64:
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// setSpecialField sets dst to the index of the field f of t, which has the
+// special tag option. The field must be of the type described by expect, which
+// valid reports, and t may only have one field with each special option.
+func (s *structType) setSpecialField(t reflect.Type, f reflect.StructField, option string, valid bool, expect string, dst *[]int) {
+	switch {
+	case !valid:
+		s.err = fmt.Errorf("objconv: the %s field %s of %s must be %s", option, f.Name, t, expect)
+	case *dst != nil:
+		s.err = fmt.Errorf("objconv: %s has more than one %s field", t, option)
+	default:
+		*dst = f.Index
+	}
+}
+
+// hasTagName returns true if the struct field f has a name set by its objconv
+// tag, or its json tag when it has no objconv tag.
+func hasTagName(f reflect.StructField) bool {
+	if tag := f.Tag.Get("objconv"); len(tag) != 0 {
+		return len(objutil.ParseTag(tag).Name) != 0
+	}
+	return len(objutil.ParseTagJSON(f.Tag.Get("json")).Name) != 0
+}
+
+// isEmbeddedStruct returns true if the embedded field f is a struct or a
+// pointer to a struct whose fields can be promoted. Pointers to non-exported
+// struct types cannot be allocated when decoding so they are excluded.
+func isEmbeddedStruct(f reflect.StructField) bool {
+	switch t := f.Type; t.Kind() {
+	case reflect.Struct:
+		return true
+	case reflect.Ptr:
+		return t.Elem().Kind() == reflect.Struct && len(f.PkgPath) == 0
+	default:
+		return false
+	}
+}
+
+// promote adds the fields of the embedded structs of s, which represents the
+// type t, following the rules of encoding/json: the fields of the struct take
+// precedence over the promoted fields, then the shallowest fields win over the
+// deeper ones. When multiple fields with the same name are at the same depth
+// the one given its name by a tag wins, and if there is none or more than one
+// all these fields are ignored.
+//
+// Fields located by JSON pointers and special fields (like the warnings field)
+// of embedded structs are not promoted.
+func (s *structType) promote(t reflect.Type, c map[reflect.Type]*structType) {
+	taken := make(map[string]bool, len(s.fields))
+	for _, f := range s.fields {
+		taken[f.name] = true
+	}
+
+	visited := map[reflect.Type]bool{t: true}
+	next := make([]embeddedStruct, 0, len(s.embedded))
+
+	for _, f := range s.embedded {
+		next = append(next, makeEmbeddedStruct(nil, f))
+	}
+
+	for len(next) != 0 {
+		current := next
+		next = nil
+
+		count := make(map[reflect.Type]int, len(current))
+		for _, es := range current {
+			count[es.typ]++
+		}
+
+		var fields []structField
+		var tagged []bool
+
+		for _, es := range current {
+			if visited[es.typ] {
+				continue
+			}
+			visited[es.typ] = true
+
+			e := newStructType(es.typ, c)
+			if e.err != nil {
+				s.err = e.err
+				return
+			}
+
+			for _, ef := range e.fields {
+				if ef.promoted || len(ef.path) != 0 || taken[ef.name] {
+					continue
+				}
+				named := hasTagName(es.typ.FieldByIndex(ef.index))
+				ef.index = append(es.index[:len(es.index):len(es.index)], ef.index...)
+				ef.promoted = true
+				fields = append(fields, ef)
+				tagged = append(tagged, named)
+
+				// The same type embedded multiple times at the same depth
+				// makes all its fields ambiguous.
+				if count[es.typ] > 1 {
+					fields = append(fields, ef)
+					tagged = append(tagged, named)
+				}
+			}
+
+			for _, f := range e.embedded {
+				next = append(next, makeEmbeddedStruct(es.index, f))
+			}
+		}
+
+		for i, f := range fields {
+			if taken[f.name] {
+				continue
+			}
+			dominant, n := -1, 0
+			for j := i; j != len(fields); j++ {
+				if fields[j].name != f.name {
+					continue
+				}
+				n++
+				if tagged[j] {
+					if dominant < 0 {
+						dominant = j
+					} else {
+						dominant = -2
+					}
+				}
+			}
+			if n == 1 {
+				dominant = i
+			}
+			if dominant >= 0 {
+				s.fields = append(s.fields, fields[dominant])
+			}
+			taken[f.name] = true
+		}
+	}
+}
+
+// embeddedStruct is an embedded struct found while promoting fields, index is
+// the index of the embedded field from the struct that promotes its fields.
+type embeddedStruct struct {
+	typ   reflect.Type
+	index []int
+}
+
+func makeEmbeddedStruct(index []int, f reflect.StructField) embeddedStruct {
+	t := f.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return embeddedStruct{
+		typ:   t,
+		index: append(index[:len(index):len(index)], f.Index...),
+	}
+}
+
+// fieldByIndex returns the field of the struct value v at index, allocating the
+// pointers to embedded structs that it goes through when they are nil.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i != 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// lookupFieldByIndex is like fieldByIndex but returns false instead of
+// allocating when one of the pointers to embedded structs is nil.
+func lookupFieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i != 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// fieldSet is a bitmask of the positions of struct fields.
+type fieldSet []uint64
+
+func makeFieldSet(n int) fieldSet {
+	return make(fieldSet, (n+63)/64)
+}
+
+func (s fieldSet) add(i int) { s[i/64] |= 1 << uint(i%64) }
+
+func (s fieldSet) has(i int) bool { return s[i/64]&(1<<uint(i%64)) != 0 }
+
+// addDefaults records which fields of s have default values, or are structs
+// which fields have default values.
+func (s *structType) addDefaults(t reflect.Type, c map[reflect.Type]*structType) {
+	for i := range s.fields {
+		f := &s.fields[i]
+
+		if len(f.path) != 0 {
+			continue
+		}
+
+		if len(f.defaultValue) != 0 {
+			s.defaults = true
+			continue
+		}
+
+		if ft := t.FieldByIndex(f.index).Type; ft.Kind() == reflect.Struct {
+			if fs := newStructType(ft, c); fs.defaults {
+				f.defaults = fs
+				s.defaults = true
+			}
+		}
+	}
+}
+
+// checkRequired returns an error listing the required fields of s which are
+// not in seen.
+func (s *structType) checkRequired(t reflect.Type, seen fieldSet) error {
+	var missing []string
+
+	for i := range s.fields {
+		if f := &s.fields[i]; f.required && len(f.path) == 0 && !seen.has(i) {
+			missing = append(missing, f.name)
+		}
+	}
+
+	switch len(missing) {
+	case 0:
+		return nil
+	case 1:
+		return fmt.Errorf("objconv: the required field %s of %s is missing", missing[0], t)
+	default:
+		return fmt.Errorf("objconv: the required fields %s of %s are missing", strings.Join(missing, ", "), t)
+	}
+}
+
+// oneOfGroup represents a group of mutually exclusive fields of a struct.
+type oneOfGroup struct {
+	names    []string // names of the fields in the group
+	fields   []int    // positions of the fields in the group
+	required bool     // whether one of the fields must be set
+}
+
+func (s *structType) addOneOfGroup(t reflect.Type, list string, required bool) {
+	g := oneOfGroup{
+		names:    strings.Split(list, "|"),
+		required: required,
+	}
+
+	for _, name := range g.names {
+		i := s.fieldIndex(name)
+		if i < 0 {
+			s.err = fmt.Errorf("objconv: the oneof group %q of %s refers to a field %q which does not exist", list, t, name)
+			return
+		}
+		if len(s.fields[i].path) != 0 {
+			s.err = fmt.Errorf("objconv: the field %s of %s is located by a jsonpath and cannot be part of the oneof group %q", name, t, list)
+			return
+		}
+		g.fields = append(g.fields, i)
+	}
+
+	s.oneOf = append(s.oneOf, g)
+}
+
+// addOneOfGroupsOfFields adds the groups declared by the fields of s with the
+// oneof option naming a group, in the order that the groups first appear.
+func (s *structType) addOneOfGroupsOfFields(t reflect.Type) {
+	var groups map[string]int
+
+	for i := range s.fields {
+		name := s.fields[i].oneOfGroup
+		if len(name) == 0 {
+			continue
+		}
+
+		j, ok := groups[name]
+		if !ok {
+			if groups == nil {
+				groups = make(map[string]int)
+			}
+			j = len(s.oneOf)
+			groups[name] = j
+			s.oneOf = append(s.oneOf, oneOfGroup{required: true})
+		}
+
+		if len(s.fields[i].path) != 0 {
+			s.err = fmt.Errorf("objconv: the field %s of %s is located by a jsonpath and cannot be part of the oneof group %q", s.fields[i].name, t, name)
+			return
+		}
+
+		g := &s.oneOf[j]
+		g.names = append(g.names, s.fields[i].name)
+		g.fields = append(g.fields, i)
+	}
+}
+
+// lookup returns the field matching the key name, the names of the fields are
+// looked up first so an alias never shadows the name of another field, then
+// their aliases.
+func (s *structType) lookup(name string) *structField {
+	if f := s.fieldsByName[name]; f != nil {
+		return f
+	}
+	return s.aliases[name]
+}
+
+// fieldIndex returns the position of the field with the given name in s, or -1
+// if there is none.
+func (s *structType) fieldIndex(name string) int {
+	for i := range s.fields {
+		if s.fields[i].name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// checkOneOf verifies that the groups of mutually exclusive fields of s have at
+// most one field set in v, or exactly one for groups that require it. A field is
+// set when its key was decoded (it is in seen) to a non-zero value, keys with
+// null or zero values don't count, and values that the field had before
+// decoding are ignored.
+func (s *structType) checkOneOf(v reflect.Value, seen fieldSet) error {
+	for _, g := range s.oneOf {
+		var set []string
+
+		for i, f := range g.fields {
+			if !seen.has(f) {
+				continue
+			}
+			if fv, ok := lookupFieldByIndex(v, s.fields[f].index); ok && !objutil.IsZeroValue(fv) {
+				set = append(set, g.names[i])
+			}
+		}
+
+		switch {
+		case len(set) > 1:
+			return fmt.Errorf("objconv: only one of the fields %s of %s may be set but %s were", strings.Join(g.names, ", "), v.Type(), strings.Join(set, " and "))
+		case len(set) == 0 && g.required:
+			return fmt.Errorf("objconv: one of the fields %s of %s must be set", strings.Join(g.names, ", "), v.Type())
+		}
+	}
+	return nil
+}
+
+// jsonPointerKey returns the first reference token of the JSON pointer p.
+func jsonPointerKey(p string) (key string, ok bool) {
+	if len(p) < 2 || p[0] != '/' {
+		return
+	}
+	if key = p[1:]; strings.IndexByte(key, '/') >= 0 {
+		key = key[:strings.IndexByte(key, '/')]
+	}
+	return unescapeJSONPointer(key), true
+}
+
+// resolveJSONPointer returns the value located by the JSON pointer p in v, as
+// defined by RFC 6901.
+func resolveJSONPointer(v interface{}, p string) (interface{}, bool) {
+	if len(p) == 0 || p[0] != '/' {
+		return nil, false
+	}
+
+	for _, token := range strings.Split(p[1:], "/") {
+		token = unescapeJSONPointer(token)
+		r := reflect.ValueOf(v)
+
+		switch r.Kind() {
+		case reflect.Map:
+			var k reflect.Value
+
+			switch t := r.Type().Key(); t.Kind() {
+			case reflect.String:
+				k = reflect.ValueOf(token).Convert(t)
+			case reflect.Interface:
+				k = reflect.ValueOf(token)
+			default:
+				return nil, false
+			}
+
+			if r = r.MapIndex(k); !r.IsValid() {
+				return nil, false
+			}
+
+		case reflect.Slice:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= r.Len() {
+				return nil, false
+			}
+			r = r.Index(i)
+
+		default:
+			return nil, false
+		}
+
+		v = r.Interface()
+	}
+
+	return v, true
+}
+
+func unescapeJSONPointer(s string) string {
+	if strings.IndexByte(s, '~') < 0 {
+		return s
+	}
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(s)
+}
+
+// fuzzyLookup returns the field with the name closest to name, as long as its
+// edit distance is lower or equal to max. The method returns an error if more
+// than one field is found at the smallest distance.
+func (s *structType) fuzzyLookup(name string, max int) (f *structField, err error) {
+	best := max + 1
+
+	for i := range s.fields {
+		if len(s.fields[i].path) != 0 {
+			continue
+		}
+		switch d := editDistance(name, s.fields[i].name); {
+		case d < best:
+			f, best, err = &s.fields[i], d, nil
+		case d == best && f != nil:
+			err = fmt.Errorf("objconv: ambiguous key %q matches both fields %q and %q", name, f.name, s.fields[i].name)
+		}
+	}
+
+	if err != nil {
+		f = nil
+	}
+	return
+}
+
+// editDistance computes the Levenshtein distance between a and b.
+func editDistance(a string, b string) int {
+	r1 := []rune(a)
+	r2 := []rune(b)
+
+	prev := make([]int, len(r2)+1)
+	next := make([]int, len(r2)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := range r1 {
+		next[0] = i + 1
+
+		for j := range r2 {
+			cost := 1
+			if r1[i] == r2[j] {
+				cost = 0
+			}
+			next[j+1] = min3(prev[j+1]+1, next[j]+1, prev[j]+cost)
+		}
+
+		prev, next = next, prev
+	}
+
+	return prev[len(r2)]
+}
+
+func min3(a int, b int, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
 // structTypeCache is a simple cache for mapping Go types to Struct values.
 type structTypeCache struct {
 	mutex sync.RWMutex
@@ -60,3 +60,90 @@ func TestMakeStructField(t *testing.T) {
 		})
 	}
 }
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a string
+		b string
+		d int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"abc", "", 3},
+		{"hostname", "hostname", 0},
+		{"hostnme", "hostname", 1},
+		{"kitten", "sitting", 3},
+	}
+
+	for _, test := range tests {
+		if d := editDistance(test.a, test.b); d != test.d {
+			t.Errorf("editDistance(%q, %q) = %d != %d", test.a, test.b, d, test.d)
+		}
+	}
+}
+
+func TestResolveJSONPointer(t *testing.T) {
+	doc := map[interface{}]interface{}{
+		"a": map[string]interface{}{
+			"b/c": []interface{}{1, "x"},
+			"d~e": true,
+		},
+	}
+
+	tests := []struct {
+		path  string
+		value interface{}
+		ok    bool
+	}{
+		{"/a/b~1c/1", "x", true},
+		{"/a/d~0e", true, true},
+		{"/a/b~1c/2", nil, false},
+		{"/a/missing", nil, false},
+		{"/a/d~0e/x", nil, false},
+		{"a", nil, false},
+	}
+
+	for _, test := range tests {
+		v, ok := resolveJSONPointer(doc, test.path)
+
+		if ok != test.ok || v != test.value {
+			t.Errorf("%s: %#v, %t != %#v, %t", test.path, v, ok, test.value, test.ok)
+		}
+	}
+}
+
+func TestStructTypeFlat(t *testing.T) {
+	type E struct{ A int }
+
+	tests := []struct {
+		v    interface{}
+		flat bool
+	}{
+		{struct{}{}, true},
+		{struct {
+			A int
+			B string `objconv:"b,omitempty"`
+			C float64
+			D bool
+		}{}, true},
+		{struct{ A []int }{}, false},
+		{struct{ A *int }{}, false},
+		{struct{ E }{}, false},
+		{struct {
+			A int `objconv:",required"`
+		}{}, false},
+		{struct {
+			A int `objconv:"a,alias=b"`
+		}{}, false},
+		{struct {
+			A string   `objconv:",trimprefix=x"`
+			B []string `objconv:",unknownfields"`
+		}{}, false},
+	}
+
+	for _, test := range tests {
+		if s := structCache.lookup(reflect.TypeOf(test.v)); s.flat != test.flat {
+			t.Errorf("%T: expected flat=%t", test.v, test.flat)
+		}
+	}
+}
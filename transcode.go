@@ -0,0 +1,261 @@
+package objconv
+
+import "time"
+
+// Transcode reads the next value from p and writes it to e.
+//
+// Unlike decoding the value and encoding it again, transcoding forwards the
+// parsing events to the emitter as they are read, so the types and the order
+// of map keys produced by the parser are preserved. The only exception are maps
+// of unknown length when the emitter rejects them (some formats require the
+// number of entries to be known when starting to emit a map), those are loaded
+// in memory before being emitted.
+func Transcode(e Emitter, p Parser) (err error) {
+	var t Type
+
+	if t, err = p.ParseType(); err != nil {
+		return
+	}
+
+	switch t {
+	case Nil:
+		if err = p.ParseNil(); err == nil {
+			err = e.EmitNil()
+		}
+
+	case Bool:
+		var v bool
+		if v, err = p.ParseBool(); err == nil {
+			err = e.EmitBool(v)
+		}
+
+	case Int:
+		var v int64
+		if v, err = p.ParseInt(); err == nil {
+			err = e.EmitInt(v, 64)
+		}
+
+	case Uint:
+		var v uint64
+		if v, err = p.ParseUint(); err == nil {
+			err = e.EmitUint(v, 64)
+		}
+
+	case Float:
+		var v float64
+		if v, err = p.ParseFloat(); err == nil {
+			err = e.EmitFloat(v, 64)
+		}
+
+	case String:
+		var v []byte
+		if v, err = p.ParseString(); err == nil {
+			err = e.EmitString(string(v))
+		}
+
+	case Bytes:
+		var v []byte
+		if v, err = p.ParseBytes(); err == nil {
+			err = e.EmitBytes(v)
+		}
+
+	case Time:
+		var v time.Time
+		if v, err = p.ParseTime(); err == nil {
+			err = e.EmitTime(v)
+		}
+
+	case Duration:
+		var v time.Duration
+		if v, err = p.ParseDuration(); err == nil {
+			err = e.EmitDuration(v)
+		}
+
+	case Error:
+		var v error
+		if v, err = p.ParseError(); err == nil {
+			err = e.EmitError(v)
+		}
+
+	case Array:
+		err = transcodeArray(e, p)
+
+	case Map:
+		err = transcodeMap(e, p)
+
+	default:
+		panic("objconv: parser returned an unsupported value type: " + t.String())
+	}
+
+	return
+}
+
+func transcodeArray(e Emitter, p Parser) (err error) {
+	var n int
+
+	if n, err = p.ParseArrayBegin(); err != nil {
+		return
+	}
+
+	if err = e.EmitArrayBegin(n); err != nil {
+		return
+	}
+
+	i := 0
+
+	for n < 0 || i < n {
+		if n < 0 || i != 0 {
+			if err = p.ParseArrayNext(i); err != nil {
+				if err == End {
+					err = nil
+					break
+				}
+				return
+			}
+		}
+
+		if i != 0 {
+			if err = e.EmitArrayNext(); err != nil {
+				return
+			}
+		}
+
+		if err = Transcode(e, p); err != nil {
+			return
+		}
+
+		i++
+	}
+
+	if err = p.ParseArrayEnd(i); err != nil {
+		return
+	}
+
+	return e.EmitArrayEnd()
+}
+
+func transcodeMap(e Emitter, p Parser) (err error) {
+	var n int
+
+	if n, err = p.ParseMapBegin(); err != nil {
+		return
+	}
+
+	if err = e.EmitMapBegin(n); err != nil {
+		if n < 0 {
+			err = transcodeMapBuffered(e, p)
+		}
+		return
+	}
+
+	i := 0
+
+	for n < 0 || i < n {
+		if n < 0 || i != 0 {
+			if err = p.ParseMapNext(i); err != nil {
+				if err == End {
+					err = nil
+					break
+				}
+				return
+			}
+		}
+
+		if i != 0 {
+			if err = e.EmitMapNext(); err != nil {
+				return
+			}
+		}
+
+		if err = Transcode(e, p); err != nil {
+			return
+		}
+
+		if err = p.ParseMapValue(i); err != nil {
+			return
+		}
+
+		if err = e.EmitMapValue(); err != nil {
+			return
+		}
+
+		if err = Transcode(e, p); err != nil {
+			return
+		}
+
+		i++
+	}
+
+	if err = p.ParseMapEnd(i); err != nil {
+		return
+	}
+
+	return e.EmitMapEnd()
+}
+
+func transcodeMapBuffered(e Emitter, p Parser) (err error) {
+	var keys []interface{}
+	var values []interface{}
+
+	d := Decoder{Parser: p}
+
+	for i := 0; true; i++ {
+		var k interface{}
+		var v interface{}
+
+		if err = p.ParseMapNext(i); err != nil {
+			if err == End {
+				err = nil
+				break
+			}
+			return
+		}
+
+		if err = d.Decode(&k); err != nil {
+			return
+		}
+
+		if err = p.ParseMapValue(i); err != nil {
+			return
+		}
+
+		if err = d.Decode(&v); err != nil {
+			return
+		}
+
+		keys = append(keys, k)
+		values = append(values, v)
+	}
+
+	if err = p.ParseMapEnd(len(keys)); err != nil {
+		return
+	}
+
+	enc := Encoder{Emitter: e}
+
+	if err = e.EmitMapBegin(len(keys)); err != nil {
+		return
+	}
+
+	for i := range keys {
+		if i != 0 {
+			if err = e.EmitMapNext(); err != nil {
+				return
+			}
+		}
+
+		if err = enc.Encode(keys[i]); err != nil {
+			return
+		}
+
+		if err = e.EmitMapValue(); err != nil {
+			return
+		}
+
+		if err = enc.Encode(values[i]); err != nil {
+			return
+		}
+	}
+
+	return e.EmitMapEnd()
+}
@@ -0,0 +1,169 @@
+package objconv
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// A TypeRegistry associates names to concrete types, it is used by decoders to
+// construct values of non-empty interface types.
+//
+// When decoding into an interface type, the decoder expects to read a map with
+// a key holding the name of the concrete type to construct (the discriminator).
+// The discriminator is removed from the map, the rest of the map is decoded into
+// a value of the registered type, which is then assigned to the interface.
+// Registered struct types may have a string field tagged with
+// `objconv:",discriminatorvalue"`, which receives the discriminator.
+//
+// It is safe to use a type registry concurrently from multiple goroutines.
+type TypeRegistry struct {
+	// Key is the name of the map key holding the discriminator, "type" is used
+	// when Key is empty.
+	Key string
+
+	mutex sync.RWMutex
+	types map[string]reflect.Type
+}
+
+// Register associates name to the type of proto. The type may be a pointer
+// type, in which case decoders allocate a new value each time they construct a
+// value of that type.
+//
+// The method panics if proto is nil.
+func (reg *TypeRegistry) Register(name string, proto interface{}) {
+	if proto == nil {
+		panic("objconv: cannot register a nil value in a type registry")
+	}
+
+	defer reg.mutex.Unlock()
+	reg.mutex.Lock()
+
+	if reg.types == nil {
+		reg.types = make(map[string]reflect.Type)
+	}
+
+	reg.types[name] = reflect.TypeOf(proto)
+}
+
+// Unregister removes the type associated with name from reg.
+func (reg *TypeRegistry) Unregister(name string) {
+	defer reg.mutex.Unlock()
+	reg.mutex.Lock()
+
+	delete(reg.types, name)
+}
+
+// Lookup returns the type associated with name, ok is set to true or false
+// based on whether a type was found.
+func (reg *TypeRegistry) Lookup(name string) (t reflect.Type, ok bool) {
+	reg.mutex.RLock()
+	t, ok = reg.types[name]
+	reg.mutex.RUnlock()
+	return
+}
+
+func (reg *TypeRegistry) key() string {
+	if len(reg.Key) != 0 {
+		return reg.Key
+	}
+	return "type"
+}
+
+func (d Decoder) decodeRegisteredInterface(to reflect.Value) (typ Type, err error) {
+	if d.Registry == nil {
+		return d.decodeUnsupported(to)
+	}
+
+	if typ, err = d.Parser.ParseType(); err != nil {
+		return
+	}
+
+	if typ == Nil {
+		err = d.decodeInterfaceFromNil(to)
+		return
+	}
+
+	if typ != Map {
+		err = fmt.Errorf("objconv: expected a map to decode a value of type %s but found %s", to.Type(), typ)
+		return
+	}
+
+	// The discriminator may appear after other keys in the map, so the whole
+	// map is loaded first and replayed once the concrete type is known.
+	var m map[interface{}]interface{}
+
+	if err = d.loader().decodeMapFromType(typ, reflect.ValueOf(&m).Elem()); err != nil {
+		return
+	}
+
+	key := d.Registry.key()
+
+	var name string
+	switch k := m[key].(type) {
+	case string:
+		name = k
+	case []byte:
+		name = string(k)
+	default:
+		err = fmt.Errorf("objconv: missing discriminator key %q to decode a value of type %s", key, to.Type())
+		return
+	}
+	delete(m, key)
+
+	t, ok := d.Registry.Lookup(name)
+	if !ok {
+		err = fmt.Errorf("objconv: no type registered for %q to decode a value of type %s", name, to.Type())
+		return
+	}
+
+	if !t.Implements(to.Type()) {
+		err = fmt.Errorf("objconv: the type %s registered for %q does not implement %s", t, name, to.Type())
+		return
+	}
+
+	var v reflect.Value
+	var e reflect.Value
+
+	if t.Kind() == reflect.Ptr {
+		v = reflect.New(t.Elem())
+		e = v.Elem()
+	} else {
+		v = reflect.New(t).Elem()
+		e = v
+	}
+
+	r := d
+	r.Parser = replayParser{ValueParser: NewValueParser(m), parser: d.Parser}
+	r.off = 0
+	r.discriminator = name
+
+	if _, err = r.decode(e); err != nil {
+		return
+	}
+
+	to.Set(v)
+	return
+}
+
+// replayParser is used to decode values that were buffered in memory, it
+// preserves the behavior of the parser that the values were loaded from.
+type replayParser struct {
+	*ValueParser
+	parser Parser
+}
+
+func (p replayParser) DecodeBytes(b []byte) ([]byte, error) {
+	if bd, ok := p.parser.(bytesDecoder); ok {
+		return bd.DecodeBytes(b)
+	}
+	return b, nil
+}
+
+func (p replayParser) FormatName() string {
+	return formatName(p.parser)
+}
+
+func (p replayParser) TextParser() bool {
+	return isTextParser(p.parser)
+}
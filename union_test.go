@@ -0,0 +1,145 @@
+package objconv
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testShape interface {
+	Area() float64
+}
+
+type testCircle struct {
+	Kind string `objconv:",discriminatorvalue"`
+	R    float64
+}
+
+func (c testCircle) Area() float64 { return 3 * c.R * c.R }
+
+type testSquare struct {
+	Side float64
+}
+
+func (s *testSquare) Area() float64 { return s.Side * s.Side }
+
+func newTestTypeRegistry() *TypeRegistry {
+	reg := &TypeRegistry{}
+	reg.Register("circle", testCircle{})
+	reg.Register("square", (*testSquare)(nil))
+	return reg
+}
+
+func TestDecoderTypeRegistry(t *testing.T) {
+	type T struct {
+		Shapes []testShape
+		Main   testShape
+		None   testShape
+	}
+
+	in := struct {
+		Shapes []interface{}
+		Main   interface{}
+		None   interface{}
+	}{
+		Shapes: []interface{}{
+			struct {
+				Type string
+				R    int
+			}{"circle", 2},
+			struct {
+				Side int
+				Type string // the discriminator is after the other fields
+			}{3, "square"},
+		},
+		Main: map[string]interface{}{"R": 1, "Type": "circle"},
+	}
+
+	reg := newTestTypeRegistry()
+	reg.Key = "Type"
+
+	var out T
+	d := Decoder{Parser: NewValueParser(in), Registry: reg}
+
+	if err := d.Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := T{
+		Shapes: []testShape{
+			testCircle{Kind: "circle", R: 2},
+			&testSquare{Side: 3},
+		},
+		Main: testCircle{Kind: "circle", R: 1},
+	}
+
+	if !reflect.DeepEqual(out, expect) {
+		t.Errorf("%#v != %#v", out, expect)
+	}
+}
+
+func TestDecoderTypeRegistryErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   interface{}
+		reg  *TypeRegistry
+	}{
+		{"no-registry", map[string]interface{}{"type": "circle"}, nil},
+		{"not-a-map", 42, newTestTypeRegistry()},
+		{"missing-key", map[string]interface{}{"R": 1}, newTestTypeRegistry()},
+		{"unknown-type", map[string]interface{}{"type": "triangle"}, newTestTypeRegistry()},
+		{"not-implemented", map[string]interface{}{"type": "square"}, func() *TypeRegistry {
+			reg := &TypeRegistry{}
+			reg.Register("square", testSquare{}) // Area has a pointer receiver
+			return reg
+		}()},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var s testShape
+			d := Decoder{Parser: NewValueParser(test.in), Registry: test.reg}
+
+			if err := d.Decode(&s); err == nil {
+				t.Errorf("expected an error but decoded %#v", s)
+			}
+		})
+	}
+}
+
+type testRing struct {
+	Kind   string `objconv:",discriminatorvalue"`
+	R      float64
+	Center struct {
+		Kind string `objconv:",discriminatorvalue"`
+	}
+}
+
+func (r testRing) Area() float64 { return 0 }
+
+func TestDecoderDiscriminatorValue(t *testing.T) {
+	reg := &TypeRegistry{}
+	reg.Register("ring", testRing{})
+
+	var v testShape
+	in := map[string]interface{}{"type": "ring", "R": 3, "Center": map[string]interface{}{}}
+
+	if err := (Decoder{Parser: NewValueParser(in), Registry: reg}).Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	// The discriminator is only set on the struct selected by the union, not
+	// on the structs nested within it.
+	if r, ok := v.(testRing); !ok || r.Kind != "ring" || r.R != 3 || r.Center.Kind != "" {
+		t.Errorf("%#v", v)
+	}
+
+	var r testRing
+
+	if err := NewDecoder(NewValueParser(map[string]interface{}{"R": 1})).Decode(&r); err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Kind != "" {
+		t.Errorf("the discriminatorvalue field must not be set outside of unions: %#v", r)
+	}
+}
@@ -2,9 +2,15 @@ package objconv
 
 import (
 	"encoding"
+	"encoding/json"
 	"errors"
+	"math/big"
+	"net"
+	"net/url"
 	"reflect"
+	"regexp"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unsafe"
 )
@@ -101,23 +107,40 @@ var (
 	float64Type        = reflect.TypeOf(float64(0))
 	stringType         = reflect.TypeOf("")
 	bytesType          = reflect.TypeOf([]byte(nil))
+	stringsType        = reflect.TypeOf([]string(nil))
+	fieldResultType    = reflect.TypeOf(FieldResult(nil))
 	timeType           = reflect.TypeOf(time.Time{})
 	durationType       = reflect.TypeOf(time.Duration(0))
 	sliceInterfaceType = reflect.TypeOf(([]interface{})(nil))
 	timePtrType        = reflect.PtrTo(timeType)
+	bigIntType         = reflect.TypeOf(big.Int{})
+	bigFloatType       = reflect.TypeOf(big.Float{})
+	bigIntPtrType      = reflect.PtrTo(bigIntType)
+	bigFloatPtrType    = reflect.PtrTo(bigFloatType)
+	ipType             = reflect.TypeOf(net.IP(nil))
+	ipNetType          = reflect.TypeOf(net.IPNet{})
+	ipNetPtrType       = reflect.PtrTo(ipNetType)
+	urlType            = reflect.TypeOf(url.URL{})
+	urlPtrType         = reflect.PtrTo(urlType)
+	regexpPtrType      = reflect.TypeOf((*regexp.Regexp)(nil))
+	jsonNumberType     = reflect.TypeOf(json.Number(""))
+	atomicValueType    = reflect.TypeOf(atomic.Value{})
 
 	// interfaces
 	errorInterface             = elemTypeOf((*error)(nil))
 	valueEncoderInterface      = elemTypeOf((*ValueEncoder)(nil))
 	valueDecoderInterface      = elemTypeOf((*ValueDecoder)(nil))
+	typedValueDecoderInterface = elemTypeOf((*TypedValueDecoder)(nil))
 	binaryMarshalerInterface   = elemTypeOf((*encoding.BinaryMarshaler)(nil))
 	binaryUnmarshalerInterface = elemTypeOf((*encoding.BinaryUnmarshaler)(nil))
 	textMarshalerInterface     = elemTypeOf((*encoding.TextMarshaler)(nil))
 	textUnmarshalerInterface   = elemTypeOf((*encoding.TextUnmarshaler)(nil))
 	emptyInterface             = elemTypeOf((*interface{})(nil))
+	scannerInterface           = elemTypeOf((*scanner)(nil))
 
 	// common map types, used for optimization for map encoding algorithms
 	mapStringStringType       = reflect.TypeOf((map[string]string)(nil))
+	mapStringIntType          = reflect.TypeOf((map[string]int)(nil))
 	mapStringInterfaceType    = reflect.TypeOf((map[string]interface{})(nil))
 	mapInterfaceInterfaceType = reflect.TypeOf((map[interface{}]interface{})(nil))
 )
@@ -308,7 +331,7 @@ func (p *ValueParser) ParseMapBegin() (n int, err error) {
 		s := structCache.lookup(v.Type())
 
 		for _, f := range s.fields {
-			if !f.omit(v.FieldByIndex(f.index)) {
+			if !f.decodeOnly() && !f.omit(v.FieldByIndex(f.index)) {
 				c.fields = append(c.fields, f)
 				n++
 			}
@@ -35,6 +35,11 @@ func (p *Parser) Buffered() io.Reader {
 	return bytes.NewReader(nil)
 }
 
+// FormatName satisfies the objconv.Named interface.
+func (p *Parser) FormatName() string {
+	return "yaml"
+}
+
 func (p *Parser) ParseType() (typ objconv.Type, err error) {
 	if p.stack == nil {
 		var b []byte